@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
+	"google.golang.org/grpc/credentials"
 	"harmonycloud.cn/stellaris/pkg/core/monitor"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/klog/v2/klogr"
@@ -20,6 +26,7 @@ import (
 
 	"k8s.io/klog/v2"
 
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
 	managerHelper "harmonycloud.cn/stellaris/pkg/common/helper"
 	controllerCommon "harmonycloud.cn/stellaris/pkg/controller/common"
 	managerWebhook "harmonycloud.cn/stellaris/pkg/webhook"
@@ -27,11 +34,17 @@ import (
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/config/forward"
+	v2 "harmonycloud.cn/stellaris/config/v2"
 	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
 	clientset "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
 	"harmonycloud.cn/stellaris/pkg/controller"
 	corecfg "harmonycloud.cn/stellaris/pkg/core/config"
 	"harmonycloud.cn/stellaris/pkg/core/handler"
+	"harmonycloud.cn/stellaris/pkg/core/route"
+	table "harmonycloud.cn/stellaris/pkg/core/stream"
+	"harmonycloud.cn/stellaris/pkg/core/tunnel"
+	"harmonycloud.cn/stellaris/pkg/protocol"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -47,6 +60,8 @@ var (
 	lisPort                  int
 	heartbeatExpirePeriod    int
 	onlineExpirationTime     int
+	unknownExpirationTime    int
+	unhealthyToleranceTime   int
 	clusterStatusCheckPeriod int
 	masterURL                string
 	metricsAddr              string
@@ -54,19 +69,49 @@ var (
 	certDir                  string
 	tmplStr                  string
 	webhookPort              int
+	tlsCertFile              string
+	tlsKeyFile               string
+	tlsCAFile                string
+	tlsSecret                string
+	tlsReloadPeriod          int
+	enableBootstrapToken     bool
+	bootstrapTokenNamespace  string
+	sessionTTL               int
+	enableLeaderElection     bool
+	leaderElectionNamespace  string
+	internalPort             int
+	internalAddress          string
+	internalServerName       string
+	tunnelPort               int
 )
 
 func init() {
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
 	flag.IntVar(&lisPort, "listen-port", 8080, "Bind port used to provider grpc serve")
 	flag.IntVar(&heartbeatExpirePeriod, "heartbeat-expire-period", 30, "The period of maximum heartbeat interval")
-	flag.IntVar(&clusterStatusCheckPeriod, "cluster-status-check-period", 60, "The period of check cluster status interval")
+	flag.IntVar(&clusterStatusCheckPeriod, "cluster-status-check-period", 60, "The period of resyncing the cluster expiry timers of monitor, clusters are checked when their timers expire")
 	flag.IntVar(&onlineExpirationTime, "online-expiration-time", 90, "cluster status online expiration time")
+	flag.IntVar(&unknownExpirationTime, "unknown-expiration-time", 60, "How long in seconds without heartbeat before an online or degraded cluster becomes unknown")
+	flag.IntVar(&unhealthyToleranceTime, "unhealthy-tolerance-time", 60, "How long in seconds an online cluster can be unhealthy before it becomes degraded")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":9000", "The address the metrics endpoint binds to")
 	flag.StringVar(&probeAddr, "health-probe-addr", ":9001", "The address the probe endpoint binds to.")
 	flag.StringVar(&certDir, "webhook-cert-dir", "/k8s-webhook-server/serving-certs", "Admission webhook cert/key dir.")
 	flag.IntVar(&webhookPort, "webhook-port", 9443, "Admission webhook listen address")
 	flag.StringVar(&tmplStr, "cue-template-config-map", "", "The CUE template which use to deploy proxy, value should be namespace/name")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "File containing the grpc server certificate")
+	flag.StringVar(&tlsKeyFile, "tls-private-key-file", "", "File containing the grpc server private key")
+	flag.StringVar(&tlsCAFile, "tls-ca-file", "", "File containing the CA bundle used to verify proxy client certificates")
+	flag.StringVar(&tlsSecret, "tls-secret", "", "Secret containing tls.crt, tls.key and ca.crt for grpc mutual tls, value should be namespace/name, ignored when tls files are set")
+	flag.IntVar(&tlsReloadPeriod, "tls-reload-period", 60, "The period of checking whether grpc certificates are rotated")
+	flag.BoolVar(&enableBootstrapToken, "enable-bootstrap-token", false, "Require proxy to register with a bootstrap token and bind the stream to the token cluster")
+	flag.StringVar(&bootstrapTokenNamespace, "bootstrap-token-namespace", managerCommon.ManagerNamespace, "The namespace of bootstrap token secrets")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controllers and monitor, and route proxy streams across core replicas, grpc mutual tls is required")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", managerCommon.ManagerNamespace, "The namespace of leader election lease and proxy stream leases")
+	flag.IntVar(&internalPort, "internal-listen-port", 8081, "Bind port used to serve grpc forwarding between core replicas")
+	flag.StringVar(&internalAddress, "internal-address", "", "The address other core replicas use to reach this replica, default is $POD_IP:internal-listen-port")
+	flag.StringVar(&internalServerName, "internal-tls-server-name", "", "The server name in core certificate which replicas verify on forwarding, default is the host of internal address")
+	flag.IntVar(&sessionTTL, "session-ttl", 300, "How long in seconds a reconnected proxy can resume its session without full resync, 0 disables resume")
+	flag.IntVar(&tunnelPort, "tunnel-listen-port", 8443, "Bind port used to serve Kubernetes API requests of member clusters at /clusters/{name}/proxy/ and container logs at /logs/, 0 disables the tunnel")
 
 	utilruntime.Must(v1alpha1.AddToScheme(coreScheme))
 	utilruntime.Must(scheme.AddToScheme(coreScheme))
@@ -95,16 +140,69 @@ func main() {
 	if err != nil {
 		logrus.Fatalf("failed get multicluster client set: %s", err)
 	}
+	kubeClient, err := kubernetes.NewForConfig(kubeCfg)
+	if err != nil {
+		logrus.Fatalf("failed get kube client set: %s", err)
+	}
 
 	cfg := corecfg.DefaultConfiguration()
 	cfg.HeartbeatExpirePeriod = time.Duration(heartbeatExpirePeriod) * time.Second
 	cfg.ClusterStatusCheckPeriod = time.Duration(clusterStatusCheckPeriod) * time.Second
 	cfg.OnlineExpirationTime = time.Duration(onlineExpirationTime) * time.Second
+	cfg.UnknownExpirationTime = time.Duration(unknownExpirationTime) * time.Second
+	cfg.UnhealthyToleranceTime = time.Duration(unhealthyToleranceTime) * time.Second
+	cfg.RequireBootstrapToken = enableBootstrapToken
+	cfg.BootstrapTokenNamespace = bootstrapTokenNamespace
+	cfg.SessionTTL = time.Duration(sessionTTL) * time.Second
 
-	s := grpc.NewServer()
-	config.RegisterChannelServer(s, &handler.Channel{
-		Server: handler.NewCoreServer(cfg, mClient),
-	})
+	// sends on one stream are serialized, so that handlers and controllers can send to proxy concurrently
+	var dialOptions []grpc.DialOption
+	var corePeerName string
+	var certStore *certificate.Store
+	serverOptions := []grpc.ServerOption{grpc.StreamInterceptor(protocol.SerialStreamServerInterceptor(protocol.DefaultSendQueueSize))}
+	certSource := &certificate.Source{CertFile: tlsCertFile, KeyFile: tlsKeyFile, CAFile: tlsCAFile}
+	if len(tlsSecret) > 0 {
+		certSource.SecretNamespace, certSource.SecretName, err = cache.SplitMetaNamespaceKey(tlsSecret)
+		if err != nil {
+			logrus.Fatalf("--tls-secret args must format be namespace/name, but got %s", tlsSecret)
+		}
+		certSource.KubeClient = kubeClient
+	}
+	if !certSource.IsEmpty() {
+		certStore, err = certificate.NewStore(certSource)
+		if err != nil {
+			logrus.Fatalf("failed load grpc certificate: %s", err)
+		}
+		go certStore.Start(context.Background(), time.Duration(tlsReloadPeriod)*time.Second)
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(certificate.ServerTLSConfig(certStore))))
+		// replicas present the core certificate to each other
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(certificate.ClientCredentials(certStore, internalServerName)))
+		if corePeerName, err = certStore.CommonName(); err != nil {
+			logrus.Fatalf("failed get common name of grpc certificate: %s", err)
+		}
+		cfg.RequireClientCertificate = true
+	} else {
+		logrus.Warn("grpc mutual tls is disabled, data between core and proxy is transmitted in plaintext")
+		dialOptions = append(dialOptions, grpc.WithInsecure())
+	}
+
+	s := grpc.NewServer(serverOptions...)
+	coreServer := handler.NewCoreServer(cfg, mClient, kubeClient)
+	var router *route.Router
+	if enableLeaderElection {
+		// the internal endpoint can not tell core replicas from proxies without client certificates, and the
+		// replicas which do not hold the stream of a proxy can not deliver to it without routing
+		if len(corePeerName) == 0 {
+			logrus.Fatal("--leader-elect requires grpc mutual tls to route proxy streams between core replicas")
+		}
+		router = startRouting(&handler.Forward{Server: coreServer, PeerName: corePeerName}, kubeClient, cfg, serverOptions, dialOptions)
+	}
+	if tunnelPort > 0 {
+		startTunnel(kubeClient, mClient, router, certStore)
+	}
+	// v1 is still served for proxies which are not upgraded yet
+	config.RegisterChannelServer(s, &handler.Channel{Server: coreServer})
+	v2.RegisterChannelServer(s, &handler.ChannelV2{Server: coreServer})
 	go func() {
 		logrus.Infof("listening port %d", lisPort)
 		if err := s.Serve(l); err != nil {
@@ -114,11 +212,14 @@ func main() {
 
 	restCfg := ctrl.GetConfigOrDie()
 	mgr, err := ctrl.NewManager(restCfg, ctrl.Options{
-		Scheme:                 coreScheme,
-		MetricsBindAddress:     metricsAddr,
-		CertDir:                certDir,
-		Port:                   webhookPort,
-		HealthProbeBindAddress: probeAddr,
+		Scheme:                  coreScheme,
+		MetricsBindAddress:      metricsAddr,
+		CertDir:                 certDir,
+		Port:                    webhookPort,
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "stellaris-core-leader",
+		LeaderElectionNamespace: leaderElectionNamespace,
 	})
 	if err != nil {
 		logrus.Fatalf("failed create manager: %s", err)
@@ -140,14 +241,25 @@ func main() {
 		klog.ErrorS(err, "Unable to get webhook secret")
 		os.Exit(1)
 	}
-	// monitor
-	go monitor.StartCheckClusterStatus(mClient, cfg)
+	// monitor runs in leader only
+	clusterMonitor, err := monitor.NewMonitor(mgr, mClient, cfg)
+	if err != nil {
+		logrus.Fatalf("failed to create cluster monitor: %s", err)
+	}
+	if err = mgr.Add(clusterMonitor); err != nil {
+		logrus.Fatalf("failed to add cluster monitor: %s", err)
+	}
+	// retry resource deliveries which are not acknowledged by proxy
+	go coreServer.RetryDeliveries(context.Background())
 
 	// setup controllers
 	if err = controller.Setup(mgr, controllerArgs); err != nil {
 		logrus.Fatalf("failed to create controller: %s", err)
 	}
 
+	if err := mgr.AddMetricsExtraHandler("/debug/streams", table.DebugHandler()); err != nil {
+		logrus.Fatalf("failed to add stream table debug handler: %s", err)
+	}
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logrus.Fatalf("failed to setup health check")
 	}
@@ -159,6 +271,76 @@ func main() {
 	}
 }
 
+// startRouting serves the internal grpc endpoint and records the proxy streams of this replica in Leases
+func startRouting(forwardServer *handler.Forward, kubeClient kubernetes.Interface, cfg *corecfg.Configuration, serverOptions []grpc.ServerOption, dialOptions []grpc.DialOption) *route.Router {
+	address := internalAddress
+	if len(address) == 0 {
+		podIP := os.Getenv("POD_IP")
+		if len(podIP) == 0 {
+			logrus.Fatalf("--internal-address or POD_IP env must be set when leader election is enabled")
+		}
+		address = net.JoinHostPort(podIP, strconv.Itoa(internalPort))
+	}
+	router := route.New(kubeClient, leaderElectionNamespace, address, cfg.OnlineExpirationTime, dialOptions...)
+	handler.EnableRouting(router)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", internalPort))
+	if err != nil {
+		logrus.Fatalf("listen internal port %d error: %s", internalPort, err)
+	}
+	s := grpc.NewServer(serverOptions...)
+	forward.RegisterForwardServer(s, forwardServer)
+	go func() {
+		logrus.Infof("listening internal port %d", internalPort)
+		if err := s.Serve(l); err != nil {
+			logrus.Fatalf("internal grpc server running error: %s", err)
+		}
+	}()
+	return router
+}
+
+// startTunnel serves the Kubernetes API of member clusters, it uses the grpc server certificate when tls is enabled
+func startTunnel(kubeClient kubernetes.Interface, mClient clientset.Interface, router *route.Router, certStore *certificate.Store) {
+	server := &tunnel.Server{
+		KubeClient:         kubeClient,
+		MultiClusterClient: mClient,
+		Router:             router,
+		PeerScheme:         "http",
+		PeerPort:           tunnelPort,
+		PeerTransport:      http.DefaultTransport,
+	}
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", tunnelPort),
+		Handler: server,
+	}
+	if certStore != nil {
+		server.PeerScheme = "https"
+		server.PeerTransport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			// a rotated CA is picked up by new connections
+			DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialer := &tls.Dialer{Config: certificate.ClientTLSConfig(certStore, internalServerName)}
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+		httpServer.TLSConfig = certificate.HTTPServerTLSConfig(certStore)
+	} else {
+		logrus.Warn("tunnel tls is disabled, bearer tokens of callers are transmitted in plaintext")
+	}
+	go func() {
+		logrus.Infof("listening tunnel port %d", tunnelPort)
+		var err error
+		if httpServer.TLSConfig != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil {
+			logrus.Fatalf("tunnel server running error: %s", err)
+		}
+	}()
+}
+
 // waitWebhookSecretVolume waits for webhook secret ready to avoid mgr running crash
 func waitWebhookSecretVolume(certDir string, timeout, interval time.Duration) error {
 	start := time.Now()
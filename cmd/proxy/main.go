@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"time"
 
-	"harmonycloud.cn/stellaris/pkg/proxy/send"
+	"harmonycloud.cn/stellaris/pkg/protocol"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
 
-	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
 
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"harmonycloud.cn/stellaris/pkg/proxy/condition"
+	"harmonycloud.cn/stellaris/pkg/proxy/event"
 	"harmonycloud.cn/stellaris/pkg/proxy/handler"
+	"harmonycloud.cn/stellaris/pkg/proxy/send"
+	"harmonycloud.cn/stellaris/pkg/proxy/tunnel"
 
 	"harmonycloud.cn/stellaris/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -29,13 +38,24 @@ import (
 )
 
 var (
-	heartbeatPeriod  int
-	coreAddress      string
-	clusterName      string
-	addonPath        string
-	metricsAddr      string
-	probeAddr        string
-	addonLoadTimeout int
+	heartbeatPeriod   int
+	coreAddress       string
+	clusterName       string
+	addonPath         string
+	metricsAddr       string
+	probeAddr         string
+	addonLoadTimeout  int
+	tlsCertFile       string
+	tlsKeyFile        string
+	tlsCAFile         string
+	tlsSecret         string
+	tlsServerName     string
+	tlsReloadPeriod   int
+	bootstrapToken    string
+	protocolVersion   string
+	enableTunnel      bool
+	tunnelImpersonate bool
+	forwardEvents     bool
 )
 
 var proxyScheme = runtime.NewScheme()
@@ -53,6 +73,17 @@ func init() {
 	flag.StringVar(&probeAddr, "health-probe-addr", ":9001", "The address the probe endpoint binds to.")
 
 	flag.IntVar(&addonLoadTimeout, "addon-load-timeout", 3, "Load addon timeout")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "File containing the proxy client certificate, its common name must be the cluster name")
+	flag.StringVar(&tlsKeyFile, "tls-private-key-file", "", "File containing the proxy client private key")
+	flag.StringVar(&tlsCAFile, "tls-ca-file", "", "File containing the CA bundle used to verify the core certificate")
+	flag.StringVar(&tlsSecret, "tls-secret", "", "Secret containing tls.crt, tls.key and ca.crt for grpc mutual tls, value should be namespace/name, ignored when tls files are set")
+	flag.StringVar(&tlsServerName, "tls-server-name", "", "Server name used to verify the core certificate, default is the host of core address")
+	flag.IntVar(&tlsReloadPeriod, "tls-reload-period", 60, "The period of checking whether grpc certificates are rotated")
+	flag.StringVar(&bootstrapToken, "bootstrap-token", "", "Token used to join core, format is [a-z0-9]{6}.[a-z0-9]{16}")
+	flag.StringVar(&protocolVersion, "protocol-version", protocol.VersionV2, "Version of the protocol used to communicate with core, v1 or v2, use v1 when core is not upgraded")
+	flag.BoolVar(&enableTunnel, "enable-tunnel", true, "Execute the Kubernetes API requests tunnelled by core in member cluster")
+	flag.BoolVar(&tunnelImpersonate, "tunnel-impersonate", true, "Impersonate the caller authenticated by core when executing tunnelled requests, otherwise use the proxy service account")
+	flag.BoolVar(&forwardEvents, "forward-events", true, "Forward the Events of objects created from ClusterResources to core")
 	utilruntime.Must(v1alpha1.AddToScheme(proxyScheme))
 	utilruntime.Must(scheme.AddToScheme(proxyScheme))
 
@@ -71,6 +102,12 @@ func main() {
 	cfg.CoreAddress = coreAddress
 	cfg.AddonPath = addonPath
 	cfg.AddonLoadTimeout = time.Duration(addonLoadTimeout) * time.Second
+	cfg.TLSServerName = tlsServerName
+	cfg.BootstrapToken = bootstrapToken
+	if protocolVersion != protocol.VersionV1 && protocolVersion != protocol.VersionV2 {
+		logrus.Fatalf("--protocol-version must be %s or %s, but got %s", protocol.VersionV1, protocol.VersionV2, protocolVersion)
+	}
+	cfg.ProtocolVersion = protocolVersion
 
 	restCfg := ctrl.GetConfigOrDie()
 	mgr, err := ctrl.NewManager(restCfg, ctrl.Options{
@@ -98,17 +135,54 @@ func main() {
 	// new proxyConfig
 	proxy_cfg.NewProxyConfig(cfg, proxyClient, mgr.GetClient())
 
-	// new stream
-	stream := proxy_stream.GetConnection()
-	if stream == nil {
-		logrus.Fatalf("failed get connection %s", cfg.CoreAddress)
+	// load grpc client certificate
+	certSource := &certificate.Source{CertFile: tlsCertFile, KeyFile: tlsKeyFile, CAFile: tlsCAFile}
+	if len(tlsSecret) > 0 {
+		certSource.SecretNamespace, certSource.SecretName, err = cache.SplitMetaNamespaceKey(tlsSecret)
+		if err != nil {
+			logrus.Fatalf("--tls-secret args must format be namespace/name, but got %s", tlsSecret)
+		}
+		certSource.KubeClient, err = kubernetes.NewForConfig(restCfg)
+		if err != nil {
+			logrus.Fatalf("failed get kube client set: %s", err)
+		}
+	}
+	if !certSource.IsEmpty() {
+		certStore, err := certificate.NewStore(certSource)
+		if err != nil {
+			logrus.Fatalf("failed load grpc certificate: %s", err)
+		}
+		go certStore.Start(context.Background(), time.Duration(tlsReloadPeriod)*time.Second)
+		proxy_cfg.ProxyConfig.CertStore = certStore
 	}
 
-	err = send.Register()
-	if err != nil {
-		logrus.Fatalf("failed send register request, cluster: %s", err)
+	// conditions of member cluster are sent in heartbeat
+	condition.Register(
+		condition.NewNodeProbe(mgr.GetClient()),
+		condition.NewAPIServerProbe(proxyClient.DiscoveryClient.RESTClient()),
+		condition.NewEtcdProbe(proxyClient.DiscoveryClient.RESTClient()),
+		condition.NewCertificateProbe(proxy_cfg.ProxyConfig.CertStore),
+	)
+
+	if enableTunnel {
+		tunnel.Setup(restCfg, tunnelImpersonate, send.SendTunnelChunk, send.SendLogChunk)
+	}
+
+	if forwardEvents {
+		kubeClient, err := kubernetes.NewForConfig(restCfg)
+		if err != nil {
+			logrus.Fatalf("failed get kube client set: %s", err)
+		}
+		dynamicClient, err := dynamic.NewForConfig(restCfg)
+		if err != nil {
+			logrus.Fatalf("failed get dynamic client: %s", err)
+		}
+		if err = mgr.Add(event.NewForwarder(kubeClient, dynamicClient, mgr.GetRESTMapper(), send.SendEventRequest)); err != nil {
+			logrus.Fatalf("failed to add event forwarder: %s", err)
+		}
 	}
 
+	// connect and register to core, reconnect when the stream is broken
 	go handler.RecvResponse()
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
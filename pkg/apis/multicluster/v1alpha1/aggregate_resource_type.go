@@ -43,6 +43,8 @@ type AggregatedResourceStatusClusters struct {
 	ResourceName string                                `json:"resourceName"`
 	UpdateTime   *metav1.Time                          `json:"updateTime"`
 	Status       AggregatedResourceStatusClusterStatus `json:"status"`
+	// Message is the error of rule evaluation when status is error
+	Message string `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
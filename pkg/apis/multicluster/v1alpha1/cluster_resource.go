@@ -27,6 +27,29 @@ type ClusterResourceStatus struct {
 	ObservedReceiveGeneration int64                            `json:"observedReceiveGeneration,omitempty"`
 	Phase                     common.MultiClusterResourcePhase `json:"phase,omitempty"`
 	Message                   string                           `json:"message,omitempty"`
+	// Delivery is the state of the latest delivery from core to proxy, only set in control plane
+	Delivery *ClusterResourceDelivery `json:"delivery,omitempty"`
+}
+
+type ClusterResourceDeliveryPhase string
+
+const (
+	// DeliveryPending means the delivery is sent or waiting for retry, and not acknowledged by proxy
+	DeliveryPending ClusterResourceDeliveryPhase = "Pending"
+	// DeliveryAcknowledged means proxy has applied the delivery
+	DeliveryAcknowledged ClusterResourceDeliveryPhase = "Acknowledged"
+	// DeliveryFailed means proxy failed to apply the delivery, it will be retried
+	DeliveryFailed ClusterResourceDeliveryPhase = "Failed"
+)
+
+type ClusterResourceDelivery struct {
+	// RequestID is the id of the latest message sent to proxy
+	RequestID        string                       `json:"requestID,omitempty"`
+	Phase            ClusterResourceDeliveryPhase `json:"phase,omitempty"`
+	Attempts         int32                        `json:"attempts,omitempty"`
+	LastAttemptTime  *metav1.Time                 `json:"lastAttemptTime,omitempty"`
+	AcknowledgedTime *metav1.Time                 `json:"acknowledgedTime,omitempty"`
+	Message          string                       `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -60,6 +60,8 @@ type AggregatePolicyStatus string
 const (
 	AggregatePolicyStatusNormal     AggregatePolicyStatus = "Normal"
 	AggregatePolicyStatusRuleRepeat AggregatePolicyStatus = "RuleRepeat"
+	// AggregatePolicyStatusRuleError means a rule of policy can not be evaluated in member cluster
+	AggregatePolicyStatusRuleError AggregatePolicyStatus = "RuleError"
 )
 
 type MultiClusterResourceAggregatePolicyStatus struct {
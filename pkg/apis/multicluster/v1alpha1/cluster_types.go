@@ -1,7 +1,7 @@
 package v1alpha1
 
 import (
-	"harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -11,6 +11,17 @@ import (
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:resource:scope="Cluster"
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.status"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Nodes",type="integer",JSONPath=".status.resourceSummary.nodes"
+// +kubebuilder:printcolumn:name="Schedulable",type="integer",JSONPath=".status.resourceSummary.schedulableNodes"
+// +kubebuilder:printcolumn:name="CPU",type="string",JSONPath=".status.resourceSummary.allocatable.cpu"
+// +kubebuilder:printcolumn:name="CPU-Allocated",type="string",JSONPath=".status.resourceSummary.allocated.cpu"
+// +kubebuilder:printcolumn:name="Memory",type="string",JSONPath=".status.resourceSummary.allocatable.memory"
+// +kubebuilder:printcolumn:name="Memory-Allocated",type="string",JSONPath=".status.resourceSummary.allocated.memory",priority=1
+// +kubebuilder:printcolumn:name="Pods",type="string",JSONPath=".status.resourceSummary.allocatable.pods",priority=1
+// +kubebuilder:printcolumn:name="Pods-Allocated",type="string",JSONPath=".status.resourceSummary.allocated.pods",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -25,15 +36,99 @@ type ClusterSpec struct {
 	Addons    []ClusterAddon   `json:"addons,omitempty"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Configuration *runtime.RawExtension `json:"configuration,omitempty"`
+	// Unschedulable cordons cluster, no new workloads are scheduled to it
+	Unschedulable bool `json:"unschedulable,omitempty"`
+	// Taints repel the policies which do not tolerate them. NoSchedule and PreferNoSchedule keep the workloads
+	// already scheduled to cluster, NoExecute evicts them to failover clusters
+	Taints []corev1.Taint `json:"taints,omitempty"`
+	// Maintenance taints cluster during the window
+	Maintenance *MaintenanceWindow `json:"maintenance,omitempty"`
+}
+
+// MaintenanceWindow takes cluster out of rotation from Start to End, the window never ends when End is empty
+type MaintenanceWindow struct {
+	Start metav1.Time  `json:"start"`
+	End   *metav1.Time `json:"end,omitempty"`
+	// Effect of the maintenance taint, it is NoSchedule when empty
+	// +kubebuilder:validation:Enum=NoSchedule;PreferNoSchedule;NoExecute
+	Effect corev1.TaintEffect `json:"effect,omitempty"`
+	Reason string             `json:"reason,omitempty"`
 }
 
 type ClusterStatus struct {
-	Addons                        []ClusterAddonStatus `json:"addons,omitempty"`
-	Conditions                    []common.Condition   `json:"conditions,omitempty"`
-	LastReceiveHeartBeatTimestamp metav1.Time          `json:"lastReceiveHeartBeatTimestamp,omitempty"`
-	LastUpdateTimestamp           metav1.Time          `json:"lastUpdateTimestamp,omitempty"`
-	Healthy                       bool                 `json:"healthy,omitempty"`
-	Status                        ClusterStatusType    `json:"status,omitempty"`
+	Addons []ClusterAddonStatus `json:"addons,omitempty"`
+	// Conditions are keyed by type, Ready is set by core and the others are reported by proxy
+	// +listType=map
+	// +listMapKey=type
+	Conditions                    []metav1.Condition `json:"conditions,omitempty"`
+	LastReceiveHeartBeatTimestamp metav1.Time        `json:"lastReceiveHeartBeatTimestamp,omitempty"`
+	LastUpdateTimestamp           metav1.Time        `json:"lastUpdateTimestamp,omitempty"`
+	// Deprecated: Healthy is true only when Status is online, use Status and the Ready condition instead
+	Healthy bool `json:"healthy,omitempty"`
+	// Status is the state of cluster health, see ClusterStatusType for the transitions
+	Status ClusterStatusType `json:"status,omitempty"`
+	// OutboxDepth is the number of ClusterResource changes queued in core while proxy is offline
+	OutboxDepth int32 `json:"outboxDepth,omitempty"`
+	// ResourceSummary is the capacity of member cluster reported by proxy in heartbeat
+	ResourceSummary *ClusterResourceSummary `json:"resourceSummary,omitempty"`
+	// Proxy is the proxy deployed by core from the CUE template, it is empty when proxy is not auto deployed
+	Proxy *ClusterProxyStatus `json:"proxy,omitempty"`
+	// Decommission is the progress of draining the workloads of cluster after it is deleted
+	Decommission *ClusterDecommissionStatus `json:"decommission,omitempty"`
+}
+
+type ClusterDecommissionPhase string
+
+const (
+	// DecommissionDraining means the workloads of cluster are being rescheduled to other clusters
+	DecommissionDraining ClusterDecommissionPhase = "Draining"
+	// DecommissionDrained means the workloads are running in other clusters and the cluster can be removed
+	DecommissionDrained ClusterDecommissionPhase = "Drained"
+)
+
+// ClusterDecommissionStatus is set when cluster is deleted, the finalizer is removed after cluster is drained
+type ClusterDecommissionStatus struct {
+	Phase     ClusterDecommissionPhase `json:"phase,omitempty"`
+	StartTime metav1.Time              `json:"startTime,omitempty"`
+	// Bindings are the MultiClusterResourceBindings bound to cluster since draining started, in form of namespace/name
+	Bindings []string `json:"bindings,omitempty"`
+	// PendingBindings is the number of Bindings which still bind to cluster
+	PendingBindings int32 `json:"pendingBindings"`
+	// PendingResources is the number of ClusterResources of Bindings in other clusters which are not complete
+	PendingResources int32 `json:"pendingResources"`
+	// UnmanagedBindings are the MultiClusterResourceBindings bound to cluster which are not created by scheduler, they
+	// are not rescheduled and do not block draining, in form of namespace/name
+	UnmanagedBindings []string `json:"unmanagedBindings,omitempty"`
+	Message           string   `json:"message,omitempty"`
+}
+
+// ClusterProxyStatus records the last apply of proxy resources to member cluster
+type ClusterProxyStatus struct {
+	// TemplateHash is the hash of the template, configuration and addons last applied
+	TemplateHash string `json:"templateHash,omitempty"`
+	// LastAppliedTime is when the resources were last applied, they are applied again periodically to correct drift
+	LastAppliedTime metav1.Time `json:"lastAppliedTime,omitempty"`
+	// Resources are the outputs of template last applied, the ones removed from template are pruned
+	Resources []ProxyResource `json:"resources,omitempty"`
+}
+
+type ProxyResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// ClusterResourceSummary is the capacity of member cluster, allocatable and allocated contain cpu, memory and pods
+type ClusterResourceSummary struct {
+	// Nodes is the number of all nodes in member cluster
+	Nodes int32 `json:"nodes"`
+	// SchedulableNodes is the number of nodes which are ready, not cordoned and not tainted with NoSchedule or NoExecute
+	SchedulableNodes int32 `json:"schedulableNodes"`
+	// Allocatable is the sum of allocatable resources of schedulable nodes
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+	// Allocated is the sum of requests of the pods which are not terminated on schedulable nodes
+	Allocated corev1.ResourceList `json:"allocated,omitempty"`
 }
 
 const (
@@ -70,12 +165,26 @@ type ClusterAddonStatus struct {
 	Info *runtime.RawExtension `json:"info,omitempty"`
 }
 
+// ClusterStatusType is the state of cluster health. A cluster is initializing until the first heartbeat, online
+// when proxy reports it healthy, degraded when it stays unhealthy, unknown when the stream is disconnected or
+// heartbeat is late, and offline when there is no heartbeat for long
 type ClusterStatusType string
 
 const (
 	OnlineStatus       ClusterStatusType = "online"
 	OfflineStatus      ClusterStatusType = "offline"
 	InitializingStatus ClusterStatusType = "initializing"
+	// UnknownStatus means the stream of proxy is disconnected, cluster becomes offline if proxy does not come back in time
+	UnknownStatus ClusterStatusType = "unknown"
+	// DegradedStatus means proxy is connected but the member cluster has been unhealthy for a while
+	DegradedStatus ClusterStatusType = "degraded"
+)
+
+const (
+	// ClusterReadyCondition is true only when cluster is online
+	ClusterReadyCondition = "Ready"
+	// ClusterHealthyCondition is the result of health endpoint of member cluster in the last heartbeat
+	ClusterHealthyCondition = "Healthy"
 )
 
 // +genclient:nonNamespaced
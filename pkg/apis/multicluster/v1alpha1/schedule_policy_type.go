@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	"harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -41,6 +42,8 @@ type MultiClusterResourceSchedulePolicySpec struct {
 	Policy         []SchedulePolicy         `json:"policy,omitempty"`
 	FailoverPolicy []ScheduleFailoverPolicy `json:"failoverPolicy,omitempty"`
 	OutTreePolicy  ScheduleOutTreePolicy    `json:"outTreePolicy,omitempty"`
+	// Tolerations let the policy be scheduled to the clusters with matching taints, tolerationSeconds is ignored
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
 type SchedulePolicyResource struct {
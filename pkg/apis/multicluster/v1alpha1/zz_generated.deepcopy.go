@@ -23,6 +23,7 @@ package v1alpha1
 
 import (
 	common "harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -223,6 +224,33 @@ func (in *ClusterAddonStatus) DeepCopy() *ClusterAddonStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDecommissionStatus) DeepCopyInto(out *ClusterDecommissionStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.Bindings != nil {
+		in, out := &in.Bindings, &out.Bindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnmanagedBindings != nil {
+		in, out := &in.UnmanagedBindings, &out.UnmanagedBindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDecommissionStatus.
+func (in *ClusterDecommissionStatus) DeepCopy() *ClusterDecommissionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDecommissionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterList) DeepCopyInto(out *ClusterList) {
 	*out = *in
@@ -256,13 +284,35 @@ func (in *ClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterProxyStatus) DeepCopyInto(out *ClusterProxyStatus) {
+	*out = *in
+	in.LastAppliedTime.DeepCopyInto(&out.LastAppliedTime)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ProxyResource, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterProxyStatus.
+func (in *ClusterProxyStatus) DeepCopy() *ClusterProxyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterProxyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterResource) DeepCopyInto(out *ClusterResource) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -284,6 +334,30 @@ func (in *ClusterResource) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterResourceDelivery) DeepCopyInto(out *ClusterResourceDelivery) {
+	*out = *in
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AcknowledgedTime != nil {
+		in, out := &in.AcknowledgedTime, &out.AcknowledgedTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterResourceDelivery.
+func (in *ClusterResourceDelivery) DeepCopy() *ClusterResourceDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterResourceDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterResourceList) DeepCopyInto(out *ClusterResourceList) {
 	*out = *in
@@ -341,6 +415,11 @@ func (in *ClusterResourceSpec) DeepCopy() *ClusterResourceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterResourceStatus) DeepCopyInto(out *ClusterResourceStatus) {
 	*out = *in
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(ClusterResourceDelivery)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -354,6 +433,36 @@ func (in *ClusterResourceStatus) DeepCopy() *ClusterResourceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterResourceSummary) DeepCopyInto(out *ClusterResourceSummary) {
+	*out = *in
+	if in.Allocatable != nil {
+		in, out := &in.Allocatable, &out.Allocatable
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Allocated != nil {
+		in, out := &in.Allocated, &out.Allocated
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterResourceSummary.
+func (in *ClusterResourceSummary) DeepCopy() *ClusterResourceSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterResourceSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSecretRef) DeepCopyInto(out *ClusterSecretRef) {
 	*out = *in
@@ -507,6 +616,18 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 		*out = new(runtime.RawExtension)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]corev1.Taint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -532,13 +653,28 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]common.Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	in.LastReceiveHeartBeatTimestamp.DeepCopyInto(&out.LastReceiveHeartBeatTimestamp)
 	in.LastUpdateTimestamp.DeepCopyInto(&out.LastUpdateTimestamp)
+	if in.ResourceSummary != nil {
+		in, out := &in.ResourceSummary, &out.ResourceSummary
+		*out = new(ClusterResourceSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ClusterProxyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Decommission != nil {
+		in, out := &in.Decommission, &out.Decommission
+		*out = new(ClusterDecommissionStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -552,6 +688,27 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MultiClusterResource) DeepCopyInto(out *MultiClusterResource) {
 	*out = *in
@@ -1288,6 +1445,13 @@ func (in *MultiClusterResourceSchedulePolicySpec) DeepCopyInto(out *MultiCluster
 		copy(*out, *in)
 	}
 	in.OutTreePolicy.DeepCopyInto(&out.OutTreePolicy)
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -1470,6 +1634,22 @@ func (in *NamespaceMappingSpec) DeepCopy() *NamespaceMappingSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyResource) DeepCopyInto(out *ProxyResource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyResource.
+func (in *ProxyResource) DeepCopy() *ProxyResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceAggregatePolicy) DeepCopyInto(out *ResourceAggregatePolicy) {
 	*out = *in
@@ -0,0 +1,196 @@
+package cluster_health
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+const (
+	clusterInitializing       = "ClusterInitializing"
+	clusterInitializingMsg    = "waiting for proxy to register"
+	clusterReady              = "ClusterReady"
+	clusterReadyMsg           = "cluster is reachable and health endpoint responded with ok"
+	clusterDegraded           = "ClusterDegraded"
+	clusterDisconnected       = "ClusterDisconnected"
+	clusterDisconnectedMsg    = "stream between core and proxy is disconnected"
+	clusterHeartbeatLate      = "ClusterHeartbeatLate"
+	clusterNotReachableReason = "ClusterNotReachable"
+
+	healthEndpointOK     = "HealthEndpointOK"
+	healthEndpointOKMsg  = "health endpoint responded with ok"
+	healthEndpointFailed = "HealthEndpointFailed"
+	healthEndpointMsg    = "health endpoint responded without ok"
+)
+
+// Thresholds configures the transitions between the states of cluster
+type Thresholds struct {
+	// UnhealthyTolerance is how long an online cluster can be unhealthy before it becomes degraded
+	UnhealthyTolerance time.Duration
+	// UnknownAfter is how long without heartbeat before an online or degraded cluster becomes unknown
+	UnknownAfter time.Duration
+	// OfflineAfter is how long without heartbeat before a cluster becomes offline
+	OfflineAfter time.Duration
+}
+
+// Transition is the next state of cluster, reason and message are set to the Ready condition
+type Transition struct {
+	State   v1alpha1.ClusterStatusType
+	Reason  string
+	Message string
+}
+
+// Initializing is the state of cluster whose proxy is being deployed
+func Initializing() *Transition {
+	return &Transition{State: v1alpha1.InitializingStatus, Reason: clusterInitializing, Message: clusterInitializingMsg}
+}
+
+// OnHeartbeat returns the state after a heartbeat or register of proxy, the Healthy condition must have been set.
+// nil is returned when the state does not change
+func (t Thresholds) OnHeartbeat(status *v1alpha1.ClusterStatus, now time.Time) *Transition {
+	healthy := meta.FindStatusCondition(status.Conditions, v1alpha1.ClusterHealthyCondition)
+	if healthy == nil || healthy.Status == metav1.ConditionTrue {
+		return next(status, &Transition{State: v1alpha1.OnlineStatus, Reason: clusterReady, Message: clusterReadyMsg})
+	}
+	// an online cluster tolerates short failures of health endpoint, the others become degraded at once
+	if status.Status == v1alpha1.OnlineStatus && now.Sub(healthy.LastTransitionTime.Time) < t.UnhealthyTolerance {
+		return nil
+	}
+	return next(status, &Transition{
+		State:   v1alpha1.DegradedStatus,
+		Reason:  clusterDegraded,
+		Message: fmt.Sprintf("%s since %s", healthEndpointMsg, healthy.LastTransitionTime.UTC().Format(time.RFC3339)),
+	})
+}
+
+// OnCheck returns the state when no heartbeat is received for a while, it is called by monitor periodically.
+// Initializing and offline clusters only change on heartbeat
+func (t Thresholds) OnCheck(status *v1alpha1.ClusterStatus, now time.Time) *Transition {
+	switch status.Status {
+	case v1alpha1.OnlineStatus, v1alpha1.DegradedStatus, v1alpha1.UnknownStatus:
+	default:
+		return nil
+	}
+	last := status.LastReceiveHeartBeatTimestamp.Time
+	message := fmt.Sprintf("no heartbeat since %s", last.UTC().Format(time.RFC3339))
+	switch since := now.Sub(last); {
+	case since >= t.OfflineAfter:
+		return next(status, &Transition{State: v1alpha1.OfflineStatus, Reason: clusterNotReachableReason, Message: message})
+	case since >= t.UnknownAfter && status.Status != v1alpha1.UnknownStatus:
+		return next(status, &Transition{State: v1alpha1.UnknownStatus, Reason: clusterHeartbeatLate, Message: message})
+	default:
+		return nil
+	}
+}
+
+// NextCheck is when OnCheck may change the state if no heartbeat is received, false is returned when the state
+// only changes on heartbeat
+func (t Thresholds) NextCheck(status *v1alpha1.ClusterStatus) (time.Time, bool) {
+	last := status.LastReceiveHeartBeatTimestamp.Time
+	switch status.Status {
+	case v1alpha1.OnlineStatus, v1alpha1.DegradedStatus:
+		after := t.UnknownAfter
+		if t.OfflineAfter < after {
+			after = t.OfflineAfter
+		}
+		return last.Add(after), true
+	case v1alpha1.UnknownStatus:
+		return last.Add(t.OfflineAfter), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// OnDisconnect returns the state after the stream of proxy is disconnected
+func OnDisconnect(status *v1alpha1.ClusterStatus) *Transition {
+	if status.Status != v1alpha1.OnlineStatus && status.Status != v1alpha1.DegradedStatus {
+		return nil
+	}
+	return &Transition{State: v1alpha1.UnknownStatus, Reason: clusterDisconnected, Message: clusterDisconnectedMsg}
+}
+
+func next(status *v1alpha1.ClusterStatus, transition *Transition) *Transition {
+	if status.Status == transition.State {
+		return nil
+	}
+	return transition
+}
+
+// SetState sets the state of cluster and the Ready condition, Ready is unknown when core can not tell the health
+// of cluster
+func SetState(status *v1alpha1.ClusterStatus, transition *Transition, generation int64) {
+	readyStatus := metav1.ConditionFalse
+	switch transition.State {
+	case v1alpha1.OnlineStatus:
+		readyStatus = metav1.ConditionTrue
+	case v1alpha1.InitializingStatus, v1alpha1.UnknownStatus:
+		readyStatus = metav1.ConditionUnknown
+	}
+	status.Status = transition.State
+	status.Healthy = transition.State == v1alpha1.OnlineStatus
+	status.LastUpdateTimestamp = metav1.Now()
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ClusterReadyCondition,
+		Status:             readyStatus,
+		ObservedGeneration: generation,
+		Reason:             transition.Reason,
+		Message:            transition.Message,
+	})
+}
+
+// SetHealthy sets the Healthy condition with the result of health endpoint in heartbeat
+func SetHealthy(status *v1alpha1.ClusterStatus, healthy bool, generation int64) {
+	condition := metav1.Condition{
+		Type:               v1alpha1.ClusterHealthyCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             healthEndpointOK,
+		Message:            healthEndpointOKMsg,
+	}
+	if !healthy {
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, healthEndpointFailed, healthEndpointMsg
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// SetProxyConditions sets the conditions reported by proxy by type, the last transition time only changes with
+// status. The Ready and Healthy conditions are owned by core and can not be reported
+func SetProxyConditions(status *v1alpha1.ClusterStatus, conditions []model.Condition, generation int64) {
+	for _, condition := range conditions {
+		if len(condition.Type) == 0 || condition.Type == v1alpha1.ClusterReadyCondition || condition.Type == v1alpha1.ClusterHealthyCondition {
+			continue
+		}
+		newCondition := metav1.Condition{
+			Type:               condition.Type,
+			Status:             condition.Status,
+			ObservedGeneration: generation,
+			LastTransitionTime: condition.Timestamp,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		}
+		if len(newCondition.Status) == 0 {
+			newCondition.Status = metav1.ConditionUnknown
+		}
+		if len(newCondition.Reason) == 0 {
+			newCondition.Reason = "Unknown"
+		}
+		meta.SetStatusCondition(&status.Conditions, newCondition)
+	}
+}
+
+// RemoveLegacyConditions drops the conditions which are written before conditions have status, they would fail
+// the validation of Cluster
+func RemoveLegacyConditions(status *v1alpha1.ClusterStatus) {
+	conditions := status.Conditions[:0]
+	for _, condition := range status.Conditions {
+		if len(condition.Status) > 0 && len(condition.Reason) > 0 {
+			conditions = append(conditions, condition)
+		}
+	}
+	status.Conditions = conditions
+}
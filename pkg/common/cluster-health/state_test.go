@@ -0,0 +1,152 @@
+package cluster_health
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var thresholds = Thresholds{
+	UnhealthyTolerance: time.Minute,
+	UnknownAfter:       time.Minute,
+	OfflineAfter:       3 * time.Minute,
+}
+
+func state(status *v1alpha1.ClusterStatus, transition *Transition) v1alpha1.ClusterStatusType {
+	if transition == nil {
+		return status.Status
+	}
+	SetState(status, transition, 1)
+	return status.Status
+}
+
+func TestOnHeartbeat(t *testing.T) {
+	now := time.Now()
+	status := &v1alpha1.ClusterStatus{Status: v1alpha1.InitializingStatus}
+
+	SetHealthy(status, true, 1)
+	if got := state(status, thresholds.OnHeartbeat(status, now)); got != v1alpha1.OnlineStatus {
+		t.Fatalf("healthy heartbeat: state = %s, want online", got)
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, v1alpha1.ClusterReadyCondition) || !status.Healthy {
+		t.Errorf("online cluster should be ready: %+v", status)
+	}
+
+	// an online cluster tolerates the short failure
+	SetHealthy(status, false, 1)
+	if got := state(status, thresholds.OnHeartbeat(status, now.Add(30*time.Second))); got != v1alpha1.OnlineStatus {
+		t.Errorf("unhealthy for 30s: state = %s, want online", got)
+	}
+	if got := state(status, thresholds.OnHeartbeat(status, now.Add(2*time.Minute))); got != v1alpha1.DegradedStatus {
+		t.Errorf("unhealthy for 2m: state = %s, want degraded", got)
+	}
+	if meta.IsStatusConditionTrue(status.Conditions, v1alpha1.ClusterReadyCondition) || status.Healthy {
+		t.Errorf("degraded cluster should not be ready: %+v", status)
+	}
+
+	SetHealthy(status, true, 1)
+	if got := state(status, thresholds.OnHeartbeat(status, now.Add(3*time.Minute))); got != v1alpha1.OnlineStatus {
+		t.Errorf("healthy again: state = %s, want online", got)
+	}
+}
+
+func TestOnHeartbeatUnhealthyReconnect(t *testing.T) {
+	status := &v1alpha1.ClusterStatus{Status: v1alpha1.UnknownStatus}
+	SetHealthy(status, false, 1)
+	if got := state(status, thresholds.OnHeartbeat(status, time.Now())); got != v1alpha1.DegradedStatus {
+		t.Errorf("state = %s, want degraded at once", got)
+	}
+}
+
+func TestOnCheck(t *testing.T) {
+	last := time.Now()
+	tests := []struct {
+		current v1alpha1.ClusterStatusType
+		since   time.Duration
+		want    v1alpha1.ClusterStatusType
+	}{
+		{current: v1alpha1.OnlineStatus, since: 30 * time.Second, want: v1alpha1.OnlineStatus},
+		{current: v1alpha1.OnlineStatus, since: 2 * time.Minute, want: v1alpha1.UnknownStatus},
+		{current: v1alpha1.DegradedStatus, since: 2 * time.Minute, want: v1alpha1.UnknownStatus},
+		{current: v1alpha1.UnknownStatus, since: 2 * time.Minute, want: v1alpha1.UnknownStatus},
+		{current: v1alpha1.UnknownStatus, since: 5 * time.Minute, want: v1alpha1.OfflineStatus},
+		{current: v1alpha1.OnlineStatus, since: 5 * time.Minute, want: v1alpha1.OfflineStatus},
+		{current: v1alpha1.InitializingStatus, since: 5 * time.Minute, want: v1alpha1.InitializingStatus},
+	}
+	for _, test := range tests {
+		status := &v1alpha1.ClusterStatus{Status: test.current, LastReceiveHeartBeatTimestamp: metav1.NewTime(last)}
+		if got := state(status, thresholds.OnCheck(status, last.Add(test.since))); got != test.want {
+			t.Errorf("%s without heartbeat for %s: state = %s, want %s", test.current, test.since, got, test.want)
+		}
+	}
+}
+
+func TestNextCheck(t *testing.T) {
+	last := time.Now()
+	for current, want := range map[v1alpha1.ClusterStatusType]time.Duration{
+		v1alpha1.OnlineStatus:   time.Minute,
+		v1alpha1.DegradedStatus: time.Minute,
+		v1alpha1.UnknownStatus:  3 * time.Minute,
+	} {
+		status := &v1alpha1.ClusterStatus{Status: current, LastReceiveHeartBeatTimestamp: metav1.NewTime(last)}
+		next, ok := thresholds.NextCheck(status)
+		if !ok || !next.Equal(last.Add(want)) {
+			t.Errorf("next check of %s = %s %v, want %s later", current, next, ok, want)
+			continue
+		}
+		// the state changes at the next check
+		if got := state(status, thresholds.OnCheck(status, next)); got == current {
+			t.Errorf("%s should change at next check", current)
+		}
+	}
+	for _, current := range []v1alpha1.ClusterStatusType{v1alpha1.InitializingStatus, v1alpha1.OfflineStatus} {
+		if _, ok := thresholds.NextCheck(&v1alpha1.ClusterStatus{Status: current}); ok {
+			t.Errorf("%s should only change on heartbeat", current)
+		}
+	}
+}
+
+func TestOnDisconnect(t *testing.T) {
+	for current, want := range map[v1alpha1.ClusterStatusType]v1alpha1.ClusterStatusType{
+		v1alpha1.OnlineStatus:       v1alpha1.UnknownStatus,
+		v1alpha1.DegradedStatus:     v1alpha1.UnknownStatus,
+		v1alpha1.OfflineStatus:      v1alpha1.OfflineStatus,
+		v1alpha1.InitializingStatus: v1alpha1.InitializingStatus,
+	} {
+		status := &v1alpha1.ClusterStatus{Status: current}
+		if got := state(status, OnDisconnect(status)); got != want {
+			t.Errorf("disconnect %s: state = %s, want %s", current, got, want)
+		}
+	}
+}
+
+func TestSetProxyConditions(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	status := &v1alpha1.ClusterStatus{Conditions: []metav1.Condition{
+		// written before conditions have status
+		{Type: "Ready", Reason: "ClusterReady"},
+		{Type: "EtcdHealthy", Status: metav1.ConditionTrue, Reason: "EtcdHealthy", LastTransitionTime: before},
+	}}
+	SetProxyConditions(status, []model.Condition{
+		{Type: "EtcdHealthy", Status: metav1.ConditionTrue, Reason: "EtcdHealthy", Message: "new", Timestamp: metav1.Now()},
+		{Type: "NodesReady", Reason: "NodesReady", Timestamp: metav1.Now()},
+		{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Reported"},
+	}, 1)
+	RemoveLegacyConditions(status)
+
+	if len(status.Conditions) != 2 {
+		t.Fatalf("conditions = %+v, want EtcdHealthy and NodesReady", status.Conditions)
+	}
+	etcd := meta.FindStatusCondition(status.Conditions, "EtcdHealthy")
+	if etcd.Message != "new" || !etcd.LastTransitionTime.Equal(&before) {
+		t.Errorf("EtcdHealthy = %+v, last transition time should be kept", etcd)
+	}
+	if nodes := meta.FindStatusCondition(status.Conditions, "NodesReady"); nodes.Status != metav1.ConditionUnknown {
+		t.Errorf("NodesReady = %+v, empty status should be unknown", nodes)
+	}
+}
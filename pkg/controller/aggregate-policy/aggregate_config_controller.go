@@ -0,0 +1,147 @@
+package aggregate_policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	controllerCommon "harmonycloud.cn/stellaris/pkg/controller/common"
+	coreHandler "harmonycloud.cn/stellaris/pkg/core/handler"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+// PolicyPushReconciler runs in core, it pushes the policies of a cluster namespace to the proxy of the cluster
+type PolicyPushReconciler struct {
+	client.Client
+	log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func (r *PolicyPushReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	clusterName := managerCommon.ClusterName(request.Namespace)
+	if len(clusterName) == 0 {
+		// only the policies in cluster namespaces are aggregated by proxies
+		return ctrl.Result{}, nil
+	}
+	r.log.Info(fmt.Sprintf("Reconciling MultiClusterResourceAggregatePolicy(%s:%s)", request.Namespace, request.Name))
+
+	policy := &v1alpha1.MultiClusterResourceAggregatePolicy{}
+	err := r.Client.Get(ctx, request.NamespacedName, policy)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	body := &model.SyncAggregateConfigResponse{}
+	resType := model.AggregateConfigUpdateOrCreate
+	if err != nil || !policy.GetDeletionTimestamp().IsZero() {
+		resType = model.AggregateConfigDelete
+		policy = &v1alpha1.MultiClusterResourceAggregatePolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		}
+	}
+	body.Policies = append(body.Policies, policy)
+	if err = pushAggregateConfig(clusterName, resType, body); err != nil {
+		// a resumed session does not get the policies again, so the push is retried until proxy is back
+		r.log.Error(err, fmt.Sprintf("push policy(%s:%s) to cluster(%s) failed", request.Namespace, request.Name, clusterName))
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *PolicyPushReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.MultiClusterResourceAggregatePolicy{}).
+		Complete(r)
+}
+
+// RulePushReconciler runs in core, rules are used by the policies of all clusters so they are pushed to every proxy
+type RulePushReconciler struct {
+	client.Client
+	log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func (r *RulePushReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	r.log.Info(fmt.Sprintf("Reconciling MultiClusterResourceAggregateRule(%s:%s)", request.Namespace, request.Name))
+
+	rule := &v1alpha1.MultiClusterResourceAggregateRule{}
+	err := r.Client.Get(ctx, request.NamespacedName, rule)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	body := &model.SyncAggregateConfigResponse{}
+	resType := model.AggregateConfigUpdateOrCreate
+	if err != nil || !rule.GetDeletionTimestamp().IsZero() {
+		resType = model.AggregateConfigDelete
+		rule = &v1alpha1.MultiClusterResourceAggregateRule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		}
+	}
+	body.Rules = append(body.Rules, rule)
+
+	clusterList := &v1alpha1.ClusterList{}
+	if err = r.Client.List(ctx, clusterList); err != nil {
+		return ctrl.Result{}, err
+	}
+	// the rule is pushed to every cluster whatever its state is, a degraded proxy is still connected, and a proxy
+	// which resumes its session after a short disconnection does not get the rules again
+	var errs []error
+	for _, cluster := range clusterList.Items {
+		if !cluster.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if err = pushAggregateConfig(cluster.Name, resType, body); err != nil {
+			r.log.Error(err, fmt.Sprintf("push rule(%s:%s) to cluster(%s) failed", request.Namespace, request.Name, cluster.Name))
+			errs = append(errs, err)
+		}
+	}
+	// the rule is pushed to every cluster again until all proxies are reached, applying it is idempotent
+	return ctrl.Result{}, utilerrors.NewAggregate(errs)
+}
+
+func (r *RulePushReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.MultiClusterResourceAggregateRule{}).
+		Complete(r)
+}
+
+func pushAggregateConfig(clusterName string, resType model.ServiceResponseType, body *model.SyncAggregateConfigResponse) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return coreHandler.SendResourceToProxy(clusterName, &config.Response{
+		Type:        resType.String(),
+		ClusterName: clusterName,
+		Body:        string(data),
+	})
+}
+
+// SetupPush only works in core, the proxies apply the pushed policies and rules
+func SetupPush(mgr ctrl.Manager, controllerCommon controllerCommon.Args) error {
+	policyReconciler := PolicyPushReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		log:    logf.Log.WithName("aggregate_policy_push_controller"),
+	}
+	if err := policyReconciler.SetupWithManager(mgr); err != nil {
+		return err
+	}
+	ruleReconciler := RulePushReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		log:    logf.Log.WithName("aggregate_rule_push_controller"),
+	}
+	return ruleReconciler.SetupWithManager(mgr)
+}
@@ -0,0 +1,101 @@
+package aggregate_policy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	controllerCommon "harmonycloud.cn/stellaris/pkg/controller/common"
+	"harmonycloud.cn/stellaris/pkg/proxy/aggregate"
+	"harmonycloud.cn/stellaris/pkg/proxy/send"
+)
+
+// Reconciler runs in proxy, it watches the aggregate policies and rules pushed by core and starts or stops
+// aggregating their resources
+type Reconciler struct {
+	client.Client
+	log     logr.Logger
+	Scheme  *runtime.Scheme
+	manager *aggregate.Manager
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	r.log.Info(fmt.Sprintf("Reconciling MultiClusterResourceAggregatePolicy(%s:%s)", request.Namespace, request.Name))
+
+	policy := &v1alpha1.MultiClusterResourceAggregatePolicy{}
+	err := r.Client.Get(ctx, request.NamespacedName, policy)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.manager.RemovePolicy(request.Namespace, request.Name)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !policy.GetDeletionTimestamp().IsZero() {
+		r.manager.RemovePolicy(request.Namespace, request.Name)
+		return ctrl.Result{}, nil
+	}
+
+	ruleList := &v1alpha1.MultiClusterResourceAggregateRuleList{}
+	if err = r.Client.List(ctx, ruleList); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.manager.SetPolicy(policy, ruleList.Items)
+	return ctrl.Result{}, nil
+}
+
+// policiesOfRule maps the rule to the policies which reference it
+func (r *Reconciler) policiesOfRule(object client.Object) []reconcile.Request {
+	policyList := &v1alpha1.MultiClusterResourceAggregatePolicyList{}
+	if err := r.Client.List(context.Background(), policyList); err != nil {
+		r.log.Error(err, "list MultiClusterResourceAggregatePolicy failed")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, policy := range policyList.Items {
+		for _, ruleName := range policy.Spec.AggregateRules {
+			if ruleName == object.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.MultiClusterResourceAggregatePolicy{}).
+		Watches(&source.Kind{Type: &v1alpha1.MultiClusterResourceAggregateRule{}},
+			handler.EnqueueRequestsFromMapFunc(r.policiesOfRule)).
+		Complete(r)
+}
+
+// Setup only works in proxy, core receives the aggregated results
+func Setup(mgr ctrl.Manager, controllerCommon controllerCommon.Args) error {
+	dynamicClient, err := dynamic.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	manager := aggregate.NewManager(dynamicClient, mgr.GetRESTMapper(), send.SendAggregateRequest)
+	if err = mgr.Add(manager); err != nil {
+		return err
+	}
+	reconciler := Reconciler{
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		log:     logf.Log.WithName("aggregate_policy_controller"),
+		manager: manager,
+	}
+	return reconciler.SetupWithManager(mgr)
+}
@@ -58,9 +58,13 @@ func (r *Reconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.
 }
 
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.ClusterResource{}).
-		Complete(r)
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterResource{})
+	if r.isControlPlane {
+		// status is written by proxy report and delivery ack, it should not cause another delivery
+		builder = builder.WithEventFilter(ignoreStatusUpdatePredicate())
+	}
+	return builder.Complete(r)
 }
 
 func Setup(mgr ctrl.Manager, controllerCommon controllerCommon.Args) error {
@@ -83,11 +87,16 @@ func (r *Reconciler) syncClusterResource(ctx context.Context, instance *v1alpha1
 }
 
 func (r *Reconciler) syncCoreClusterResource(ctx context.Context, instance *v1alpha1.ClusterResource) (ctrl.Result, error) {
-	err := sendClusterResourceToProxy(SyncEventTypeUpdate, instance)
+	delivery, err := sendClusterResourceToProxy(SyncEventTypeUpdate, instance)
 	if err != nil {
 		r.log.Error(err, fmt.Sprintf("send ClusterResouce failed, resource(%s)", instance.Name))
 		return controllerCommon.ReQueueResult(err)
 	}
+	err = updateClusterResourceDelivery(ctx, r.Client, instance, delivery)
+	if err != nil {
+		r.log.Error(err, fmt.Sprintf("update delivery state of ClusterResource(%s:%s) failed", instance.Namespace, instance.Name))
+		return controllerCommon.ReQueueResult(err)
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -122,8 +131,8 @@ func (r *Reconciler) deleteClusterResource(ctx context.Context, instance *v1alph
 			}
 		}
 	} else {
-		// send proxy the clusterResource delete event
-		err := sendClusterResourceToProxy(SyncEventTypeDelete, instance)
+		// send proxy the clusterResource delete event, core keeps retrying it after finalizer is removed
+		_, err := sendClusterResourceToProxy(SyncEventTypeDelete, instance)
 		if err != nil {
 			r.log.Error(err, fmt.Sprintf("send ClusterResouce failed, resource(%s)", instance.Name))
 			return err
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -169,6 +170,15 @@ func sendStatusToControlPlane(resource *v1alpha1.ClusterResource) error {
 	return proxy_send.SendSyncResourceRequest(request)
 }
 
+// ReportClusterResourceStatus sends the status of ClusterResources again, it is used when core has stale status after resync
+func ReportClusterResourceStatus(clusterResourceList []*v1alpha1.ClusterResource) error {
+	request, err := newUpdateClusterResourceStatusRequest(clusterResourceList, proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	return proxy_send.SendSyncResourceRequest(request)
+}
+
 func newUpdateClusterResourceStatusRequest(clusterResourceList []*v1alpha1.ClusterResource, clusterName string) (*config.Request, error) {
 	request := &model.ResourceRequest{}
 	for _, clusterResource := range clusterResourceList {
@@ -185,30 +195,33 @@ func newUpdateClusterResourceStatusRequest(clusterResourceList []*v1alpha1.Clust
 	return proxy_send.NewResourceRequest(model.Resource, clusterName, string(requestData))
 }
 
-// send clusterResource to proxy
-func sendClusterResourceToProxy(eventType SyncEventType, clusterResource *v1alpha1.ClusterResource) error {
+// send clusterResource to proxy, the delivery is retried by core until proxy acks
+func sendClusterResourceToProxy(eventType SyncEventType, clusterResource *v1alpha1.ClusterResource) (*v1alpha1.ClusterResourceDelivery, error) {
 	clusterName := managerCommon.ClusterName(clusterResource.Namespace)
 	if len(clusterName) == 0 {
-		return errors.New("can not find cluster name")
+		return nil, errors.New("can not find cluster name")
 	}
 	resType := model.ResourceUpdateOrCreate
 	if eventType == SyncEventTypeDelete {
 		resType = model.ResourceDelete
 	}
-	syncResourceResponse, err := newSyncResourceResponse(resType, clusterName, clusterResource)
-	if err != nil {
-		return err
-	}
-	return coreHandler.SendResourceToProxy(clusterName, syncResourceResponse)
+	return coreHandler.DeliverResourceToProxy(clusterName, resType, clusterResource)
 }
 
-func newSyncResourceResponse(resType model.ServiceResponseType, clusterName string, clusterResource *v1alpha1.ClusterResource) (*config.Response, error) {
-	responseModel := &model.SyncResourceResponse{ClusterResourceList: []*v1alpha1.ClusterResource{clusterResource}}
-	data, err := json.Marshal(responseModel)
-	if err != nil {
-		return nil, err
-	}
-	return coreHandler.NewResourceResponse(resType, clusterName, string(data))
+// updateClusterResourceDelivery records the delivery state, it is skipped when the ack of the delivery is recorded already
+func updateClusterResourceDelivery(ctx context.Context, clientSet client.Client, clusterResource *v1alpha1.ClusterResource, state *v1alpha1.ClusterResourceDelivery) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		instance := &v1alpha1.ClusterResource{}
+		err := clientSet.Get(ctx, types.NamespacedName{Namespace: clusterResource.Namespace, Name: clusterResource.Name}, instance)
+		if err != nil {
+			return err
+		}
+		if instance.Status.Delivery != nil && instance.Status.Delivery.RequestID == state.RequestID {
+			return nil
+		}
+		instance.Status.Delivery = state
+		return clientSet.Status().Update(ctx, instance)
+	})
 }
 
 // sync Resource when create/update/delete
@@ -7,11 +7,14 @@ import (
 
 	"github.com/go-logr/logr"
 	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	clusterHealth "harmonycloud.cn/stellaris/pkg/common/cluster-health"
 	controllerCommon "harmonycloud.cn/stellaris/pkg/controller/common"
 	"harmonycloud.cn/stellaris/pkg/utils"
+	"harmonycloud.cn/stellaris/pkg/utils/taint"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
@@ -20,13 +23,29 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	AutoDeployProxyAnnotationKey   = "auto.deploy/stellaris.harmonycloud.cn"
 	AutoDeployProxyAnnotationValue = "true"
 	AutoDeployCueTemplateField     = "deploy-proxy.cue"
+
+	// UninstallNamespaceAnnotationKey makes the namespace of proxy in member cluster deleted with the cluster
+	UninstallNamespaceAnnotationKey   = "uninstall.namespace/stellaris.harmonycloud.cn"
+	UninstallNamespaceAnnotationValue = "true"
+	// ForceDeleteAnnotationKey lets cluster be deleted when proxy can not be uninstalled from member cluster
+	ForceDeleteAnnotationKey   = "force.delete/stellaris.harmonycloud.cn"
+	ForceDeleteAnnotationValue = "true"
+	// SkipDrainAnnotationKey lets cluster be deleted without moving its workloads to other clusters
+	SkipDrainAnnotationKey   = "skip.drain/stellaris.harmonycloud.cn"
+	SkipDrainAnnotationValue = "true"
+
+	// proxyResyncPeriod is how often the proxy resources are applied again to correct drift in member cluster
+	proxyResyncPeriod = 10 * time.Minute
 )
 
 type ClusterReconciler struct {
@@ -35,6 +54,8 @@ type ClusterReconciler struct {
 	log                logr.Logger
 	Recorder           record.EventRecorder
 	tmplNamespacedName types.NamespacedName
+	// memberClient connects member cluster, the credentials in secretRef of cluster are used when it is nil
+	memberClient func(cluster *v1alpha1.Cluster) (dynamic.Interface, error)
 }
 
 func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -58,26 +79,50 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	// delete event need delete cluster
 	if !cluster.DeletionTimestamp.IsZero() {
+		// the workloads are moved to other clusters before cluster is removed
+		if cluster.Annotations[SkipDrainAnnotationKey] != SkipDrainAnnotationValue {
+			drained, err := r.drainCluster(ctx, cluster)
+			if err != nil {
+				r.log.Error(err, "failed drain cluster", "clusterName", cluster.Name)
+				r.Recorder.Event(cluster, "Warning", "FailedDrainCluster", fmt.Sprintf("failed drain cluster: %s", err))
+				return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
+			}
+			if !drained {
+				return ctrl.Result{RequeueAfter: drainCheckPeriod}, nil
+			}
+		}
 		if err := r.deleteCluster(ctx, cluster); err != nil {
 			r.log.Error(err, "failed delete cluster", "clusterName", cluster.Name)
 			r.Recorder.Event(cluster, "Warning", "FailedDeleteCluster", fmt.Sprintf("failed delete cluster: %s", err))
 			return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
 		}
+		return ctrl.Result{}, nil
 	}
 
-	// auto deploy proxy
-	if cluster.Annotations[AutoDeployProxyAnnotationKey] == AutoDeployProxyAnnotationValue &&
-		(cluster.Status.Status == "" || cluster.Status.Status == v1alpha1.InitializingStatus) {
-		cluster.Status.Status = v1alpha1.InitializingStatus
-		if err := r.Status().Update(ctx, cluster); err != nil {
-			r.log.Error(err, "failed update cluster status", "clusterName", cluster.Name)
-			return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
+	// evict the workloads which do not tolerate the NoExecute taints of cluster
+	if err := r.evictCluster(ctx, cluster); err != nil {
+		r.log.Error(err, "failed evict cluster", "clusterName", cluster.Name)
+		r.Recorder.Event(cluster, "Warning", "FailedEvictCluster", fmt.Sprintf("failed evict cluster: %s", err))
+		return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
+	}
+
+	// auto deploy proxy, it is upgraded when the template or the configuration changes
+	var requeueAfter time.Duration
+	if cluster.Annotations[AutoDeployProxyAnnotationKey] == AutoDeployProxyAnnotationValue {
+		if cluster.Status.Status == "" {
+			clusterHealth.SetState(&cluster.Status, clusterHealth.Initializing(), cluster.Generation)
+			clusterHealth.RemoveLegacyConditions(&cluster.Status)
+			if err := r.Status().Update(ctx, cluster); err != nil {
+				r.log.Error(err, "failed update cluster status", "clusterName", cluster.Name)
+				return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
+			}
 		}
 		if err := r.deployProxy(ctx, cluster); err != nil {
 			r.log.Error(err, "failed deploy proxy to target cluster", "clusterName", cluster.Name)
 			r.Recorder.Event(cluster, "Warning", "FailedDeployProxy", fmt.Sprintf("failed deploy proxy to target cluster: %s: %s", cluster.Spec.ApiServer, err))
 			return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
 		}
+		requeueAfter = proxyResyncPeriod
 	}
 
 	// create namespace in control plane
@@ -87,11 +132,28 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, err
 	}
 
-	return ctrl.Result{}, nil
+	// the taint of maintenance window changes when the window starts or ends
+	if next, ok := taint.NextMaintenanceChange(cluster, time.Now()); ok && (requeueAfter == 0 || next < requeueAfter) {
+		requeueAfter = next
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-// deleteCluster will delete cluster in control plane
+// deleteCluster will delete cluster in control plane, the auto deployed proxy is uninstalled from member cluster first
+// so that it does not register the cluster again
 func (r *ClusterReconciler) deleteCluster(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	if cluster.Annotations[AutoDeployProxyAnnotationKey] == AutoDeployProxyAnnotationValue {
+		if err := r.uninstallProxy(ctx, cluster); err != nil {
+			if cluster.Annotations[ForceDeleteAnnotationKey] != ForceDeleteAnnotationValue {
+				return fmt.Errorf("uninstall proxy from member cluster: %s, annotate cluster with %s=%s to delete it anyway",
+					err, ForceDeleteAnnotationKey, ForceDeleteAnnotationValue)
+			}
+			r.Recorder.Event(cluster, "Warning", "FailedUninstallProxy", fmt.Sprintf("proxy is left in member cluster by force delete: %s", err))
+		} else {
+			r.Recorder.Event(cluster, "Normal", "ProxyUninstalled", "proxy is uninstalled from member cluster")
+		}
+	}
+
 	// delete namespace in control plane
 	if err := r.Client.Delete(ctx, utils.GenerateNamespaceInControlPlane(cluster)); err != nil && !errors.IsNotFound(err) {
 		return err
@@ -147,71 +209,181 @@ func (r *ClusterReconciler) kubeconfigGetterForStellarisCluster(cluster *v1alpha
 	}
 }
 
+// deployProxy renders the CUE template and applies the proxy resources to member cluster with server-side apply,
+// the outputs removed from template are pruned. Nothing is applied when the template, configuration and addons are
+// not changed, until the resources are applied again to correct drift
 func (r *ClusterReconciler) deployProxy(ctx context.Context, cluster *v1alpha1.Cluster) error {
-	cfg, err := clientcmd.BuildConfigFromKubeconfigGetter(cluster.Spec.ApiServer, r.kubeconfigGetterForStellarisCluster(cluster))
+	clusterTemplate, err := r.proxyTemplate(ctx)
 	if err != nil {
 		return err
 	}
-	c, err := dynamic.NewForConfig(cfg)
+
+	proxyBuilder, err := NewProxyBuilder(cluster)
 	if err != nil {
 		return err
 	}
-
-	cm := &corev1.ConfigMap{}
-	if err := r.Get(ctx, r.tmplNamespacedName, cm); err != nil {
+	hash, err := proxyTemplateHash(clusterTemplate, cluster)
+	if err != nil {
 		return err
 	}
-
-	clusterTemplate, exist := cm.Data[AutoDeployCueTemplateField]
-	if !exist {
-		return fmt.Errorf("cannot find cluster template in ConfigMap %s field %s", r.tmplNamespacedName, AutoDeployCueTemplateField)
+	last := cluster.Status.Proxy
+	if last != nil && last.TemplateHash == hash && time.Since(last.LastAppliedTime.Time) < proxyResyncPeriod {
+		return nil
 	}
 
-	proxyBuilder, err := NewProxyBuilder(cluster)
+	outputs, err := proxyBuilder.GenerateProxyResources(clusterTemplate)
 	if err != nil {
 		return err
 	}
-	resources, err := proxyBuilder.GenerateProxyResources(clusterTemplate)
+	objects, err := proxyObjects(proxyBuilder, outputs)
 	if err != nil {
 		return err
 	}
 
-	// create namespace first
-	namespace := proxyBuilder.GenerateNamespaces()
-	err = r.Client.Create(ctx, namespace)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	c, err := r.memberClusterClient(cluster)
+	if err != nil {
 		return err
 	}
 
-	// create proxy addons configmap
-	proxyAddonsCfg, err := proxyBuilder.GenerateAddonsConfigMap()
-	if err != nil {
-		return err
+	// namespace and addons ConfigMap are applied first, the outputs are in order of their keys
+	for _, obj := range objects {
+		if err = applyProxyResource(ctx, c, obj); err != nil {
+			return fmt.Errorf("apply %s %s/%s failed: %s", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
 	}
-	err = r.Client.Create(ctx, proxyAddonsCfg)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	resources := proxyResourcesOf(objects[len(objects)-len(outputs):])
+	if last != nil {
+		for _, resource := range staleProxyResources(last.Resources, resources) {
+			if err = deleteProxyResource(ctx, c, resource); err != nil {
+				return fmt.Errorf("prune %s %s/%s failed: %s", resource.Kind, resource.Namespace, resource.Name, err)
+			}
+			r.log.Info("pruned proxy resource", "clusterName", cluster.Name, "kind", resource.Kind, "namespace", resource.Namespace, "name", resource.Name)
+		}
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+	cluster.Status.Proxy = &v1alpha1.ClusterProxyStatus{
+		TemplateHash:    hash,
+		LastAppliedTime: metav1.Now(),
+		Resources:       resources,
+	}
+	if err = r.Status().Patch(ctx, cluster, patch); err != nil {
 		return err
 	}
+	switch {
+	case last == nil || len(last.TemplateHash) == 0:
+		r.Recorder.Event(cluster, "Normal", "ProxyDeployed", fmt.Sprintf("proxy is deployed with template %s", hash))
+	case last.TemplateHash != hash:
+		r.Recorder.Event(cluster, "Normal", "ProxyUpgraded", fmt.Sprintf("proxy is upgraded from template %s to %s", last.TemplateHash, hash))
+	}
+	return nil
+}
 
-	// create proxy all resources
-	for _, resource := range resources {
-		if _, err := c.Resource(utils.GroupVersionResourceFromUnstructured(resource)).Namespace(resource.GetNamespace()).Create(ctx, resource, metav1.CreateOptions{}); err != nil {
-			if errors.IsAlreadyExists(err) {
-				continue
-			}
+// uninstallProxy deletes the outputs of template and the addons ConfigMap from member cluster, the resources
+// recorded in status are deleted too in case the template has changed since last apply
+func (r *ClusterReconciler) uninstallProxy(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	proxyBuilder, err := NewProxyBuilder(cluster)
+	if err != nil {
+		return err
+	}
+	var resources []v1alpha1.ProxyResource
+	if cluster.Status.Proxy != nil {
+		resources = append(resources, cluster.Status.Proxy.Resources...)
+	}
+	outputs, err := r.renderProxy(ctx, proxyBuilder)
+	if err != nil {
+		if len(resources) == 0 {
 			return err
 		}
+		r.log.Error(err, "failed render proxy template, only the recorded resources are deleted", "clusterName", cluster.Name)
+	}
+	resources = append(resources, proxyResourcesOf(sortedOutputs(outputs))...)
+	resources = append(resources, v1alpha1.ProxyResource{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  proxyBuilder.ConfigurationNamespace,
+		Name:       proxyBuilder.ConfigurationName,
+	})
+	if cluster.Annotations[UninstallNamespaceAnnotationKey] == UninstallNamespaceAnnotationValue {
+		resources = append(resources, v1alpha1.ProxyResource{APIVersion: "v1", Kind: "Namespace", Name: proxyBuilder.ConfigurationNamespace})
 	}
 
+	c, err := r.memberClusterClient(cluster)
+	if err != nil {
+		return err
+	}
+	// the resources are deleted in reverse order of apply, the recorded ones may duplicate the rendered ones
+	deleted := make(map[v1alpha1.ProxyResource]bool)
+	for i := len(resources) - 1; i >= 0; i-- {
+		if deleted[resources[i]] {
+			continue
+		}
+		deleted[resources[i]] = true
+		if err = deleteProxyResource(ctx, c, resources[i]); err != nil {
+			return fmt.Errorf("delete %s %s/%s failed: %s", resources[i].Kind, resources[i].Namespace, resources[i].Name, err)
+		}
+	}
 	return nil
 }
 
+func (r *ClusterReconciler) renderProxy(ctx context.Context, proxyBuilder *ProxyBuilder) (map[string]*unstructured.Unstructured, error) {
+	clusterTemplate, err := r.proxyTemplate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return proxyBuilder.GenerateProxyResources(clusterTemplate)
+}
+
+func (r *ClusterReconciler) proxyTemplate(ctx context.Context) (string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, r.tmplNamespacedName, cm); err != nil {
+		return "", err
+	}
+	clusterTemplate, exist := cm.Data[AutoDeployCueTemplateField]
+	if !exist {
+		return "", fmt.Errorf("cannot find cluster template in ConfigMap %s field %s", r.tmplNamespacedName, AutoDeployCueTemplateField)
+	}
+	return clusterTemplate, nil
+}
+
+// memberClusterClient connects member cluster with the credentials in secretRef of cluster
+func (r *ClusterReconciler) memberClusterClient(cluster *v1alpha1.Cluster) (dynamic.Interface, error) {
+	if r.memberClient != nil {
+		return r.memberClient(cluster)
+	}
+	cfg, err := clientcmd.BuildConfigFromKubeconfigGetter(cluster.Spec.ApiServer, r.kubeconfigGetterForStellarisCluster(cluster))
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(cfg)
+}
+
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.Cluster{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.clustersOfTemplate)).
 		Complete(r)
 }
 
+// clustersOfTemplate enqueues the clusters whose proxy is auto deployed when the template ConfigMap changes
+func (r *ClusterReconciler) clustersOfTemplate(obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != r.tmplNamespacedName.Namespace || obj.GetName() != r.tmplNamespacedName.Name {
+		return nil
+	}
+	clusterList := &v1alpha1.ClusterList{}
+	if err := r.List(context.Background(), clusterList); err != nil {
+		r.log.Error(err, "failed list clusters for template change")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, cluster := range clusterList.Items {
+		if cluster.Annotations[AutoDeployProxyAnnotationKey] == AutoDeployProxyAnnotationValue {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cluster.Name}})
+		}
+	}
+	return requests
+}
+
 func Setup(mgr ctrl.Manager, controllerCommon controllerCommon.Args) error {
 	reconciler := ClusterReconciler{
 		Client:             mgr.GetClient(),
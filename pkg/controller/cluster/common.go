@@ -9,27 +9,23 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const (
-	ConditionMaximumLength = 10
-)
-
-func OfflineCluster(ctx context.Context, client *multclusterclient.Clientset, cluster *v1alpha1.Cluster) error {
-	if cluster.Status.Status == v1alpha1.OfflineStatus {
+// TransitCluster changes the state of cluster, nothing is updated when transition is nil
+func TransitCluster(ctx context.Context, client *multclusterclient.Clientset, cluster *v1alpha1.Cluster, transition *clusterHealth.Transition) error {
+	if transition == nil {
 		return nil
 	}
-	cluster.Status.Status = v1alpha1.OfflineStatus
-	cluster.Status.LastUpdateTimestamp = metav1.Now()
-	conditions := clusterHealth.GenerateReadyCondition(false, false)
-	cluster.Status.Conditions = append(cluster.Status.Conditions, conditions...)
-
+	clusterHealth.SetState(&cluster.Status, transition, cluster.Generation)
 	_, err := UpdateClusterStatus(ctx, client, cluster)
 	return err
 }
 
+// DisconnectCluster changes online or degraded cluster to unknown when the stream of proxy is disconnected
+func DisconnectCluster(ctx context.Context, client *multclusterclient.Clientset, cluster *v1alpha1.Cluster) error {
+	return TransitCluster(ctx, client, cluster, clusterHealth.OnDisconnect(&cluster.Status))
+}
+
 func UpdateClusterStatus(ctx context.Context, client *multclusterclient.Clientset, cluster *v1alpha1.Cluster) (*v1alpha1.Cluster, error) {
-	if len(cluster.Status.Conditions) > ConditionMaximumLength {
-		cluster.Status.Conditions = cluster.Status.Conditions[len(cluster.Status.Conditions)-ConditionMaximumLength:]
-	}
+	clusterHealth.RemoveLegacyConditions(&cluster.Status)
 	return client.MulticlusterV1alpha1().Clusters().UpdateStatus(ctx, cluster, metav1.UpdateOptions{})
 }
 
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DrainClusterAnnotationKey is set to the policies rescheduled for draining cluster, the value is the cluster name.
+	// The update of annotation triggers the schedule even if the policy has been rescheduled before
+	DrainClusterAnnotationKey = "drain.cluster/stellaris.harmonycloud.cn"
+
+	// drainCheckPeriod is how often the progress of draining is checked
+	drainCheckPeriod = 10 * time.Second
+)
+
+// drainCluster reschedules the policies bound to the deleted cluster and waits for their ClusterResources to be
+// complete in other clusters, it returns true when cluster is drained. The progress is reported in status
+func (r *ClusterReconciler) drainCluster(ctx context.Context, cluster *v1alpha1.Cluster) (bool, error) {
+	if cluster.Status.Decommission != nil && cluster.Status.Decommission.Phase == v1alpha1.DecommissionDrained {
+		return true, nil
+	}
+
+	bindingList := &v1alpha1.MultiClusterResourceBindingList{}
+	if err := r.List(ctx, bindingList); err != nil {
+		return false, err
+	}
+	decommission := &v1alpha1.ClusterDecommissionStatus{Phase: v1alpha1.DecommissionDraining, StartTime: metav1.Now()}
+	if cluster.Status.Decommission != nil {
+		decommission.StartTime = cluster.Status.Decommission.StartTime
+		decommission.Bindings = append(decommission.Bindings, cluster.Status.Decommission.Bindings...)
+	}
+	tracked := make(map[string]bool, len(decommission.Bindings))
+	for _, key := range decommission.Bindings {
+		tracked[key] = true
+	}
+
+	// the bindings still bound to cluster wait for their policies to be rescheduled, the ones not created by
+	// scheduler are reported only, nothing moves them
+	pending := make(map[string]bool)
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		if !bindsToCluster(binding, cluster.Name) {
+			continue
+		}
+		key := types.NamespacedName{Namespace: binding.Namespace, Name: binding.Name}.String()
+		managed, err := r.reschedulePolicyOf(ctx, binding, cluster.Name)
+		if err != nil {
+			return false, fmt.Errorf("reschedule policy of binding %s failed: %s", key, err)
+		}
+		if !managed {
+			decommission.UnmanagedBindings = append(decommission.UnmanagedBindings, key)
+			continue
+		}
+		pending[key] = true
+		if !tracked[key] {
+			tracked[key] = true
+			decommission.Bindings = append(decommission.Bindings, key)
+		}
+	}
+	sort.Strings(decommission.Bindings)
+	sort.Strings(decommission.UnmanagedBindings)
+
+	// the rescheduled bindings wait for their ClusterResources to be complete in other clusters
+	var notComplete []string
+	for _, key := range decommission.Bindings {
+		if pending[key] {
+			continue
+		}
+		i := strings.LastIndex(key, "/")
+		binding := &v1alpha1.MultiClusterResourceBinding{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: key[:i], Name: key[i+1:]}, binding); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		// ClusterResources are labeled with the name of binding only, the bindings of the same name in other
+		// namespaces are told apart by the owner
+		resourceList := &v1alpha1.ClusterResourceList{}
+		if err := r.List(ctx, resourceList, client.MatchingLabels{managerCommon.ResourceBindingLabelName: binding.Name}); err != nil {
+			return false, err
+		}
+		for _, resource := range pendingClusterResources(resourceList.Items, binding, cluster.Name) {
+			notComplete = append(notComplete, resource.Namespace+"/"+resource.Name)
+		}
+	}
+
+	decommission.PendingBindings = int32(len(pending))
+	decommission.PendingResources = int32(len(notComplete))
+	switch {
+	case len(pending) > 0:
+		decommission.Message = fmt.Sprintf("waiting for %d bindings to be rescheduled to other clusters", len(pending))
+	case len(notComplete) > 0:
+		decommission.Message = fmt.Sprintf("waiting for %d cluster resources to be complete in other clusters, e.g. %s", len(notComplete), notComplete[0])
+	default:
+		decommission.Phase = v1alpha1.DecommissionDrained
+		decommission.Message = fmt.Sprintf("%d bindings are rescheduled to other clusters", len(decommission.Bindings))
+	}
+	if len(decommission.UnmanagedBindings) > 0 {
+		decommission.Message += fmt.Sprintf(", %d bindings not created by scheduler are not rescheduled, e.g. %s",
+			len(decommission.UnmanagedBindings), decommission.UnmanagedBindings[0])
+	}
+
+	last := cluster.Status.Decommission
+	patch := client.MergeFrom(cluster.DeepCopy())
+	cluster.Status.Decommission = decommission
+	if err := r.Status().Patch(ctx, cluster, patch); err != nil {
+		return false, err
+	}
+	switch {
+	case last == nil:
+		r.Recorder.Event(cluster, "Normal", "ClusterDraining", fmt.Sprintf("cluster is draining, %d bindings are rescheduled", len(pending)))
+	case decommission.Phase == v1alpha1.DecommissionDrained && len(decommission.UnmanagedBindings) > 0:
+		r.Recorder.Event(cluster, "Warning", "ClusterDrained", decommission.Message)
+	case decommission.Phase == v1alpha1.DecommissionDrained:
+		r.Recorder.Event(cluster, "Normal", "ClusterDrained", decommission.Message)
+	}
+	return decommission.Phase == v1alpha1.DecommissionDrained, nil
+}
+
+// reschedulePolicyOf marks the policy which owns binding to be rescheduled, false is returned when binding is not
+// created by scheduler, it is left to its creator
+func (r *ClusterReconciler) reschedulePolicyOf(ctx context.Context, binding *v1alpha1.MultiClusterResourceBinding, clusterName string) (bool, error) {
+	policy, err := r.policyOf(ctx, binding)
+	if err != nil || policy == nil {
+		return false, err
+	}
+	return true, r.reschedulePolicy(ctx, policy, DrainClusterAnnotationKey, clusterName)
+}
+
+// policyOf is the policy which owns binding, nil is returned when binding is not created by scheduler
+func (r *ClusterReconciler) policyOf(ctx context.Context, binding *v1alpha1.MultiClusterResourceBinding) (*v1alpha1.MultiClusterResourceSchedulePolicy, error) {
+	owner := metav1.GetControllerOf(binding)
+	if owner == nil || owner.Kind != v1alpha1.MultiClusterResourceSchedulePolicyGroupVersionKind.Kind {
+		return nil, nil
+	}
+	policy := &v1alpha1.MultiClusterResourceSchedulePolicy{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: owner.Name}, policy); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return policy, nil
+}
+
+// reschedulePolicy sets Reschedule of policy with the annotation, the policy is updated only once for the same value
+func (r *ClusterReconciler) reschedulePolicy(ctx context.Context, policy *v1alpha1.MultiClusterResourceSchedulePolicy, key, value string) error {
+	if policy.Spec.Reschedule && policy.Annotations[key] == value {
+		return nil
+	}
+	policy.Spec.Reschedule = true
+	if policy.Annotations == nil {
+		policy.Annotations = map[string]string{}
+	}
+	policy.Annotations[key] = value
+	err := r.Update(ctx, policy)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func bindsToCluster(binding *v1alpha1.MultiClusterResourceBinding, clusterName string) bool {
+	for _, resource := range binding.Spec.Resources {
+		for _, cluster := range resource.Clusters {
+			if cluster.Name == clusterName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pendingClusterResources are the ClusterResources of binding in other clusters which are not complete
+func pendingClusterResources(resources []v1alpha1.ClusterResource, binding *v1alpha1.MultiClusterResourceBinding, clusterName string) []v1alpha1.ClusterResource {
+	clusterNamespace := managerCommon.ClusterNamespace(clusterName)
+	var pending []v1alpha1.ClusterResource
+	for i := range resources {
+		resource := resources[i]
+		if resource.Namespace == clusterNamespace || !resource.DeletionTimestamp.IsZero() || !metav1.IsControlledBy(&resource, binding) {
+			continue
+		}
+		if resource.Status.Phase != common.Complete {
+			pending = append(pending, resource)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestDrainCluster(t *testing.T) {
+	policy := &v1alpha1.MultiClusterResourceSchedulePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web", UID: "policy-uid"},
+	}
+	bound := testBinding("app", "web", "binding-uid", "member", policy)
+	moved := testBinding("app", "web", "binding-uid", "other", policy)
+	unmanaged := testBinding("app", "manual", "manual-uid", "member", nil)
+	// the binding of the same name in another namespace
+	foreign := testBinding("other-app", "web", "foreign-uid", "other", nil)
+	tracked := &v1alpha1.ClusterDecommissionStatus{Phase: v1alpha1.DecommissionDraining, Bindings: []string{"app/web"}}
+
+	tests := []struct {
+		name         string
+		decommission *v1alpha1.ClusterDecommissionStatus
+		objects      []client.Object
+		drained      bool
+		pending      int32
+		resources    int32
+		unmanaged    []string
+		reschedule   bool
+	}{
+		{
+			name:       "binding still bound is rescheduled",
+			objects:    []client.Object{policy.DeepCopy(), bound},
+			pending:    1,
+			reschedule: true,
+		},
+		{
+			name:         "rescheduled binding waits for its resources to be complete",
+			decommission: tracked,
+			objects:      []client.Object{policy.DeepCopy(), moved, testClusterResource(moved, "other", common.Creating)},
+			resources:    1,
+		},
+		{
+			name:         "drained when the resources are complete",
+			decommission: tracked,
+			objects:      []client.Object{policy.DeepCopy(), moved, testClusterResource(moved, "other", common.Complete)},
+			drained:      true,
+		},
+		{
+			name:         "resources of binding in other namespace are not waited for",
+			decommission: tracked,
+			objects: []client.Object{policy.DeepCopy(), moved, foreign,
+				testClusterResource(moved, "other", common.Complete), testClusterResource(foreign, "other", common.Creating)},
+			drained: true,
+		},
+		{
+			name:      "unmanaged binding does not block drain",
+			objects:   []client.Object{unmanaged},
+			drained:   true,
+			unmanaged: []string{"app/manual"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &v1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member"}}
+			cluster.Status.Decommission = tt.decommission.DeepCopy()
+			r := testReconciler(append(tt.objects, cluster)...)
+
+			drained, err := r.drainCluster(context.TODO(), cluster)
+			if err != nil {
+				t.Fatalf("drain cluster: %v", err)
+			}
+			if drained != tt.drained {
+				t.Errorf("drained = %v, want %v", drained, tt.drained)
+			}
+			decommission := cluster.Status.Decommission
+			if decommission.PendingBindings != tt.pending || decommission.PendingResources != tt.resources {
+				t.Errorf("pending bindings %d resources %d, want %d %d: %s",
+					decommission.PendingBindings, decommission.PendingResources, tt.pending, tt.resources, decommission.Message)
+			}
+			if strings.Join(decommission.UnmanagedBindings, ",") != strings.Join(tt.unmanaged, ",") {
+				t.Errorf("unmanaged bindings = %v, want %v", decommission.UnmanagedBindings, tt.unmanaged)
+			}
+
+			got := &v1alpha1.MultiClusterResourceSchedulePolicy{}
+			err = r.Get(context.TODO(), types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, got)
+			if client.IgnoreNotFound(err) != nil {
+				t.Fatalf("get policy: %v", err)
+			}
+			if rescheduled := got.Annotations[DrainClusterAnnotationKey] == "member"; rescheduled != tt.reschedule || got.Spec.Reschedule != tt.reschedule {
+				t.Errorf("policy reschedule = %v annotations %v, want %v", got.Spec.Reschedule, got.Annotations, tt.reschedule)
+			}
+		})
+	}
+}
+
+func testReconciler(objects ...client.Object) *ClusterReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	return &ClusterReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Scheme:   scheme,
+		log:      logf.Log.WithName("cluster_controller"),
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func testBinding(namespace, name string, uid types.UID, clusterName string, policy *v1alpha1.MultiClusterResourceSchedulePolicy) *v1alpha1.MultiClusterResourceBinding {
+	binding := &v1alpha1.MultiClusterResourceBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: uid},
+		Spec: v1alpha1.MultiClusterResourceBindingSpec{Resources: []v1alpha1.MultiClusterResourceBindingResource{
+			{Name: "web.deployment", Clusters: []v1alpha1.MultiClusterResourceBindingCluster{{Name: clusterName}}},
+		}},
+	}
+	if policy != nil {
+		binding.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(policy, v1alpha1.MultiClusterResourceSchedulePolicyGroupVersionKind)}
+	}
+	return binding
+}
+
+func testClusterResource(binding *v1alpha1.MultiClusterResourceBinding, clusterName string, phase common.MultiClusterResourcePhase) *v1alpha1.ClusterResource {
+	resource := &v1alpha1.ClusterResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       managerCommon.ClusterNamespace(clusterName),
+			Name:            binding.Namespace + "." + binding.Name,
+			Labels:          map[string]string{managerCommon.ResourceBindingLabelName: binding.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(binding, v1alpha1.MultiClusterResourceBindingGroupVersionKind)},
+		},
+	}
+	resource.Status.Phase = phase
+	return resource
+}
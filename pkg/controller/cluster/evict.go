@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/utils/taint"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EvictClusterAnnotationKey is set to the policies rescheduled for the NoExecute taints of cluster, the value is
+// the cluster name and the taints so that new taints trigger the schedule again
+const EvictClusterAnnotationKey = "evict.cluster/stellaris.harmonycloud.cn"
+
+// evictCluster reschedules the policies bound to cluster which do not tolerate its NoExecute taints, the scheduler
+// moves their workloads to failover clusters
+func (r *ClusterReconciler) evictCluster(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	noExecute := taint.WithEffect(taint.ClusterTaints(cluster, time.Now()), corev1.TaintEffectNoExecute)
+	if len(noExecute) == 0 {
+		return nil
+	}
+	bindingList := &v1alpha1.MultiClusterResourceBindingList{}
+	if err := r.List(ctx, bindingList); err != nil {
+		return err
+	}
+	value := evictionValue(cluster.Name, noExecute)
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		if !bindsToCluster(binding, cluster.Name) {
+			continue
+		}
+		policy, err := r.policyOf(ctx, binding)
+		if err != nil {
+			return err
+		}
+		if policy == nil || len(taint.Untolerated(noExecute, policy.Spec.Tolerations)) == 0 {
+			continue
+		}
+		if policy.Annotations[EvictClusterAnnotationKey] != value {
+			r.Recorder.Event(cluster, "Normal", "EvictPolicy", fmt.Sprintf("policy %s/%s is rescheduled for NoExecute taints", policy.Namespace, policy.Name))
+		}
+		if err = r.reschedulePolicy(ctx, policy, EvictClusterAnnotationKey, value); err != nil {
+			return fmt.Errorf("reschedule policy %s/%s failed: %s", policy.Namespace, policy.Name, err)
+		}
+	}
+	return nil
+}
+
+func evictionValue(clusterName string, taints []corev1.Taint) string {
+	keys := make([]string, 0, len(taints))
+	for i := range taints {
+		keys = append(keys, taints[i].ToString())
+	}
+	sort.Strings(keys)
+	return clusterName + ":" + strings.Join(keys, ",")
+}
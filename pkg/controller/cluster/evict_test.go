@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEvictCluster(t *testing.T) {
+	outage := corev1.Taint{Key: "outage", Effect: corev1.TaintEffectNoExecute}
+	zone := corev1.Taint{Key: "zone", Value: "down", Effect: corev1.TaintEffectNoExecute}
+	gpu := corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+
+	tests := []struct {
+		name        string
+		taints      []corev1.Taint
+		tolerations []corev1.Toleration
+		annotation  string
+	}{
+		{
+			name:   "NoSchedule taint does not evict",
+			taints: []corev1.Taint{gpu},
+		},
+		{
+			name:        "tolerated NoExecute taint does not evict",
+			taints:      []corev1.Taint{outage},
+			tolerations: []corev1.Toleration{{Key: "outage", Operator: corev1.TolerationOpExists}},
+		},
+		{
+			name:       "untolerated NoExecute taint evicts",
+			taints:     []corev1.Taint{outage, gpu},
+			annotation: "member:outage:NoExecute",
+		},
+		{
+			name:       "annotation holds the sorted NoExecute taints",
+			taints:     []corev1.Taint{zone, outage},
+			annotation: "member:outage:NoExecute,zone=down:NoExecute",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &v1alpha1.MultiClusterResourceSchedulePolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web", UID: "policy-uid"},
+			}
+			policy.Spec.Tolerations = tt.tolerations
+			cluster := &v1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member"}}
+			cluster.Spec.Taints = tt.taints
+			r := testReconciler(policy, testBinding("app", "web", "binding-uid", "member", policy), cluster)
+
+			if err := r.evictCluster(context.TODO(), cluster); err != nil {
+				t.Fatalf("evict cluster: %v", err)
+			}
+			got := &v1alpha1.MultiClusterResourceSchedulePolicy{}
+			if err := r.Get(context.TODO(), types.NamespacedName{Namespace: "app", Name: "web"}, got); err != nil {
+				t.Fatalf("get policy: %v", err)
+			}
+			if got.Annotations[EvictClusterAnnotationKey] != tt.annotation || got.Spec.Reschedule != (tt.annotation != "") {
+				t.Errorf("policy reschedule = %v annotation %q, want %q", got.Spec.Reschedule, got.Annotations[EvictClusterAnnotationKey], tt.annotation)
+			}
+		})
+	}
+}
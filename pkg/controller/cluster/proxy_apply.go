@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// proxyFieldManager owns the fields of proxy resources applied to member cluster
+const proxyFieldManager = "stellaris-core"
+
+// proxyTemplateHash changes when the template, the configuration or the addons of cluster change
+func proxyTemplateHash(clusterTemplate string, cluster *v1alpha1.Cluster) (string, error) {
+	addons, err := json.Marshal(cluster.Spec.Addons)
+	if err != nil {
+		return "", err
+	}
+	var configuration []byte
+	if cluster.Spec.Configuration != nil {
+		configuration = cluster.Spec.Configuration.Raw
+	}
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(clusterTemplate), configuration, addons} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// proxyObjects are the namespace, the addons ConfigMap and the outputs of template in order of apply
+func proxyObjects(proxyBuilder *ProxyBuilder, outputs map[string]*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	namespace, err := toUnstructured(proxyBuilder.GenerateNamespaces(), corev1.SchemeGroupVersion.WithKind("Namespace"))
+	if err != nil {
+		return nil, err
+	}
+	addonsConfigMap, err := proxyBuilder.GenerateAddonsConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := toUnstructured(addonsConfigMap, corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	if err != nil {
+		return nil, err
+	}
+	return append([]*unstructured.Unstructured{namespace, configMap}, sortedOutputs(outputs)...), nil
+}
+
+// sortedOutputs are the outputs of template in order of their keys
+func sortedOutputs(outputs map[string]*unstructured.Unstructured) []*unstructured.Unstructured {
+	keys := make([]string, 0, len(outputs))
+	for key := range outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*unstructured.Unstructured, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, outputs[key])
+	}
+	return result
+}
+
+func toUnstructured(obj runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+	return u, nil
+}
+
+func proxyResourcesOf(outputs []*unstructured.Unstructured) []v1alpha1.ProxyResource {
+	resources := make([]v1alpha1.ProxyResource, 0, len(outputs))
+	for _, output := range outputs {
+		resources = append(resources, v1alpha1.ProxyResource{
+			APIVersion: output.GetAPIVersion(),
+			Kind:       output.GetKind(),
+			Namespace:  output.GetNamespace(),
+			Name:       output.GetName(),
+		})
+	}
+	return resources
+}
+
+// staleProxyResources are the resources last applied but not in the outputs of template any more
+func staleProxyResources(last, current []v1alpha1.ProxyResource) []v1alpha1.ProxyResource {
+	exist := make(map[v1alpha1.ProxyResource]bool, len(current))
+	for _, resource := range current {
+		exist[resource] = true
+	}
+	var stale []v1alpha1.ProxyResource
+	for _, resource := range last {
+		if !exist[resource] {
+			stale = append(stale, resource)
+		}
+	}
+	return stale
+}
+
+// applyProxyResource applies the object with server-side apply, the conflicting fields changed by others are
+// taken back
+func applyProxyResource(ctx context.Context, c dynamic.Interface, obj *unstructured.Unstructured) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	force := true
+	_, err = c.Resource(utils.GroupVersionResourceFromUnstructured(obj)).Namespace(obj.GetNamespace()).Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: proxyFieldManager,
+		Force:        &force,
+	})
+	return err
+}
+
+func deleteProxyResource(ctx context.Context, c dynamic.Interface, resource v1alpha1.ProxyResource) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(resource.APIVersion)
+	obj.SetKind(resource.Kind)
+	propagation := metav1.DeletePropagationBackground
+	err := c.Resource(utils.GroupVersionResourceFromUnstructured(obj)).Namespace(resource.Namespace).Delete(ctx, resource.Name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+const testProxyTemplate = `
+parameters: {
+	name:      string
+	namespace: string
+}
+
+outputs: serviceAccount: {
+	apiVersion: "v1"
+	kind:       "ServiceAccount"
+	metadata: name:      parameters.name
+	metadata: namespace: parameters.namespace
+}
+`
+
+var (
+	testServiceAccount = v1alpha1.ProxyResource{APIVersion: "v1", Kind: "ServiceAccount", Namespace: "stellaris-system", Name: "proxy"}
+	testRemovedOutput  = v1alpha1.ProxyResource{APIVersion: "v1", Kind: "Secret", Namespace: "stellaris-system", Name: "removed"}
+)
+
+func TestDeployProxy(t *testing.T) {
+	hash, err := proxyTemplateHash(testProxyTemplate, testProxyCluster(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		last    *v1alpha1.ClusterProxyStatus
+		actions []string
+		event   string
+	}{
+		{
+			name: "deployed in order of apply",
+			actions: []string{"patch namespaces /stellaris-system", "patch configmaps stellaris-system/proxy",
+				"patch serviceaccounts stellaris-system/proxy"},
+			event: "ProxyDeployed",
+		},
+		{
+			name: "nothing applied when template is not changed",
+			last: &v1alpha1.ClusterProxyStatus{TemplateHash: hash, LastAppliedTime: metav1.Now(), Resources: []v1alpha1.ProxyResource{testServiceAccount}},
+		},
+		{
+			name: "applied again to correct drift",
+			last: &v1alpha1.ClusterProxyStatus{TemplateHash: hash, LastAppliedTime: metav1.NewTime(time.Now().Add(-2 * proxyResyncPeriod)),
+				Resources: []v1alpha1.ProxyResource{testServiceAccount}},
+			actions: []string{"patch namespaces /stellaris-system", "patch configmaps stellaris-system/proxy",
+				"patch serviceaccounts stellaris-system/proxy"},
+		},
+		{
+			name: "removed output is pruned on upgrade",
+			last: &v1alpha1.ClusterProxyStatus{TemplateHash: "old", LastAppliedTime: metav1.Now(),
+				Resources: []v1alpha1.ProxyResource{testServiceAccount, testRemovedOutput}},
+			actions: []string{"patch namespaces /stellaris-system", "patch configmaps stellaris-system/proxy",
+				"patch serviceaccounts stellaris-system/proxy", "delete secrets stellaris-system/removed"},
+			event: "ProxyUpgraded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := testProxyCluster(tt.last)
+			r, member := testProxyReconciler(cluster)
+
+			if err := r.deployProxy(context.TODO(), cluster); err != nil {
+				t.Fatalf("deploy proxy: %v", err)
+			}
+			if got := memberActions(member); strings.Join(got, ",") != strings.Join(tt.actions, ",") {
+				t.Errorf("actions = %v, want %v", got, tt.actions)
+			}
+			if proxy := cluster.Status.Proxy; proxy.TemplateHash != hash || len(proxy.Resources) != 1 || proxy.Resources[0] != testServiceAccount {
+				t.Errorf("proxy status = %+v, want template %s with the service account", proxy, hash)
+			}
+			if event := lastEvent(r); tt.event == "" && event != "" || !strings.Contains(event, tt.event) {
+				t.Errorf("event = %q, want %q", event, tt.event)
+			}
+		})
+	}
+}
+
+func TestUninstallProxy(t *testing.T) {
+	tests := []struct {
+		name      string
+		last      *v1alpha1.ClusterProxyStatus
+		namespace bool
+		actions   []string
+	}{
+		{
+			name:    "outputs and addons ConfigMap are deleted",
+			actions: []string{"delete configmaps stellaris-system/proxy", "delete serviceaccounts stellaris-system/proxy"},
+		},
+		{
+			name: "recorded resources are deleted once",
+			last: &v1alpha1.ClusterProxyStatus{TemplateHash: "old", Resources: []v1alpha1.ProxyResource{testServiceAccount, testRemovedOutput}},
+			actions: []string{"delete configmaps stellaris-system/proxy", "delete serviceaccounts stellaris-system/proxy",
+				"delete secrets stellaris-system/removed"},
+		},
+		{
+			name:      "namespace is deleted with annotation",
+			namespace: true,
+			actions: []string{"delete namespaces /stellaris-system", "delete configmaps stellaris-system/proxy",
+				"delete serviceaccounts stellaris-system/proxy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := testProxyCluster(tt.last)
+			if tt.namespace {
+				cluster.Annotations = map[string]string{UninstallNamespaceAnnotationKey: UninstallNamespaceAnnotationValue}
+			}
+			r, member := testProxyReconciler(cluster)
+
+			if err := r.uninstallProxy(context.TODO(), cluster); err != nil {
+				t.Fatalf("uninstall proxy: %v", err)
+			}
+			if got := memberActions(member); strings.Join(got, ",") != strings.Join(tt.actions, ",") {
+				t.Errorf("actions = %v, want %v", got, tt.actions)
+			}
+		})
+	}
+}
+
+func testProxyCluster(last *v1alpha1.ClusterProxyStatus) *v1alpha1.Cluster {
+	cluster := &v1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member"}}
+	cluster.Spec.Configuration = &runtime.RawExtension{Raw: []byte(`{"name":"proxy","namespace":"stellaris-system"}`)}
+	cluster.Status.Proxy = last
+	return cluster
+}
+
+// testProxyReconciler renders the test template, the requests to member cluster are recorded only
+func testProxyReconciler(cluster *v1alpha1.Cluster) (*ClusterReconciler, *dynamicfake.FakeDynamicClient) {
+	template := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "stellaris-system", Name: "proxy-template"},
+		Data:       map[string]string{AutoDeployCueTemplateField: testProxyTemplate},
+	}
+	r := testReconciler(cluster, template)
+	r.tmplNamespacedName = types.NamespacedName{Namespace: template.Namespace, Name: template.Name}
+
+	member := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	member.PrependReactor("*", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	r.memberClient = func(*v1alpha1.Cluster) (dynamic.Interface, error) {
+		return member, nil
+	}
+	return r, member
+}
+
+func memberActions(member *dynamicfake.FakeDynamicClient) []string {
+	var actions []string
+	for _, action := range member.Actions() {
+		var name string
+		switch a := action.(type) {
+		case clienttesting.PatchAction:
+			name = a.GetName()
+		case clienttesting.DeleteAction:
+			name = a.GetName()
+		}
+		actions = append(actions, action.GetVerb()+" "+action.GetResource().Resource+" "+action.GetNamespace()+"/"+name)
+	}
+	return actions
+}
+
+func lastEvent(r *ClusterReconciler) string {
+	events := r.Recorder.(*record.FakeRecorder).Events
+	var last string
+	for len(events) > 0 {
+		last = <-events
+	}
+	return last
+}
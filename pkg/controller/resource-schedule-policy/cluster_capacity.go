@@ -0,0 +1,26 @@
+package resource_schedule_policy
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/utils/capacity"
+)
+
+// checkClusterCapacity rejects the cluster which can not run any new pod, the cluster is not checked
+// before proxy reports its resource summary
+func checkClusterCapacity(cluster *v1alpha1.Cluster) error {
+	summary := cluster.Status.ResourceSummary
+	if summary == nil {
+		return nil
+	}
+	if summary.SchedulableNodes == 0 {
+		return fmt.Errorf("cluster %v has no schedulable nodes", cluster.Name)
+	}
+	if pods, ok := capacity.Available(summary, corev1.ResourcePods); ok && pods.Sign() <= 0 {
+		return fmt.Errorf("cluster %v has no room for pods", cluster.Name)
+	}
+	return nil
+}
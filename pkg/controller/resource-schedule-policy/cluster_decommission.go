@@ -0,0 +1,15 @@
+package resource_schedule_policy
+
+import (
+	"fmt"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+// checkClusterDecommission rejects the cluster which is deleted, its workloads are being drained to other clusters
+func checkClusterDecommission(cluster *v1alpha1.Cluster) error {
+	if !cluster.DeletionTimestamp.IsZero() || cluster.Status.Decommission != nil {
+		return fmt.Errorf("cluster %v is being decommissioned", cluster.Name)
+	}
+	return nil
+}
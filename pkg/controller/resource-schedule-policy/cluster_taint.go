@@ -0,0 +1,73 @@
+package resource_schedule_policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	pkgcommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/utils/taint"
+)
+
+// preferNoScheduleError rejects the cluster only when failover clusters can take its place
+type preferNoScheduleError struct {
+	cluster string
+	taint   corev1.Taint
+}
+
+func (e *preferNoScheduleError) Error() string {
+	return fmt.Sprintf("cluster %v prefers no schedule for taint %s", e.cluster, e.taint.ToString())
+}
+
+func isPreferNoSchedule(err error) bool {
+	_, ok := err.(*preferNoScheduleError)
+	return ok
+}
+
+// checkClusterTaints rejects the cluster with taints which are not tolerated by policy. The cluster already
+// scheduled keeps the workloads of policy unless it is tainted with NoExecute
+func checkClusterTaints(cluster *v1alpha1.Cluster, tolerations []corev1.Toleration, scheduled bool, now time.Time) error {
+	untolerated := taint.Untolerated(taint.ClusterTaints(cluster, now), tolerations)
+	if taints := taint.WithEffect(untolerated, corev1.TaintEffectNoExecute); len(taints) > 0 {
+		return fmt.Errorf("cluster %v has taint %s", cluster.Name, taints[0].ToString())
+	}
+	if scheduled {
+		return nil
+	}
+	if taints := taint.WithEffect(untolerated, corev1.TaintEffectNoSchedule); len(taints) > 0 {
+		return fmt.Errorf("cluster %v has taint %s", cluster.Name, taints[0].ToString())
+	}
+	if taints := taint.WithEffect(untolerated, corev1.TaintEffectPreferNoSchedule); len(taints) > 0 {
+		return &preferNoScheduleError{cluster: cluster.Name, taint: taints[0]}
+	}
+	return nil
+}
+
+// scheduledToCluster tells whether the binding of policy has been scheduled to cluster
+func scheduledToCluster(ctx context.Context, c client.Client, policy *v1alpha1.MultiClusterResourceSchedulePolicy, clusterName string) bool {
+	binding := &v1alpha1.MultiClusterResourceBinding{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: pkgcommon.Scheduler + "-" + policy.Name}, binding); err != nil {
+		return false
+	}
+	for _, resource := range binding.Spec.Resources {
+		for _, cluster := range resource.Clusters {
+			if cluster.Name == clusterName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeIndex merges the indexes of unavailable clusters in order
+func mergeIndex(index, other []int) []int {
+	merged := append(append([]int(nil), index...), other...)
+	sort.Ints(merged)
+	return merged
+}
@@ -202,6 +202,8 @@ func (r *Reconciler) checkClusters(ctx context.Context, policy *v1alpha1.MultiCl
 		unavailableClusters         []string
 		unavailableIndex            []int
 		unavailableFailoverClusters []string
+		preferNotClusters           []string
+		preferNotIndex              []int
 	)
 
 	if policy.Spec.ClusterSource == v1alpha1.ClusterSourceTypeClusterset {
@@ -214,21 +216,35 @@ func (r *Reconciler) checkClusters(ctx context.Context, policy *v1alpha1.MultiCl
 		}
 		clusterList := r.getClusterListByClusterSet(ctx, clusterSet)
 		for i, instance := range clusterList {
-			err := r.checkCluster(ctx, instance)
-			if err != nil {
+			err := r.checkCluster(ctx, policy, instance)
+			if isPreferNoSchedule(err) {
+				preferNotClusters = append(preferNotClusters, instance)
+				preferNotIndex = append(preferNotIndex, i)
+			} else if err != nil {
 				unavailableClusters = append(unavailableClusters, instance)
 				unavailableIndex = append(unavailableIndex, i)
 			}
 		}
 	} else {
 		for i, instance := range policy.Spec.Policy {
-			err := r.checkCluster(ctx, instance.Name)
-			if err != nil {
+			err := r.checkCluster(ctx, policy, instance.Name)
+			if isPreferNoSchedule(err) {
+				preferNotClusters = append(preferNotClusters, instance.Name)
+				preferNotIndex = append(preferNotIndex, i)
+			} else if err != nil {
 				unavailableClusters = append(unavailableClusters, instance.Name)
 				unavailableIndex = append(unavailableIndex, i)
 			}
 		}
 	}
+	// the clusters which prefer no schedule are replaced only when failover clusters can take their places
+	if len(policy.Spec.FailoverPolicy) > 0 && len(preferNotClusters) > 0 {
+		failoverCount, _ := r.failoverPolicyCheck(ctx, policy)
+		if len(unavailableClusters)+len(preferNotClusters) <= failoverCount {
+			unavailableClusters = append(unavailableClusters, preferNotClusters...)
+			unavailableIndex = mergeIndex(unavailableIndex, preferNotIndex)
+		}
+	}
 	if len(policy.Spec.FailoverPolicy) > 0 && len(unavailableClusters) > 0 {
 		failoverCount, unavailableFailoverClusters := r.failoverPolicyCheck(ctx, policy)
 		if len(unavailableClusters) > failoverCount {
@@ -243,8 +259,8 @@ func (r *Reconciler) checkClusters(ctx context.Context, policy *v1alpha1.MultiCl
 	return unavailableIndex, unavailableFailoverClusters, nil
 }
 
-// check single cluster
-func (r *Reconciler) checkCluster(ctx context.Context, clusterName string) error {
+// check single cluster, the taints of cluster are checked with the tolerations of policy
+func (r *Reconciler) checkCluster(ctx context.Context, policy *v1alpha1.MultiClusterResourceSchedulePolicy, clusterName string) error {
 	cluster := &v1alpha1.Cluster{}
 	clusterNamespacedName := types.NamespacedName{
 		Name: clusterName,
@@ -256,7 +272,14 @@ func (r *Reconciler) checkCluster(ctx context.Context, clusterName string) error
 	if cluster.Status.Status != v1alpha1.OnlineStatus {
 		return fmt.Errorf("cluster %v offline", clusterName)
 	}
-	return nil
+	if err = checkClusterDecommission(cluster); err != nil {
+		return err
+	}
+	if err = checkClusterCapacity(cluster); err != nil {
+		return err
+	}
+	scheduled := scheduledToCluster(ctx, r.Client, policy, clusterName)
+	return checkClusterTaints(cluster, policy.Spec.Tolerations, scheduled, time.Now())
 }
 
 func (r *Reconciler) generateBindingByDuplicated(ctx context.Context, policy *v1alpha1.MultiClusterResourceSchedulePolicy, failIndex []int, unavailableFailoverClusters []string) (*v1alpha1.MultiClusterResourceBinding, error) {
@@ -740,8 +763,8 @@ func (r *Reconciler) failoverPolicyCheck(ctx context.Context, policy *v1alpha1.M
 	var unavailableClusters []string
 	for _, instance := range policy.Spec.FailoverPolicy {
 		if instance.Type == apicommon.ClusterTypeClusters {
-			err := r.checkCluster(ctx, instance.Name)
-			if err != nil {
+			err := r.checkCluster(ctx, policy, instance.Name)
+			if err != nil && !isPreferNoSchedule(err) {
 				unavailableClusters = append(unavailableClusters, instance.Name)
 				continue
 			}
@@ -759,8 +782,8 @@ func (r *Reconciler) failoverPolicyCheck(ctx context.Context, policy *v1alpha1.M
 			}
 			clusterList := r.getClusterListByClusterSet(ctx, clusterSet)
 			for _, cluster := range clusterList {
-				err := r.checkCluster(ctx, cluster)
-				if err != nil {
+				err := r.checkCluster(ctx, policy, cluster)
+				if err != nil && !isPreferNoSchedule(err) {
 					unavailableClusters = append(unavailableClusters, cluster)
 					continue
 				}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	aggregatePolicyController "harmonycloud.cn/stellaris/pkg/controller/aggregate-policy"
 	clusterController "harmonycloud.cn/stellaris/pkg/controller/cluster"
 	clusterResourceController "harmonycloud.cn/stellaris/pkg/controller/cluster-resource"
 	clusterSetController "harmonycloud.cn/stellaris/pkg/controller/cluster-set"
@@ -21,10 +22,12 @@ func Setup(mgr ctrl.Manager, args controllerCommon.Args) error {
 		multiClusterRsourceController.Setup,
 		clusterResourceController.Setup,
 		resourceSchedulePolicyController.Setup,
+		aggregatePolicyController.SetupPush,
 	}
 	if !args.IsControlPlane {
 		controllerSetupFunctions = []func(ctrl.Manager, controllerCommon.Args) error{
 			clusterResourceController.Setup,
+			aggregatePolicyController.Setup,
 		}
 	}
 
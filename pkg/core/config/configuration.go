@@ -1,13 +1,39 @@
 package config
 
-import "time"
+import (
+	"time"
+
+	clusterHealth "harmonycloud.cn/stellaris/pkg/common/cluster-health"
+)
 
 type Configuration struct {
-	HeartbeatExpirePeriod    time.Duration
-	OnlineExpirationTime     time.Duration
+	HeartbeatExpirePeriod time.Duration
+	// OnlineExpirationTime is how long without heartbeat before a cluster becomes offline
+	OnlineExpirationTime time.Duration
+	// UnknownExpirationTime is how long without heartbeat before an online or degraded cluster becomes unknown
+	UnknownExpirationTime time.Duration
+	// UnhealthyToleranceTime is how long an online cluster can be unhealthy before it becomes degraded
+	UnhealthyToleranceTime time.Duration
+	// ClusterStatusCheckPeriod is how often monitor resets the expiry timers of clusters from cache
 	ClusterStatusCheckPeriod time.Duration
+	// RequireClientCertificate makes core take the cluster identity from the proxy client certificate
+	RequireClientCertificate bool
+	// RequireBootstrapToken makes core verify the bootstrap token in register request and bind the stream to the cluster
+	RequireBootstrapToken   bool
+	BootstrapTokenNamespace string
+	// SessionTTL is how long a proxy session can be resumed after the proxy is last seen
+	SessionTTL time.Duration
 }
 
 func DefaultConfiguration() *Configuration {
 	return &Configuration{}
 }
+
+// Thresholds are the durations of cluster state transitions
+func (c *Configuration) Thresholds() clusterHealth.Thresholds {
+	return clusterHealth.Thresholds{
+		UnhealthyTolerance: c.UnhealthyToleranceTime,
+		UnknownAfter:       c.UnknownExpirationTime,
+		OfflineAfter:       c.OnlineExpirationTime,
+	}
+}
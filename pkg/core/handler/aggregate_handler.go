@@ -1,12 +1,207 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/utils/core"
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
+)
+
+var aggregateLog = logf.Log.WithName("core_aggregate")
+
+const (
+	AggregatePolicyLabel  = "multicluster.harmonycloud.cn/aggregate-policy"
+	AggregateRuleLabel    = "multicluster.harmonycloud.cn/aggregate-rule"
+	AggregateClusterLabel = "multicluster.harmonycloud.cn/aggregate-cluster"
 )
 
 func (s *CoreServer) Aggregate(req *config.Request, stream config.Channel_EstablishServer) {
-	resourceHandlerLog.Info(fmt.Sprintf("receive grpc request for aggregate, cluster:%s", req.ClusterName))
-	// TODO receive grpc request for aggregate
+	aggregateLog.Info(fmt.Sprintf("receive grpc request for aggregate, cluster:%s", req.ClusterName))
+	data := &model.AggregateRequest{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		aggregateLog.Error(err, "unmarshal data error")
+		s.sendAggregateResponse(req, err, stream)
+		return
+	}
+	err := s.syncAggregatedResources(context.Background(), req.ClusterName, data)
+	if err != nil {
+		aggregateLog.Error(err, fmt.Sprintf("sync aggregated resources of policy(%s:%s) failed", data.PolicyNamespace, data.PolicyName))
+	}
+	s.sendAggregateResponse(req, err, stream)
+}
+
+func (s *CoreServer) sendAggregateResponse(req *config.Request, err error, stream config.Channel_EstablishServer) {
+	res := &config.Response{
+		Type:        model.AggregateSuccess.String(),
+		ClusterName: req.ClusterName,
+		RequestId:   req.RequestId,
+	}
+	if err != nil {
+		res.Type = model.AggregateFailed.String()
+		res.Body = err.Error()
+	}
+	core.SendResponse(res, stream)
+}
+
+// syncAggregatedResources upserts one AggregatedResource for every result, and deletes the ones of the rule which are not reported
+func (s *CoreServer) syncAggregatedResources(ctx context.Context, clusterName string, data *model.AggregateRequest) error {
+	// proxy can only aggregate into the namespace of its cluster
+	if data.PolicyNamespace != managerCommon.ClusterNamespace(clusterName) {
+		return fmt.Errorf("policy namespace %s does not belong to cluster %s", data.PolicyNamespace, clusterName)
+	}
+	policy, err := s.mClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(data.PolicyNamespace).Get(ctx, data.PolicyName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err = s.updateAggregatePolicyStatus(ctx, policy, data); err != nil {
+		return err
+	}
+	// the rule is not evaluated, keep the last results
+	if len(data.Error) > 0 {
+		return nil
+	}
+
+	var errs []error
+	var existList *v1alpha1.AggregatedResourceList
+	reported := make(map[string]bool, len(data.Results))
+	for _, result := range data.Results {
+		aggregatedResource := newAggregatedResource(clusterName, policy, data, result)
+		reported[aggregatedResource.Name] = true
+		if err := s.upsertAggregatedResource(ctx, aggregatedResource); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{
+		AggregatePolicyLabel:  data.PolicyName,
+		AggregateRuleLabel:    data.RuleName,
+		AggregateClusterLabel: clusterName,
+	})
+	existList, err = s.mClient.MulticlusterV1alpha1().AggregatedResources(data.PolicyNamespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+	for _, item := range existList.Items {
+		if reported[item.Name] {
+			continue
+		}
+		err = s.mClient.MulticlusterV1alpha1().AggregatedResources(item.Namespace).Delete(ctx, item.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		aggregateLog.Info(fmt.Sprintf("delete aggregated resource(%s:%s), it is not reported by cluster(%s)", item.Namespace, item.Name, clusterName))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// updateAggregatePolicyStatus records the rule error reported by proxy, the status is normal again when the rule is evaluated
+func (s *CoreServer) updateAggregatePolicyStatus(ctx context.Context, policy *v1alpha1.MultiClusterResourceAggregatePolicy, data *model.AggregateRequest) error {
+	status := v1alpha1.MultiClusterResourceAggregatePolicyStatus{Status: v1alpha1.AggregatePolicyStatusNormal}
+	if len(data.Error) > 0 {
+		status.Status = v1alpha1.AggregatePolicyStatusRuleError
+		status.Message = fmt.Sprintf("rule %s: %s", data.RuleName, data.Error)
+	} else if policy.Status.Status != v1alpha1.AggregatePolicyStatusRuleError || !strings.HasPrefix(policy.Status.Message, "rule "+data.RuleName+":") {
+		// the error of another rule is kept
+		return nil
+	}
+	if policy.Status == status {
+		return nil
+	}
+	policy.Status = status
+	_, err := s.mClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(policy.Namespace).UpdateStatus(ctx, policy, metav1.UpdateOptions{})
+	return err
+}
+
+// newAggregatedResource is owned by the policy, it is deleted with the policy
+func newAggregatedResource(clusterName string, policy *v1alpha1.MultiClusterResourceAggregatePolicy, data *model.AggregateRequest, result model.AggregateResult) *v1alpha1.AggregatedResource {
+	resourceName := result.Namespace + "/" + result.Name
+	now := metav1.NewTime(timeutils.NowTimeWithLoc())
+	status := v1alpha1.ClusterStatusNormal
+	if len(result.Error) > 0 {
+		status = v1alpha1.ClusterStatusError
+	}
+	return &v1alpha1.AggregatedResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      aggregatedResourceName(data.PolicyName, data.RuleName, resourceName),
+			Namespace: data.PolicyNamespace,
+			Labels: map[string]string{
+				AggregatePolicyLabel:  data.PolicyName,
+				AggregateRuleLabel:    data.RuleName,
+				AggregateClusterLabel: clusterName,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(policy, v1alpha1.SchemeGroupVersion.WithKind("MultiClusterResourceAggregatePolicy")),
+			},
+		},
+		Clusters: &v1alpha1.AggregatedResourceClusters{
+			Name:         clusterName,
+			ResourceName: resourceName,
+			Result:       result.Result,
+		},
+		Aggregation: result.Result,
+		Status: v1alpha1.AggregatedResourceStatus{
+			Clusters: []v1alpha1.AggregatedResourceStatusClusters{
+				{
+					Name:         clusterName,
+					ResourceName: resourceName,
+					UpdateTime:   &now,
+					Status:       status,
+					Message:      result.Error,
+				},
+			},
+		},
+	}
+}
+
+// aggregatedResourceName is stable for the same resource, the hash keeps the name valid for any resource name
+func aggregatedResourceName(policyName, ruleName, resourceName string) string {
+	sum := sha256.Sum256([]byte(policyName + "/" + ruleName + "/" + resourceName))
+	prefix := ruleName
+	if len(prefix) > 46 {
+		prefix = prefix[:46]
+	}
+	return prefix + "-" + hex.EncodeToString(sum[:])[:16]
+}
+
+func (s *CoreServer) upsertAggregatedResource(ctx context.Context, aggregatedResource *v1alpha1.AggregatedResource) error {
+	client := s.mClient.MulticlusterV1alpha1().AggregatedResources(aggregatedResource.Namespace)
+	status := aggregatedResource.Status
+	exist, err := client.Get(ctx, aggregatedResource.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		exist, err = client.Create(ctx, aggregatedResource, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+	} else {
+		exist.Labels = aggregatedResource.Labels
+		exist.OwnerReferences = aggregatedResource.OwnerReferences
+		exist.Clusters = aggregatedResource.Clusters
+		exist.Aggregation = aggregatedResource.Aggregation
+		exist, err = client.Update(ctx, exist, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	exist.Status = status
+	_, err = client.UpdateStatus(ctx, exist, metav1.UpdateOptions{})
+	return err
 }
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/model"
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// BootstrapTokenSecretType is the type of secret which stores a proxy bootstrap token,
+	// the secret name must be BootstrapTokenSecretPrefix + token id
+	BootstrapTokenSecretType   corev1.SecretType = "bootstrap.stellaris.harmonycloud.cn/token"
+	BootstrapTokenSecretPrefix                   = "bootstrap-token-"
+
+	BootstrapTokenIDKey          = "token-id"
+	BootstrapTokenSecretKey      = "token-secret"
+	BootstrapTokenClusterNameKey = "cluster-name"
+	BootstrapTokenExpirationKey  = "expiration"
+)
+
+var bootstrapTokenRegexp = regexp.MustCompile(`^([a-z0-9]{6})\.([a-z0-9]{16})$`)
+
+var coreAuthLog = logf.Log.WithName("core_auth")
+
+// session keeps the identity bound to one stream
+type session struct {
+	// certIdentity is the cluster name in the client certificate, empty when mutual tls is disabled
+	certIdentity string
+	// boundCluster is the cluster name bound by a verified register request
+	boundCluster string
+}
+
+// authorize checks request cluster name against the stream identity, register requests are verified with
+// bootstrap token and bind the stream to the cluster
+func (s *CoreServer) authorize(sess *session, req *config.Request) error {
+	if len(sess.certIdentity) > 0 && req.ClusterName != sess.certIdentity {
+		return fmt.Errorf("cluster name %s in request does not match client certificate identity %s", req.ClusterName, sess.certIdentity)
+	}
+	if !s.Config.RequireBootstrapToken {
+		return nil
+	}
+	if len(sess.boundCluster) > 0 {
+		if req.ClusterName != sess.boundCluster {
+			return fmt.Errorf("cluster name %s in request does not match the cluster %s bound at register", req.ClusterName, sess.boundCluster)
+		}
+		return nil
+	}
+	if req.Type != model.Register.String() {
+		return fmt.Errorf("stream is not registered, request %s from cluster %s is rejected", req.Type, req.ClusterName)
+	}
+	data := &model.RegisterRequest{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		return err
+	}
+	if err := s.verifyBootstrapToken(context.Background(), data.Token, req.ClusterName); err != nil {
+		return err
+	}
+	sess.boundCluster = req.ClusterName
+	coreAuthLog.Info(fmt.Sprintf("stream is bound to cluster %s", req.ClusterName))
+	return nil
+}
+
+// verifyBootstrapToken checks the token secret and binds the token to the cluster when it is used first time
+func (s *CoreServer) verifyBootstrapToken(ctx context.Context, token, clusterName string) error {
+	matches := bootstrapTokenRegexp.FindStringSubmatch(token)
+	if len(matches) != 3 {
+		return errors.New("bootstrap token format is invalid, it must be [a-z0-9]{6}.[a-z0-9]{16}")
+	}
+	tokenID, tokenSecret := matches[1], matches[2]
+
+	secret, err := s.kubeClient.CoreV1().Secrets(s.Config.BootstrapTokenNamespace).Get(ctx, BootstrapTokenSecretPrefix+tokenID, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("bootstrap token %s is not found", tokenID)
+		}
+		return err
+	}
+	if secret.Type != BootstrapTokenSecretType {
+		return fmt.Errorf("secret of bootstrap token %s must be type %s", tokenID, BootstrapTokenSecretType)
+	}
+	if string(secret.Data[BootstrapTokenIDKey]) != tokenID ||
+		subtle.ConstantTimeCompare(secret.Data[BootstrapTokenSecretKey], []byte(tokenSecret)) != 1 {
+		return fmt.Errorf("bootstrap token %s is invalid", tokenID)
+	}
+	// the cluster which joined with the token reconnects with it, expiration only limits the first use
+	boundCluster := string(secret.Data[BootstrapTokenClusterNameKey])
+	if len(boundCluster) > 0 {
+		if boundCluster != clusterName {
+			return fmt.Errorf("bootstrap token %s is bound to cluster %s, cannot be used by cluster %s", tokenID, boundCluster, clusterName)
+		}
+		return nil
+	}
+	if expiration, ok := secret.Data[BootstrapTokenExpirationKey]; ok {
+		expireTime, err := time.Parse(time.RFC3339, string(expiration))
+		if err != nil {
+			return fmt.Errorf("expiration of bootstrap token %s is invalid: %s", tokenID, err)
+		}
+		if timeutils.NowTimeWithLoc().After(expireTime) {
+			return fmt.Errorf("bootstrap token %s is expired", tokenID)
+		}
+	}
+	// first use, bind token to the cluster, conflict means another proxy bound it at the same time
+	secret.Data[BootstrapTokenClusterNameKey] = []byte(clusterName)
+	if _, err = s.kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("bind bootstrap token %s to cluster %s failed: %s", tokenID, clusterName, err)
+	}
+	coreAuthLog.Info(fmt.Sprintf("bootstrap token %s is bound to cluster %s", tokenID, clusterName))
+	return nil
+}
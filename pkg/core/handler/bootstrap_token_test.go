@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"harmonycloud.cn/stellaris/config"
+	corecfg "harmonycloud.cn/stellaris/pkg/core/config"
+	"harmonycloud.cn/stellaris/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testTokenNamespace = "stellaris-system"
+
+func newTestServer(secret *corev1.Secret) *CoreServer {
+	return &CoreServer{
+		Config: &corecfg.Configuration{
+			RequireBootstrapToken:   true,
+			BootstrapTokenNamespace: testTokenNamespace,
+		},
+		kubeClient: fake.NewSimpleClientset(secret),
+	}
+}
+
+func newTokenSecret(clusterName string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: BootstrapTokenSecretPrefix + "abcdef", Namespace: testTokenNamespace},
+		Type:       BootstrapTokenSecretType,
+		Data: map[string][]byte{
+			BootstrapTokenIDKey:     []byte("abcdef"),
+			BootstrapTokenSecretKey: []byte("0123456789abcdef"),
+		},
+	}
+	if len(clusterName) > 0 {
+		secret.Data[BootstrapTokenClusterNameKey] = []byte(clusterName)
+	}
+	return secret
+}
+
+func newRegisterRequest(t *testing.T, clusterName, token string) *config.Request {
+	body, err := json.Marshal(&model.RegisterRequest{Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &config.Request{Type: model.Register.String(), ClusterName: clusterName, Body: string(body)}
+}
+
+func TestAuthorizeBindsStreamAtRegister(t *testing.T) {
+	s := newTestServer(newTokenSecret(""))
+	sess := &session{}
+
+	heartbeat := &config.Request{Type: model.Heartbeat.String(), ClusterName: "cluster-a"}
+	if err := s.authorize(sess, heartbeat); err == nil {
+		t.Fatal("request before register should be rejected")
+	}
+	if err := s.authorize(sess, newRegisterRequest(t, "cluster-a", "abcdef.0123456789abcdef")); err != nil {
+		t.Fatalf("register with valid token failed: %s", err)
+	}
+	if err := s.authorize(sess, heartbeat); err != nil {
+		t.Fatalf("request of bound cluster failed: %s", err)
+	}
+	if err := s.authorize(sess, &config.Request{Type: model.Resource.String(), ClusterName: "cluster-b"}); err == nil {
+		t.Fatal("request of another cluster should be rejected")
+	}
+
+	secret, err := s.kubeClient.CoreV1().Secrets(testTokenNamespace).Get(context.TODO(), BootstrapTokenSecretPrefix+"abcdef", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data[BootstrapTokenClusterNameKey]) != "cluster-a" {
+		t.Fatalf("token should be bound to cluster-a, but got %s", secret.Data[BootstrapTokenClusterNameKey])
+	}
+}
+
+func TestAuthorizeRejectsInvalidToken(t *testing.T) {
+	cases := map[string]struct {
+		boundCluster string
+		expired      bool
+		clusterName  string
+		token        string
+	}{
+		"wrong secret":       {clusterName: "cluster-a", token: "abcdef.0000000000000000"},
+		"unknown token":      {clusterName: "cluster-a", token: "zzzzzz.0123456789abcdef"},
+		"malformed token":    {clusterName: "cluster-a", token: "abcdef"},
+		"bound to other one": {boundCluster: "cluster-b", clusterName: "cluster-a", token: "abcdef.0123456789abcdef"},
+		"expired":            {expired: true, clusterName: "cluster-a", token: "abcdef.0123456789abcdef"},
+	}
+	for name, c := range cases {
+		secret := newTokenSecret(c.boundCluster)
+		if c.expired {
+			expireToken(secret)
+		}
+		s := newTestServer(secret)
+		if err := s.authorize(&session{}, newRegisterRequest(t, c.clusterName, c.token)); err == nil {
+			t.Errorf("%s: register should be rejected", name)
+		}
+	}
+}
+
+func TestAuthorizeReconnectWithExpiredToken(t *testing.T) {
+	secret := newTokenSecret("cluster-a")
+	expireToken(secret)
+	s := newTestServer(secret)
+	if err := s.authorize(&session{}, newRegisterRequest(t, "cluster-a", "abcdef.0123456789abcdef")); err != nil {
+		t.Fatalf("cluster bound to expired token should reconnect, but got %s", err)
+	}
+}
+
+func expireToken(secret *corev1.Secret) {
+	secret.Data[BootstrapTokenExpirationKey] = []byte(time.Now().Add(-time.Hour).Format(time.RFC3339))
+}
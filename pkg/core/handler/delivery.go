@@ -0,0 +1,400 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	multclusterclient "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
+	"harmonycloud.cn/stellaris/pkg/core/outbox"
+	"harmonycloud.cn/stellaris/pkg/model"
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var deliveryLog = logf.Log.WithName("core_delivery")
+
+const (
+	deliveryRetryInitialInterval = 5 * time.Second
+	deliveryRetryMaxInterval     = 5 * time.Minute
+	deliveryRetryCheckPeriod     = time.Second
+)
+
+// delivery is a resource message sent to proxy and waiting for ack
+type delivery struct {
+	clusterResource *v1alpha1.ClusterResource
+	response        *config.Response
+	attempts        int32
+	lastAttempt     time.Time
+	nextRetry       time.Time
+}
+
+func (d *delivery) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: d.clusterResource.Namespace, Name: d.clusterResource.Name}
+}
+
+// deliveryTracker keeps the unacknowledged deliveries, only the latest delivery of one ClusterResource is kept
+type deliveryTracker struct {
+	mu      sync.Mutex
+	pending map[string]*delivery
+	// latest is the request id of the latest delivery of ClusterResource
+	latest map[types.NamespacedName]string
+	// clusterLocks serializes deliveries and outbox draining of one cluster
+	clusterLocks sync.Map
+
+	// outbox persists deliveries of offline proxies, it is nil when core server is not created
+	outbox  *outbox.Outbox
+	mClient *multclusterclient.Clientset
+}
+
+var deliveries = newDeliveryTracker()
+
+func newDeliveryTracker() *deliveryTracker {
+	return &deliveryTracker{
+		pending: make(map[string]*delivery),
+		latest:  make(map[types.NamespacedName]string),
+	}
+}
+
+// track records the delivery and drops the older delivery of the same ClusterResource
+func (t *deliveryTracker) track(d *delivery) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forgetLocked(d.key())
+	t.pending[d.response.RequestId] = d
+	t.latest[d.key()] = d.response.RequestId
+}
+
+// forget drops the delivery of ClusterResource, it is used when the delivery is moved to outbox
+func (t *deliveryTracker) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forgetLocked(key)
+}
+
+func (t *deliveryTracker) forgetLocked(key types.NamespacedName) {
+	if requestID, ok := t.latest[key]; ok {
+		delete(t.pending, requestID)
+		delete(t.latest, key)
+	}
+}
+
+// ack removes the delivery when it is applied, a failed delivery is kept for retry
+func (t *deliveryTracker) ack(requestID string, success bool) (delivery, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, ok := t.pending[requestID]
+	if !ok {
+		return delivery{}, false
+	}
+	if success {
+		t.forgetLocked(d.key())
+	}
+	return *d, true
+}
+
+// due returns the deliveries which should be retried now, and moves their next retry time with backoff
+func (t *deliveryTracker) due(now time.Time) []delivery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var result []delivery
+	for _, d := range t.pending {
+		if now.Before(d.nextRetry) {
+			continue
+		}
+		d.attempts++
+		d.lastAttempt = now
+		d.nextRetry = now.Add(deliveryBackoff(d.attempts))
+		result = append(result, *d)
+	}
+	return result
+}
+
+func (t *deliveryTracker) isPending(requestID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.pending[requestID]
+	return ok
+}
+
+// hasPending returns whether the latest delivery of ClusterResource is not acknowledged yet
+func (t *deliveryTracker) hasPending(key types.NamespacedName) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.latest[key]
+	return ok
+}
+
+func (t *deliveryTracker) clusterLock(clusterName string) *sync.Mutex {
+	lock, _ := t.clusterLocks.LoadOrStore(clusterName, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// enqueue moves the delivery to outbox when proxy is offline
+func (t *deliveryTracker) enqueue(ctx context.Context, clusterName string, d *delivery) error {
+	if t.outbox == nil {
+		return errors.New("outbox is not set up")
+	}
+	if err := t.outbox.Enqueue(ctx, clusterName, model.ServiceResponseType(d.response.Type), d.clusterResource); err != nil {
+		return err
+	}
+	t.forget(d.key())
+	t.updateOutboxDepth(ctx, clusterName)
+	return nil
+}
+
+// removeFromOutbox drops the queued change of ClusterResource, a newer delivery supersedes it
+func (t *deliveryTracker) removeFromOutbox(ctx context.Context, clusterName string, key types.NamespacedName) {
+	if t.outbox == nil {
+		return
+	}
+	removed, err := t.outbox.Remove(ctx, clusterName, key.Name)
+	if err != nil {
+		deliveryLog.Error(err, fmt.Sprintf("remove clusterResource(%s) from outbox failed", key))
+		return
+	}
+	if removed {
+		t.updateOutboxDepth(ctx, clusterName)
+	}
+}
+
+// updateOutboxDepth writes outbox depth to Cluster status
+func (t *deliveryTracker) updateOutboxDepth(ctx context.Context, clusterName string) {
+	if t.outbox == nil || t.mClient == nil {
+		return
+	}
+	depth, err := t.outbox.Depth(ctx, clusterName)
+	if err != nil {
+		deliveryLog.Error(err, fmt.Sprintf("get outbox depth of cluster(%s) failed", clusterName))
+		return
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := t.mClient.MulticlusterV1alpha1().Clusters().Get(ctx, clusterName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if cluster.Status.OutboxDepth == int32(depth) {
+			return nil
+		}
+		cluster.Status.OutboxDepth = int32(depth)
+		_, err = t.mClient.MulticlusterV1alpha1().Clusters().UpdateStatus(ctx, cluster, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		deliveryLog.Error(err, fmt.Sprintf("update outbox depth of cluster(%s) failed", clusterName))
+	}
+}
+
+// deliveryBackoff doubles the retry interval after every attempt until it reaches the max interval
+func deliveryBackoff(attempts int32) time.Duration {
+	interval := deliveryRetryInitialInterval
+	for i := int32(1); i < attempts; i++ {
+		interval *= 2
+		if interval >= deliveryRetryMaxInterval {
+			return deliveryRetryMaxInterval
+		}
+	}
+	return interval
+}
+
+func newDelivery(clusterName string, resType model.ServiceResponseType, clusterResource *v1alpha1.ClusterResource) (*delivery, error) {
+	data, err := json.Marshal(&model.SyncResourceResponse{ClusterResourceList: []*v1alpha1.ClusterResource{clusterResource}})
+	if err != nil {
+		return nil, err
+	}
+	response, err := NewResourceResponse(resType, clusterName, string(data))
+	if err != nil {
+		return nil, err
+	}
+	response.RequestId = uuid.NewString()
+	now := timeutils.NowTimeWithLoc()
+	return &delivery{
+		clusterResource: clusterResource,
+		response:        response,
+		attempts:        1,
+		lastAttempt:     now,
+		nextRetry:       now.Add(deliveryBackoff(1)),
+	}, nil
+}
+
+// DeliverResourceToProxy sends the ClusterResource change to proxy and tracks it until proxy acks,
+// the delivery is retried with backoff, and it is queued in outbox when no replica holds the stream of proxy
+func DeliverResourceToProxy(clusterName string, resType model.ServiceResponseType, clusterResource *v1alpha1.ClusterResource) (*v1alpha1.ClusterResourceDelivery, error) {
+	d, err := newDelivery(clusterName, resType, clusterResource)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	lock := deliveries.clusterLock(clusterName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	deliveries.removeFromOutbox(ctx, clusterName, d.key())
+	state := newDeliveryState(*d, v1alpha1.DeliveryPending, "")
+	if err = SendResourceToProxy(clusterName, d.response); err != nil {
+		state.Message = err.Error()
+		if proxyOffline(clusterName) {
+			queueErr := deliveries.enqueue(ctx, clusterName, d)
+			if queueErr == nil {
+				state.Message = fmt.Sprintf("proxy is offline, queued in outbox: %s", err)
+				return state, nil
+			}
+			deliveryLog.Error(queueErr, fmt.Sprintf("queue clusterResource(%s) in outbox failed", d.key()))
+		}
+	}
+	deliveries.track(d)
+	return state, nil
+}
+
+// Ack receive the acknowledgement of resource delivery from proxy
+func (s *CoreServer) Ack(req *config.Request, stream config.Channel_EstablishServer) {
+	data := &model.AckRequest{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		deliveryLog.Error(err, fmt.Sprintf("unmarshal ack of cluster(%s) failed", req.ClusterName))
+		return
+	}
+	d, ok := deliveries.ack(req.RequestId, data.Success)
+	if !ok && ackToOrigin(req) {
+		return
+	}
+	if !ok {
+		deliveryLog.Info(fmt.Sprintf("ignore ack of request(%s) from cluster(%s), it is acknowledged or superseded", req.RequestId, req.ClusterName))
+		return
+	}
+	var state *v1alpha1.ClusterResourceDelivery
+	if data.Success {
+		state = newDeliveryState(d, v1alpha1.DeliveryAcknowledged, "")
+		now := metav1.NewTime(timeutils.NowTimeWithLoc())
+		state.AcknowledgedTime = &now
+	} else {
+		state = newDeliveryState(d, v1alpha1.DeliveryFailed, data.Message)
+		deliveryLog.Error(errors.New(data.Message), fmt.Sprintf("proxy(%s) failed to apply request(%s)", req.ClusterName, req.RequestId))
+	}
+	if err := updateDeliveryState(context.Background(), s.mClient, d.key(), state); err != nil {
+		deliveryLog.Error(err, fmt.Sprintf("update delivery state of clusterResource(%s) failed", d.key()))
+	}
+}
+
+// RetryDeliveries resends the unacknowledged deliveries until ctx is done,
+// the delivery is moved to outbox when no replica holds the stream of proxy
+func (s *CoreServer) RetryDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(deliveryRetryCheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, d := range deliveries.due(timeutils.NowTimeWithLoc()) {
+			s.retryDelivery(ctx, d)
+		}
+	}
+}
+
+func (s *CoreServer) retryDelivery(ctx context.Context, d delivery) {
+	clusterName := d.response.ClusterName
+	lock := deliveries.clusterLock(clusterName)
+	lock.Lock()
+	defer lock.Unlock()
+	if !deliveries.isPending(d.response.RequestId) {
+		// acknowledged or superseded while waiting for the lock
+		return
+	}
+
+	deliveryLog.Info(fmt.Sprintf("retry request(%s) of clusterResource(%s), attempts %d", d.response.RequestId, d.key(), d.attempts))
+	state := newDeliveryState(d, v1alpha1.DeliveryPending, "")
+	// the delivery keeps retrying with backoff while proxy is connected
+	if err := SendResourceToProxy(clusterName, d.response); err != nil {
+		state.Message = err.Error()
+		if proxyOffline(clusterName) {
+			if queueErr := deliveries.enqueue(ctx, clusterName, &d); queueErr == nil {
+				state.Message = fmt.Sprintf("proxy is offline, queued in outbox: %s", err)
+			} else {
+				deliveryLog.Error(queueErr, fmt.Sprintf("queue clusterResource(%s) in outbox failed", d.key()))
+			}
+		}
+	}
+	if err := updateDeliveryState(ctx, s.mClient, d.key(), state); err != nil {
+		deliveryLog.Error(err, fmt.Sprintf("update delivery state of clusterResource(%s) failed", d.key()))
+	}
+}
+
+// drainOutbox sends the queued changes to proxy in the order they are queued, it stops at the first failure
+// so that the remaining changes keep their order
+func (s *CoreServer) drainOutbox(clusterName string) {
+	if deliveries.outbox == nil {
+		return
+	}
+	ctx := context.Background()
+	lock := deliveries.clusterLock(clusterName)
+	lock.Lock()
+	defer lock.Unlock()
+	defer deliveries.updateOutboxDepth(ctx, clusterName)
+
+	entries, err := deliveries.outbox.List(ctx, clusterName)
+	if err != nil {
+		deliveryLog.Error(err, fmt.Sprintf("list outbox of cluster(%s) failed", clusterName))
+		return
+	}
+	if len(entries) > 0 {
+		deliveryLog.Info(fmt.Sprintf("drain %d queued changes of cluster(%s)", len(entries), clusterName))
+	}
+	for _, entry := range entries {
+		d, err := newDelivery(clusterName, entry.Operation, entry.ClusterResource)
+		if err != nil {
+			deliveryLog.Error(err, fmt.Sprintf("build delivery of clusterResource(%s) failed", entry.ClusterResource.Name))
+			continue
+		}
+		if err = SendResourceToProxy(clusterName, d.response); err != nil {
+			deliveryLog.Error(err, fmt.Sprintf("drain outbox of cluster(%s) interrupted", clusterName))
+			return
+		}
+		deliveries.track(d)
+		if _, err = deliveries.outbox.Remove(ctx, clusterName, entry.ClusterResource.Name); err != nil {
+			deliveryLog.Error(err, fmt.Sprintf("remove clusterResource(%s) from outbox failed", d.key()))
+		}
+		if err = updateDeliveryState(ctx, s.mClient, d.key(), newDeliveryState(*d, v1alpha1.DeliveryPending, "")); err != nil {
+			deliveryLog.Error(err, fmt.Sprintf("update delivery state of clusterResource(%s) failed", d.key()))
+		}
+	}
+}
+
+func newDeliveryState(d delivery, phase v1alpha1.ClusterResourceDeliveryPhase, message string) *v1alpha1.ClusterResourceDelivery {
+	lastAttempt := metav1.NewTime(d.lastAttempt)
+	return &v1alpha1.ClusterResourceDelivery{
+		RequestID:       d.response.RequestId,
+		Phase:           phase,
+		Attempts:        d.attempts,
+		LastAttemptTime: &lastAttempt,
+		Message:         message,
+	}
+}
+
+// updateDeliveryState writes delivery state to ClusterResource
+func updateDeliveryState(ctx context.Context, mClient *multclusterclient.Clientset, key types.NamespacedName, state *v1alpha1.ClusterResourceDelivery) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterResource, err := mClient.MulticlusterV1alpha1().ClusterResources(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		clusterResource.Status.Delivery = state
+		_, err = mClient.MulticlusterV1alpha1().ClusterResources(key.Namespace).UpdateStatus(ctx, clusterResource, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		// ClusterResource is deleted, the delete delivery has no place to record state
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/core/outbox"
+	table "harmonycloud.cn/stellaris/pkg/core/stream"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/protocol"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestDelivery(requestID string, key types.NamespacedName, nextRetry time.Time) *delivery {
+	clusterResource := &v1alpha1.ClusterResource{}
+	clusterResource.Namespace, clusterResource.Name = key.Namespace, key.Name
+	return &delivery{
+		clusterResource: clusterResource,
+		response:        &config.Response{RequestId: requestID},
+		attempts:        1,
+		nextRetry:       nextRetry,
+	}
+}
+
+func TestDeliveryTrackerRetryAndAck(t *testing.T) {
+	tracker := newDeliveryTracker()
+	key := types.NamespacedName{Namespace: "stellaris-harmonycloud-cn-cluster-a", Name: "cr"}
+	now := time.Now()
+
+	tracker.track(newTestDelivery("1", key, now.Add(time.Minute)))
+	if due := tracker.due(now); len(due) != 0 {
+		t.Fatalf("delivery should not be retried before next retry time, but got %d", len(due))
+	}
+	// newer delivery of the same ClusterResource supersedes the old one
+	tracker.track(newTestDelivery("2", key, now))
+	if _, ok := tracker.ack("1", true); ok {
+		t.Fatal("ack of superseded delivery should be ignored")
+	}
+	due := tracker.due(now)
+	if len(due) != 1 || due[0].response.RequestId != "2" || due[0].attempts != 2 {
+		t.Fatalf("expect retry of delivery 2 with attempts 2, but got %v", due)
+	}
+	if due = tracker.due(now); len(due) != 0 {
+		t.Fatal("retried delivery should wait for backoff")
+	}
+	if _, ok := tracker.ack("2", false); !ok {
+		t.Fatal("failed ack should be accepted")
+	}
+	if due = tracker.due(now.Add(deliveryRetryMaxInterval)); len(due) != 1 {
+		t.Fatal("delivery should be retried after failed ack")
+	}
+	if _, ok := tracker.ack("2", true); !ok {
+		t.Fatal("ack should be accepted")
+	}
+	if due = tracker.due(now.Add(time.Hour)); len(due) != 0 {
+		t.Fatal("acknowledged delivery should not be retried")
+	}
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second}
+	for i, interval := range expected {
+		if got := deliveryBackoff(int32(i + 1)); got != interval {
+			t.Errorf("attempts %d: expect %s, but got %s", i+1, interval, got)
+		}
+	}
+	if got := deliveryBackoff(100); got != deliveryRetryMaxInterval {
+		t.Errorf("backoff should be capped at %s, but got %s", deliveryRetryMaxInterval, got)
+	}
+}
+
+type fullStream struct {
+	config.Channel_EstablishServer
+}
+
+func (s *fullStream) Send(*config.Response) error {
+	return protocol.ErrSendQueueFull
+}
+
+func TestDeliverResourceQueuesOnlyOfflineProxy(t *testing.T) {
+	ctx := context.Background()
+	box := outbox.New(fake.NewSimpleClientset())
+	last := deliveries
+	deliveries = newDeliveryTracker()
+	deliveries.outbox = box
+	defer func() { deliveries = last }()
+
+	// the connected proxy can not keep up, the delivery is retried instead of waiting for the next register
+	stream := &fullStream{}
+	table.Insert("cluster-a", &table.Stream{ClusterName: "cluster-a", Stream: stream, Status: table.OK, Expire: time.Now().Add(time.Minute)})
+	defer table.Remove("cluster-a", stream)
+	connected := &v1alpha1.ClusterResource{}
+	connected.Namespace, connected.Name = "stellaris-harmonycloud-cn-cluster-a", "connected"
+	if _, err := DeliverResourceToProxy("cluster-a", model.ResourceUpdateOrCreate, connected); err != nil {
+		t.Fatal(err)
+	}
+	if depth, _ := box.Depth(ctx, "cluster-a"); depth != 0 || !deliveries.hasPending(types.NamespacedName{Namespace: connected.Namespace, Name: connected.Name}) {
+		t.Fatalf("delivery to connected proxy should be tracked, outbox depth %d", depth)
+	}
+
+	offline := &v1alpha1.ClusterResource{}
+	offline.Namespace, offline.Name = "stellaris-harmonycloud-cn-cluster-b", "offline"
+	if _, err := DeliverResourceToProxy("cluster-b", model.ResourceUpdateOrCreate, offline); err != nil {
+		t.Fatal(err)
+	}
+	if depth, _ := box.Depth(ctx, "cluster-b"); depth != 1 || deliveries.hasPending(types.NamespacedName{Namespace: offline.Namespace, Name: offline.Name}) {
+		t.Fatalf("delivery to offline proxy should be queued in outbox, outbox depth %d", depth)
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/utils/core"
+	"harmonycloud.cn/stellaris/pkg/utils/digest"
+)
+
+// digestMismatches is the number of ClusterResources which differ between control plane and member cluster
+var digestMismatches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "stellaris_core_resource_digest_mismatches",
+	Help: "Number of ClusterResources which differ between control plane and member cluster in the last heartbeat digest",
+}, []string{"cluster"})
+
+func init() {
+	metrics.Registry.MustRegister(digestMismatches)
+}
+
+// checkDigest compares the digest in heartbeat with the cluster namespace, the differing ClusterResources are
+// delivered again and the ones which only exist in member cluster are sent to proxy as orphans
+func (s *CoreServer) checkDigest(ctx context.Context, clusterName string, remote *model.ResourceDigest, stream config.Channel_EstablishServer) error {
+	if remote == nil {
+		return nil
+	}
+	clusterResourceList, err := s.mClient.MulticlusterV1alpha1().ClusterResources(managerCommon.ClusterNamespace(clusterName)).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	upstream := make(map[types.NamespacedName]*v1alpha1.ClusterResource, len(clusterResourceList.Items))
+	var alive []v1alpha1.ClusterResource
+	for i := range clusterResourceList.Items {
+		clusterResource := &clusterResourceList.Items[i]
+		if !clusterResource.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		upstream[types.NamespacedName{Namespace: clusterResource.Namespace, Name: clusterResource.Name}] = clusterResource
+		alive = append(alive, *clusterResource)
+	}
+	local, err := digest.New(alive)
+	if err != nil {
+		return err
+	}
+	if local.Checksum == remote.Checksum {
+		digestMismatches.WithLabelValues(clusterName).Set(0)
+		return nil
+	}
+
+	coreOnly, proxyOnly, changed := digest.Diff(local, remote)
+	mismatches := len(coreOnly) + len(proxyOnly) + len(changed)
+	digestMismatches.WithLabelValues(clusterName).Set(float64(mismatches))
+	coreHeartbeatLog.Info(fmt.Sprintf("digest of cluster(%s) mismatches, missing:%d, orphaned:%d, changed:%d", clusterName, len(coreOnly), len(proxyOnly), len(changed)))
+
+	for _, item := range append(changed, coreOnly...) {
+		key := types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+		// the change is on the way to proxy
+		if deliveries.hasPending(key) {
+			continue
+		}
+		s.redeliver(ctx, clusterName, upstream[key])
+	}
+
+	response := &model.ResyncResponse{}
+	for _, item := range proxyOnly {
+		if deliveries.hasPending(types.NamespacedName{Namespace: item.Namespace, Name: item.Name}) {
+			continue
+		}
+		response.Orphans = append(response.Orphans, model.ResyncItem{Name: item.Name, Namespace: item.Namespace})
+	}
+	if len(response.Orphans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	core.SendResponse(&config.Response{
+		Type:        model.ResyncSuccess.String(),
+		ClusterName: clusterName,
+		Body:        string(body),
+	}, stream)
+	return nil
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var eventLog = logf.Log.WithName("core_event")
+
+const (
+	// ClusterEventAnnotation is the name of member cluster which the re-emitted Event comes from
+	ClusterEventAnnotation = "stellaris.harmonycloud.cn/cluster"
+	// InvolvedObjectEventAnnotation is the object in member cluster, the format is kind/namespace/name
+	InvolvedObjectEventAnnotation = "stellaris.harmonycloud.cn/involved-object"
+)
+
+// eventTargets are the objects in control plane which the Events of one ClusterResource are emitted on
+type eventTargets struct {
+	binding              *v1alpha1.MultiClusterResourceBinding
+	multiClusterResource *v1alpha1.MultiClusterResource
+}
+
+// Event emits the Events in member cluster on the MultiClusterResource and MultiClusterResourceBinding of
+// the ClusterResource which owns the involved object, proxy does not wait for a reply
+func (s *CoreServer) Event(req *config.Request, stream config.Channel_EstablishServer) {
+	data := &model.EventRequest{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		eventLog.Error(err, "unmarshal data error")
+		return
+	}
+	if s.recorder == nil {
+		return
+	}
+	ctx := context.Background()
+	targets := make(map[string]*eventTargets)
+	for _, event := range data.Events {
+		target, ok := targets[event.ClusterResource]
+		if !ok {
+			var err error
+			if target, err = s.eventTargets(ctx, req.ClusterName, event.ClusterResource); err != nil {
+				eventLog.Error(err, fmt.Sprintf("find owners of clusterResource(%s) in cluster(%s) failed", event.ClusterResource, req.ClusterName))
+			}
+			targets[event.ClusterResource] = target
+		}
+		if target == nil {
+			continue
+		}
+		annotations := map[string]string{
+			ClusterEventAnnotation:        req.ClusterName,
+			InvolvedObjectEventAnnotation: fmt.Sprintf("%s/%s/%s", event.Kind, event.Namespace, event.Name),
+		}
+		for _, obj := range []runtime.Object{target.multiClusterResource, target.binding} {
+			s.recorder.AnnotatedEventf(obj, annotations, event.Type, event.Reason, "cluster(%s) %s %s/%s: %s",
+				req.ClusterName, event.Kind, event.Namespace, event.Name, event.Message)
+		}
+	}
+}
+
+// eventTargets finds the binding by the controller of ClusterResource, and the MultiClusterResource in the binding
+func (s *CoreServer) eventTargets(ctx context.Context, clusterName, clusterResourceName string) (*eventTargets, error) {
+	clusterResource, err := s.mClient.MulticlusterV1alpha1().ClusterResources(managerCommon.ClusterNamespace(clusterName)).Get(ctx, clusterResourceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	owner := metav1.GetControllerOf(clusterResource)
+	if owner == nil {
+		return nil, fmt.Errorf("clusterResource(%s) has no binding", clusterResource.Name)
+	}
+	// the namespace of binding is not in the owner reference, it is found by name and uid
+	bindingList, err := s.mClient.MulticlusterV1alpha1().MultiClusterResourceBindings(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", owner.Name).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	targets := &eventTargets{}
+	for i := range bindingList.Items {
+		if bindingList.Items[i].UID == owner.UID {
+			targets.binding = &bindingList.Items[i]
+			break
+		}
+	}
+	if targets.binding == nil {
+		return nil, fmt.Errorf("binding(%s) of clusterResource(%s) is not found", owner.Name, clusterResource.Name)
+	}
+	multiClusterResourceName := clusterResource.Labels[managerCommon.MultiClusterResourceLabelName]
+	for _, resource := range targets.binding.Spec.Resources {
+		if resource.Name != multiClusterResourceName {
+			continue
+		}
+		namespace := resource.Namespace
+		if len(namespace) == 0 {
+			namespace = managerCommon.ManagerNamespace
+		}
+		if targets.multiClusterResource, err = s.mClient.MulticlusterV1alpha1().MultiClusterResources(namespace).Get(ctx, resource.Name, metav1.GetOptions{}); err != nil {
+			return nil, err
+		}
+		return targets, nil
+	}
+	return nil, fmt.Errorf("multiClusterResource(%s) is not in binding(%s:%s)", multiClusterResourceName, targets.binding.Namespace, targets.binding.Name)
+}
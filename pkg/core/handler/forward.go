@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/config/forward"
+	"harmonycloud.cn/stellaris/pkg/core/route"
+	table "harmonycloud.cn/stellaris/pkg/core/stream"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
+)
+
+var forwardLog = logf.Log.WithName("core_forward")
+
+// router is nil when core runs in one replica, all proxy streams are in the local table then
+var router *route.Router
+
+// EnableRouting makes core record its proxy streams in Leases and forward sends to the owner replica
+func EnableRouting(r *route.Router) {
+	router = r
+}
+
+// claimStream records this replica as the holder of cluster stream
+func claimStream(clusterName string) {
+	if router == nil {
+		return
+	}
+	if err := router.Claim(context.Background(), clusterName); err != nil {
+		forwardLog.Error(err, fmt.Sprintf("claim stream lease of cluster(%s) failed", clusterName))
+	}
+}
+
+// releaseStream gives up the stream lease after the stream is removed from table
+func releaseStream(clusterName string) {
+	if router == nil {
+		return
+	}
+	if err := router.Release(context.Background(), clusterName); err != nil {
+		forwardLog.Error(err, fmt.Sprintf("release stream lease of cluster(%s) failed", clusterName))
+	}
+}
+
+// sendToOwner forwards the response to the replica which holds the proxy stream
+func sendToOwner(clusterName string, res *config.Response) error {
+	ctx := context.Background()
+	owner, err := router.Owner(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if owner == router.Identity() {
+		return fmt.Errorf("stream of proxy(%s) is not in table of its owner replica", clusterName)
+	}
+	forwardLog.Info(fmt.Sprintf("forward response(%s) of cluster(%s) to replica(%s)", res.RequestId, clusterName, owner))
+	return router.SendToProxy(ctx, owner, res)
+}
+
+// proxyOffline tells whether no replica holds the stream of proxy. A failed send to a connected proxy, such as
+// a full send queue, is not offline
+func proxyOffline(clusterName string) bool {
+	if table.FindStream(clusterName) != nil {
+		return false
+	}
+	if router == nil {
+		return true
+	}
+	_, err := router.Owner(context.Background(), clusterName)
+	return err == route.ErrNoOwner
+}
+
+// ackToOrigin carries the ack back to the replica which sent the forwarded response
+func ackToOrigin(req *config.Request) bool {
+	if router == nil {
+		return false
+	}
+	origin, ok := router.TakeOrigin(req.RequestId)
+	if !ok {
+		return false
+	}
+	if err := router.HandleRequest(context.Background(), origin, req); err != nil {
+		forwardLog.Error(err, fmt.Sprintf("forward ack of request(%s) to replica(%s) failed", req.RequestId, origin))
+	}
+	return true
+}
+
+// Forward serves the internal grpc endpoint which other core replicas call
+type Forward struct {
+	Server *CoreServer
+	// PeerName is the common name of core certificate, proxies share the CA with core but must not call the endpoint
+	PeerName string
+}
+
+func (f *Forward) authorize(ctx context.Context) error {
+	if len(f.PeerName) == 0 {
+		return status.Error(codes.Unauthenticated, "forward requires grpc mutual tls")
+	}
+	name, err := certificate.ClusterNameFromContext(ctx)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if name != f.PeerName {
+		return status.Errorf(codes.PermissionDenied, "%s is not a core replica", name)
+	}
+	return nil
+}
+
+func (f *Forward) SendToProxy(ctx context.Context, in *forward.ProxyResponse) (*forward.Result, error) {
+	if err := f.authorize(ctx); err != nil {
+		return nil, err
+	}
+	stream := table.FindStream(in.ClusterName)
+	if stream == nil {
+		return nil, status.Errorf(codes.NotFound, "cannot find proxy(%s) stream", in.ClusterName)
+	}
+	if router != nil {
+		router.RememberOrigin(in.RequestId, in.Origin)
+	}
+	err := stream.Stream.Send(&config.Response{
+		Type:        in.Type,
+		ClusterName: in.ClusterName,
+		Body:        in.Body,
+		RequestId:   in.RequestId,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &forward.Result{}, nil
+}
+
+// HandleRequest only accepts ack, other requests must come from the proxy stream
+func (f *Forward) HandleRequest(ctx context.Context, in *forward.ProxyRequest) (*forward.Result, error) {
+	if err := f.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if in.Type != model.Ack.String() {
+		return nil, status.Errorf(codes.InvalidArgument, "request type %s can not be forwarded", in.Type)
+	}
+	f.Server.Ack(&config.Request{
+		Type:        in.Type,
+		ClusterName: in.ClusterName,
+		Body:        in.Body,
+		RequestId:   in.RequestId,
+	}, nil)
+	return &forward.Result{}, nil
+}
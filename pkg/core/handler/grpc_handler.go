@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
 	multclusterclient "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
 	corecfg "harmonycloud.cn/stellaris/pkg/core/config"
+	"harmonycloud.cn/stellaris/pkg/core/outbox"
 	"harmonycloud.cn/stellaris/pkg/model"
 )
 
@@ -10,12 +14,23 @@ type CoreServer struct {
 	Handlers map[string][]Fn
 	Config   *corecfg.Configuration
 	mClient  *multclusterclient.Clientset
+	// kubeClient reads bootstrap token secrets in control plane
+	kubeClient kubernetes.Interface
+	// sessions lets a reconnected proxy resume without full resync
+	sessions *resumeSessionStore
+	// recorder records the resync of proxy on its Cluster
+	recorder record.EventRecorder
 }
 
-func NewCoreServer(cfg *corecfg.Configuration, mClient *multclusterclient.Clientset) *CoreServer {
+func NewCoreServer(cfg *corecfg.Configuration, mClient *multclusterclient.Clientset, kubeClient kubernetes.Interface) *CoreServer {
 	s := &CoreServer{Config: cfg}
 	s.mClient = mClient
+	s.kubeClient = kubeClient
+	s.sessions = newResumeSessionStore(cfg.SessionTTL)
+	s.recorder = newEventRecorder(kubeClient)
 	s.init()
+	deliveries.outbox = outbox.New(kubeClient)
+	deliveries.mClient = mClient
 	return s
 }
 
@@ -25,6 +40,11 @@ func (s *CoreServer) init() {
 	s.registerHandler(model.Heartbeat.String(), s.Heartbeat)
 	s.registerHandler(model.Resource.String(), s.Resource)
 	s.registerHandler(model.Aggregate.String(), s.Aggregate)
+	s.registerHandler(model.Ack.String(), s.Ack)
+	s.registerHandler(model.Resync.String(), s.Resync)
+	s.registerHandler(model.TunnelChunk.String(), s.TunnelChunk)
+	s.registerHandler(model.LogChunk.String(), s.LogChunk)
+	s.registerHandler(model.Event.String(), s.Event)
 }
 
 func (s *CoreServer) registerHandler(typ string, fn Fn) {
@@ -1,13 +1,23 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"io"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/sirupsen/logrus"
 	"harmonycloud.cn/stellaris/config"
+	v2 "harmonycloud.cn/stellaris/config/v2"
+	clusterController "harmonycloud.cn/stellaris/pkg/controller/cluster"
+	table "harmonycloud.cn/stellaris/pkg/core/stream"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/protocol"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
+	"harmonycloud.cn/stellaris/pkg/utils/core"
 )
 
 var coreServerLog = logf.Log.WithName("core_server")
@@ -21,6 +31,17 @@ type Channel struct {
 func (c *Channel) Establish(stream config.Channel_EstablishServer) error {
 	clusterName := "(unknown)"
 
+	sess := &session{}
+	if c.Server.Config.RequireClientCertificate {
+		name, err := certificate.ClusterNameFromContext(stream.Context())
+		if err != nil {
+			coreServerLog.Error(err, "get cluster identity from client certificate failed")
+			return err
+		}
+		sess.certIdentity = name
+		clusterName = name
+	}
+
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
@@ -33,6 +54,15 @@ func (c *Channel) Establish(stream config.Channel_EstablishServer) error {
 			logrus.Error(err)
 			continue
 		}
+		if err := c.Server.authorize(sess, req); err != nil {
+			coreServerLog.Error(err, "reject request")
+			resType := model.Error
+			if req.Type == model.Register.String() {
+				resType = model.RegisterFailed
+			}
+			core.SendErrResponse(req.ClusterName, resType, err, stream)
+			continue
+		}
 		if clusterName == "(unknown)" {
 			clusterName = req.ClusterName
 		}
@@ -42,9 +72,38 @@ func (c *Channel) Establish(stream config.Channel_EstablishServer) error {
 	}
 
 	coreServerLog.Info(fmt.Sprintf("connection with %s interrupt", clusterName))
+	if clusterName != "(unknown)" && table.Remove(clusterName, stream) {
+		releaseStream(clusterName)
+		c.Server.disconnectCluster(clusterName)
+	}
 	return nil
 }
 
+// disconnectCluster changes cluster status to unknown right after its stream is removed
+func (s *CoreServer) disconnectCluster(clusterName string) {
+	digestMismatches.DeleteLabelValues(clusterName)
+	ctx := context.Background()
+	cluster, err := s.mClient.MulticlusterV1alpha1().Clusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err != nil {
+		coreServerLog.Error(err, fmt.Sprintf("get cluster(%s) failed", clusterName))
+		return
+	}
+	if err = clusterController.DisconnectCluster(ctx, s.mClient, cluster); err != nil {
+		coreServerLog.Error(err, fmt.Sprintf("change cluster(%s) status to unknown failed", clusterName))
+		return
+	}
+	coreServerLog.Info(fmt.Sprintf("cluster(%s) is disconnected", clusterName))
+}
+
+// ChannelV2 serves the typed v2 protocol, requests are converted to v1 and processed by the same handlers
+type ChannelV2 struct {
+	Server *CoreServer
+}
+
+func (c *ChannelV2) Establish(stream v2.Channel_EstablishServer) error {
+	return (&Channel{Server: c.Server}).Establish(protocol.NewServerStream(stream))
+}
+
 func validate(request *config.Request) error {
 	if request.Type == "" {
 		return fmt.Errorf("type field is empty in request")
@@ -41,6 +41,12 @@ func (s *CoreServer) Heartbeat(req *config.Request, stream config.Channel_Establ
 		core.SendErrResponse(req.ClusterName, model.HeartbeatFailed, err, stream)
 	}
 
+	if err = s.checkDigest(context.Background(), req.ClusterName, data.Digest, stream); err != nil {
+		coreHeartbeatLog.Error(err, fmt.Sprintf("check resource digest of cluster(%s) failed", req.ClusterName))
+	}
+
+	s.sessions.touch(req.ClusterName)
+	claimStream(req.ClusterName)
 	table.Insert(req.ClusterName, &table.Stream{
 		ClusterName: req.ClusterName,
 		Stream:      stream,
@@ -52,6 +58,7 @@ func (s *CoreServer) Heartbeat(req *config.Request, stream config.Channel_Establ
 		Type:        model.HeartbeatSuccess.String(),
 		ClusterName: req.ClusterName,
 		Body:        req.Body,
+		RequestId:   req.RequestId,
 	}
 	core.SendResponse(res, stream)
 }
@@ -68,21 +75,21 @@ func (s *CoreServer) updateClusterWithHeartbeat(clusterName string, heartbeatReq
 		return err
 	}
 
+	// proxy fails to read nodes sometimes, the last summary is kept
+	if heartbeatRequest.ResourceSummary != nil {
+		cluster.Status.ResourceSummary = heartbeatRequest.ResourceSummary
+	}
 	return s.updateClusterStatusWithHeartbeat(ctx, cluster, heartbeatRequest.Conditions, heartbeatRequest.Healthy)
 }
 
 func (s *CoreServer) updateClusterStatusWithHeartbeat(ctx context.Context, cluster *v1alpha1.Cluster, conditions []model.Condition, healthy bool) error {
-	if len(conditions) > 0 {
-		clusterConditions := core.ConvertCondition2KubeCondition(conditions)
-		cluster.Status.Conditions = append(cluster.Status.Conditions, clusterConditions...)
-	}
-	if cluster.Status.Status != v1alpha1.OnlineStatus {
-		clusterConditions := clusterHealth.GenerateReadyCondition(true, healthy)
-		cluster.Status.Conditions = append(cluster.Status.Conditions, clusterConditions...)
-	}
+	clusterHealth.SetProxyConditions(&cluster.Status, conditions, cluster.Generation)
+	clusterHealth.SetHealthy(&cluster.Status, healthy, cluster.Generation)
 	nowTime := v1.Time{Time: timeutils.NowTimeWithLoc()}
-	cluster.Status.Status = v1alpha1.OnlineStatus
-	cluster.Status.Healthy = healthy
+	if transition := s.Config.Thresholds().OnHeartbeat(&cluster.Status, nowTime.Time); transition != nil {
+		coreHeartbeatLog.Info(fmt.Sprintf("cluster(%s) changes from %s to %s", cluster.Name, cluster.Status.Status, transition.State))
+		clusterHealth.SetState(&cluster.Status, transition, cluster.Generation)
+	}
 	cluster.Status.LastReceiveHeartBeatTimestamp = nowTime
 	cluster.Status.LastUpdateTimestamp = nowTime
 
@@ -47,25 +47,42 @@ func (s *CoreServer) Register(req *config.Request, stream config.Channel_Establi
 	}
 	coreRegisterLog.Info(fmt.Sprintf("register cluster(%s) success", cluster.Name))
 
-	// write stream into stream table
-	table.Insert(req.ClusterName, &table.Stream{
+	// write stream into stream table, the stream of re-register replaces the broken one
+	table.Replace(req.ClusterName, &table.Stream{
 		ClusterName: req.ClusterName,
 		Stream:      stream,
 		Status:      table.OK,
 		Expire:      timeutils.NowTimeWithLoc().Add(s.Config.HeartbeatExpirePeriod * time.Second),
 	})
 
-	res := s.newResponse(req.ClusterName)
+	claimStream(req.ClusterName)
+
+	res := s.newResponse(req.ClusterName, s.sessions.resume(req.ClusterName, data.SessionToken))
+	res.RequestId = req.RequestId
 	core.SendResponse(res, stream)
+
+	// send the changes queued while proxy is offline
+	go s.drainOutbox(req.ClusterName)
 }
 
-func (s *CoreServer) newResponse(clusterName string) *config.Response {
+// newResponse builds register response, resources are not sent when the session is resumed
+func (s *CoreServer) newResponse(clusterName string, resumed bool) *config.Response {
 	res := &config.Response{
 		Type:        model.RegisterSuccess.String(),
 		ClusterName: clusterName,
 	}
 	// body
-	body, _ := s.getRegisterResources(clusterName)
+	body := &model.RegisterResponse{Resumed: resumed}
+	if resumed {
+		coreRegisterLog.Info(fmt.Sprintf("session of cluster(%s) is resumed", clusterName))
+	} else {
+		body, _ = s.getRegisterResources(clusterName)
+	}
+	token, err := s.sessions.issue(clusterName)
+	if err != nil {
+		coreRegisterLog.Error(err, fmt.Sprintf("issue session token of cluster(%s) failed", clusterName))
+	}
+	body.SessionToken = token
 	if !body.IsEmpty() {
 		bodyData, err := json.Marshal(body)
 		if err == nil {
@@ -102,9 +119,10 @@ func (s *CoreServer) registerClusterInKube(cluster *v1alpha1.Cluster) error {
 		nowTime := v1.Time{Time: timeutils.NowTimeWithLoc()}
 		existCluster.Status.LastUpdateTimestamp = nowTime
 		existCluster.Status.LastReceiveHeartBeatTimestamp = nowTime
-		existCluster.Status.Status = v1alpha1.OnlineStatus
-		existCluster.Status.Healthy = true
-		existCluster.Status.Conditions = append(existCluster.Status.Conditions, clusterHealth.GenerateReadyCondition(true, true)...)
+		// the Healthy condition is kept until the next heartbeat reports the health of cluster
+		if transition := s.Config.Thresholds().OnHeartbeat(&existCluster.Status, nowTime.Time); transition != nil {
+			clusterHealth.SetState(&existCluster.Status, transition, existCluster.Generation)
+		}
 		_, err = clusterController.UpdateClusterStatus(ctx, s.mClient, existCluster)
 		return err
 	}
@@ -39,6 +39,7 @@ func (s *CoreServer) Resource(req *config.Request, stream config.Channel_Establi
 		Type:        model.ResourceStatusUpdateSuccess.String(),
 		ClusterName: req.ClusterName,
 		Body:        "",
+		RequestId:   req.RequestId,
 	}, stream)
 }
 
@@ -50,6 +51,8 @@ func (s *CoreServer) syncClusterResourceStatus(statusList []model.ClusterResourc
 			resourceHandlerLog.Error(err, fmt.Sprintf("get clusterResource(%s:%s) failed", item.Namespace, item.Name))
 			return err
 		}
+		// delivery state is owned by core
+		item.Status.Delivery = clusterResource.Status.Delivery
 		if reflect.DeepEqual(clusterResource.Status, item.Status) {
 			resourceHandlerLog.Info(fmt.Sprintf("clusterResource(%s:%s) status is no changed", item.Namespace, item.Name))
 			continue
@@ -68,6 +71,14 @@ func (s *CoreServer) syncClusterResourceStatus(statusList []model.ClusterResourc
 func SendResourceToProxy(clusterName string, resourceResponse *config.Response) error {
 	resourceHandlerLog.Info(fmt.Sprintf("start to send resource request to proxy"))
 	stream := table.FindStream(clusterName)
+	if stream == nil && router != nil {
+		// the proxy may be connected to another replica
+		if err := sendToOwner(clusterName, resourceResponse); err != nil {
+			resourceHandlerLog.Error(err, fmt.Sprintf("forward resource to proxy(%s) failed", clusterName))
+			return err
+		}
+		return nil
+	}
 	if stream == nil {
 		err := errors.New(fmt.Sprintf("cannot find proxy(%s) stream", clusterName))
 		resourceHandlerLog.Error(err, "find proxy stream failed")
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
+)
+
+// resumeSession is the session of a registered proxy, it lives in memory, so that a restarted core
+// always makes proxies resync fully
+type resumeSession struct {
+	token    string
+	lastSeen time.Time
+}
+
+// resumeSessionStore keeps the latest session of every cluster
+type resumeSessionStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]*resumeSession
+}
+
+func newResumeSessionStore(ttl time.Duration) *resumeSessionStore {
+	return &resumeSessionStore{
+		ttl:      ttl,
+		sessions: make(map[string]*resumeSession),
+	}
+}
+
+// resume checks the token of cluster, the session can be resumed when the token matches and it is not expired
+func (s *resumeSessionStore) resume(clusterName, token string) bool {
+	if len(token) == 0 || s.ttl <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[clusterName]
+	if !ok {
+		return false
+	}
+	if timeutils.NowTimeWithLoc().Sub(session.lastSeen) > s.ttl {
+		delete(s.sessions, clusterName)
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(session.token), []byte(token)) == 1
+}
+
+// issue creates a new session token of cluster, the old one is invalid after that
+func (s *resumeSessionStore) issue(clusterName string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[clusterName] = &resumeSession{token: token, lastSeen: timeutils.NowTimeWithLoc()}
+	return token, nil
+}
+
+// touch refreshes the session when the proxy is seen
+func (s *resumeSessionStore) touch(clusterName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[clusterName]; ok {
+		session.lastSeen = timeutils.NowTimeWithLoc()
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResumeSessionStore(t *testing.T) {
+	store := newResumeSessionStore(time.Minute)
+	if store.resume("cluster-a", "") {
+		t.Fatal("empty token should not resume")
+	}
+	token, err := store.issue("cluster-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.resume("cluster-a", token) {
+		t.Fatal("issued token should resume")
+	}
+	if store.resume("cluster-b", token) {
+		t.Fatal("token of another cluster should not resume")
+	}
+	newToken, _ := store.issue("cluster-a")
+	if store.resume("cluster-a", token) {
+		t.Fatal("old token should be invalid after a new one is issued")
+	}
+	store.sessions["cluster-a"].lastSeen = time.Now().Add(-2 * time.Minute)
+	if store.resume("cluster-a", newToken) {
+		t.Fatal("expired session should not resume")
+	}
+}
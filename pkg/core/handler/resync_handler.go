@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/client/clientset/versioned/scheme"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/utils/core"
+)
+
+var resyncLog = logf.Log.WithName("core_resync")
+
+const (
+	ResyncEventReason       = "Resync"
+	ResyncFailedEventReason = "ResyncFailed"
+)
+
+func newEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "stellaris-core"})
+}
+
+// Resync compares the inventory of proxy with the ClusterResources in control plane, proxy deletes the orphans
+// and reports the stale status again, the missing ones are delivered to proxy again
+func (s *CoreServer) Resync(req *config.Request, stream config.Channel_EstablishServer) {
+	resyncLog.Info(fmt.Sprintf("receive grpc request for resync, cluster:%s", req.ClusterName))
+	ctx := context.Background()
+	data := &model.ResyncRequest{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		resyncLog.Error(err, "unmarshal data error")
+		s.sendResyncFailed(ctx, req, err, stream)
+		return
+	}
+	clusterNamespace := managerCommon.ClusterNamespace(req.ClusterName)
+	clusterResourceList, err := s.mClient.MulticlusterV1alpha1().ClusterResources(clusterNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		resyncLog.Error(err, fmt.Sprintf("list clusterResources of cluster(%s) failed", req.ClusterName))
+		s.sendResyncFailed(ctx, req, err, stream)
+		return
+	}
+	response, missing := diffInventory(clusterNamespace, data.Inventory, clusterResourceList.Items)
+	body, err := json.Marshal(response)
+	if err != nil {
+		s.sendResyncFailed(ctx, req, err, stream)
+		return
+	}
+	core.SendResponse(&config.Response{
+		Type:        model.ResyncSuccess.String(),
+		ClusterName: req.ClusterName,
+		Body:        string(body),
+		RequestId:   req.RequestId,
+	}, stream)
+
+	message := fmt.Sprintf("resync finished, reported:%d, in control plane:%d, orphaned:%d, stale:%d, missing:%d",
+		len(data.Inventory), len(response.Resources), len(response.Orphans), len(response.Stale), len(missing))
+	resyncLog.Info(fmt.Sprintf("cluster(%s) %s", req.ClusterName, message))
+	s.recordClusterEvent(ctx, req.ClusterName, corev1.EventTypeNormal, ResyncEventReason, message)
+
+	for _, clusterResource := range missing {
+		s.redeliver(ctx, req.ClusterName, clusterResource)
+	}
+}
+
+func (s *CoreServer) sendResyncFailed(ctx context.Context, req *config.Request, err error, stream config.Channel_EstablishServer) {
+	s.recordClusterEvent(ctx, req.ClusterName, corev1.EventTypeWarning, ResyncFailedEventReason, err.Error())
+	core.SendResponse(&config.Response{
+		Type:        model.ResyncFailed.String(),
+		ClusterName: req.ClusterName,
+		Body:        err.Error(),
+		RequestId:   req.RequestId,
+	}, stream)
+}
+
+// redeliver sends the ClusterResource which proxy does not have, it is tracked as a normal delivery
+func (s *CoreServer) redeliver(ctx context.Context, clusterName string, clusterResource *v1alpha1.ClusterResource) {
+	key := types.NamespacedName{Namespace: clusterResource.Namespace, Name: clusterResource.Name}
+	state, err := DeliverResourceToProxy(clusterName, model.ResourceUpdateOrCreate, clusterResource)
+	if err != nil {
+		resyncLog.Error(err, fmt.Sprintf("deliver missing clusterResource(%s) failed", key))
+		return
+	}
+	if err = updateDeliveryState(ctx, s.mClient, key, state); err != nil {
+		resyncLog.Error(err, fmt.Sprintf("update delivery state of clusterResource(%s) failed", key))
+	}
+}
+
+func (s *CoreServer) recordClusterEvent(ctx context.Context, clusterName, eventType, reason, message string) {
+	if s.recorder == nil {
+		return
+	}
+	cluster, err := s.mClient.MulticlusterV1alpha1().Clusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err != nil {
+		resyncLog.Error(err, fmt.Sprintf("get cluster(%s) failed, event is not recorded", clusterName))
+		return
+	}
+	s.recorder.Event(cluster, eventType, reason, message)
+}
+
+// diffInventory compares by namespace and name, the ClusterResource in member cluster keeps the cluster namespace
+// of control plane. The items in other namespaces belong to other clusters when proxy runs in control plane, they
+// are neither orphans nor reported
+func diffInventory(clusterNamespace string, inventory []model.ResyncItem, clusterResources []v1alpha1.ClusterResource) (*model.ResyncResponse, []*v1alpha1.ClusterResource) {
+	response := &model.ResyncResponse{}
+	upstream := make(map[types.NamespacedName]*v1alpha1.ClusterResource, len(clusterResources))
+	for i := range clusterResources {
+		clusterResource := &clusterResources[i]
+		// the ClusterResource is being deleted, proxy should not keep it
+		if !clusterResource.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		upstream[types.NamespacedName{Namespace: clusterResource.Namespace, Name: clusterResource.Name}] = clusterResource
+		response.Resources = append(response.Resources, model.ResyncItem{
+			Name:                      clusterResource.Name,
+			Namespace:                 clusterResource.Namespace,
+			Generation:                clusterResource.Generation,
+			ObservedReceiveGeneration: clusterResource.Status.ObservedReceiveGeneration,
+		})
+	}
+
+	reported := make(map[types.NamespacedName]bool, len(inventory))
+	for _, item := range inventory {
+		if item.Namespace != clusterNamespace {
+			continue
+		}
+		key := types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+		reported[key] = true
+		clusterResource, ok := upstream[key]
+		if !ok {
+			response.Orphans = append(response.Orphans, item)
+			continue
+		}
+		// the status is not observed in member cluster yet, it will be reported when it is
+		if item.ObservedReceiveGeneration == 0 {
+			continue
+		}
+		if clusterResource.Status.ObservedReceiveGeneration != item.ObservedReceiveGeneration {
+			response.Stale = append(response.Stale, item)
+		}
+	}
+
+	var missing []*v1alpha1.ClusterResource
+	for _, item := range response.Resources {
+		key := types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+		if !reported[key] {
+			missing = append(missing, upstream[key])
+		}
+	}
+	return response, missing
+}
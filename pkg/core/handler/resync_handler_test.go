@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func TestDiffInventory(t *testing.T) {
+	now := metav1.Now()
+	clusterNamespace := "stellaris-harmonycloud-cn-a"
+	newClusterResource := func(name string, observed int64) v1alpha1.ClusterResource {
+		return v1alpha1.ClusterResource{
+			ObjectMeta: metav1.ObjectMeta{Namespace: clusterNamespace, Name: name, Generation: 1},
+			Status:     v1alpha1.ClusterResourceStatus{ObservedReceiveGeneration: observed},
+		}
+	}
+	deleting := newClusterResource("deleting", 1)
+	deleting.DeletionTimestamp = &now
+	clusterResources := []v1alpha1.ClusterResource{
+		newClusterResource("synced", 2),
+		newClusterResource("stale", 1),
+		newClusterResource("pending", 0),
+		newClusterResource("missing", 0),
+		deleting,
+	}
+	inventory := []model.ResyncItem{
+		{Namespace: clusterNamespace, Name: "synced", Generation: 2, ObservedReceiveGeneration: 2},
+		{Namespace: clusterNamespace, Name: "stale", Generation: 3, ObservedReceiveGeneration: 3},
+		{Namespace: clusterNamespace, Name: "pending", Generation: 1},
+		{Namespace: clusterNamespace, Name: "orphan", Generation: 1, ObservedReceiveGeneration: 1},
+		{Namespace: clusterNamespace, Name: "deleting", Generation: 1, ObservedReceiveGeneration: 1},
+		// ClusterResource of another cluster, when proxy runs in control plane
+		{Namespace: "stellaris-harmonycloud-cn-b", Name: "missing", Generation: 1, ObservedReceiveGeneration: 1},
+	}
+
+	response, missing := diffInventory(clusterNamespace, inventory, clusterResources)
+	if len(response.Resources) != 4 {
+		t.Fatalf("expected 4 authoritative resources, got %d", len(response.Resources))
+	}
+	if len(response.Orphans) != 2 || response.Orphans[0].Name != "orphan" || response.Orphans[1].Name != "deleting" {
+		t.Fatalf("unexpected orphans %v", response.Orphans)
+	}
+	if len(response.Stale) != 1 || response.Stale[0].Name != "stale" {
+		t.Fatalf("unexpected stale %v", response.Stale)
+	}
+	if len(missing) != 1 || missing[0].Name != "missing" {
+		t.Fatalf("unexpected missing %v, the item of another cluster should not be reported", missing)
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/core/tunnel"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var tunnelHandlerLog = logf.Log.WithName("core_tunnel_handler")
+
+// TunnelChunk passes the response chunk of a tunnelled request to the waiting caller
+func (s *CoreServer) TunnelChunk(req *config.Request, stream config.Channel_EstablishServer) {
+	data := &model.TunnelResponse{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		tunnelHandlerLog.Error(err, "unmarshal data error")
+		return
+	}
+	if err := tunnel.Deliver(data); err != nil {
+		tunnelHandlerLog.Error(err, fmt.Sprintf("deliver chunk of tunnel %s from cluster(%s) failed", data.StreamID, req.ClusterName))
+	}
+}
+
+// LogChunk passes the container logs read by proxy to the waiting caller
+func (s *CoreServer) LogChunk(req *config.Request, stream config.Channel_EstablishServer) {
+	data := &model.LogResponse{}
+	if err := json.Unmarshal([]byte(req.Body), data); err != nil {
+		tunnelHandlerLog.Error(err, "unmarshal data error")
+		return
+	}
+	if err := tunnel.DeliverLog(data); err != nil {
+		tunnelHandlerLog.Error(err, fmt.Sprintf("deliver logs of stream %s from cluster(%s) failed", data.StreamID, req.ClusterName))
+	}
+}
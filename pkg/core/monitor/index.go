@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+const (
+	// policyClusterIndex indexes policy by the clusters assigned and the ClusterSet it schedules to
+	policyClusterIndex = "stellaris.policy.clusters"
+	// clusterSetClusterIndex indexes ClusterSet by its clusters, the ClusterSets with selector share selectorKey
+	clusterSetClusterIndex = "stellaris.clusterset.clusters"
+
+	// selectorKey is not a valid cluster name
+	selectorKey = "/selector"
+)
+
+func setupIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &v1alpha1.MultiClusterResourceSchedulePolicy{}, policyClusterIndex, policyClusters); err != nil {
+		return err
+	}
+	return indexer.IndexField(ctx, &v1alpha1.ClusterSet{}, clusterSetClusterIndex, clusterSetClusters)
+}
+
+func clusterKey(name string) string {
+	return "cluster/" + name
+}
+
+func clusterSetKey(name string) string {
+	return "clusterset/" + name
+}
+
+func policyClusters(obj client.Object) []string {
+	policy, ok := obj.(*v1alpha1.MultiClusterResourceSchedulePolicy)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	switch policy.Spec.ClusterSource {
+	case v1alpha1.ClusterSourceTypeAssign:
+		for _, item := range policy.Spec.Policy {
+			keys = append(keys, clusterKey(item.Name))
+		}
+	case v1alpha1.ClusterSourceTypeClusterset:
+		keys = append(keys, clusterSetKey(policy.Spec.Clusterset))
+	}
+	return keys
+}
+
+func clusterSetClusters(obj client.Object) []string {
+	clusterSet, ok := obj.(*v1alpha1.ClusterSet)
+	if !ok {
+		return nil
+	}
+	if len(clusterSet.Spec.Clusters) > 0 {
+		keys := make([]string, 0, len(clusterSet.Spec.Clusters))
+		for _, c := range clusterSet.Spec.Clusters {
+			keys = append(keys, c.Name)
+		}
+		return keys
+	}
+	if len(clusterSet.Spec.Selector.Labels) > 0 {
+		return []string{selectorKey}
+	}
+	return nil
+}
+
+// clusterSetsOfCluster are the ClusterSets which contain cluster by name or by selector
+func (m *Monitor) clusterSetsOfCluster(ctx context.Context, cluster *v1alpha1.Cluster) ([]v1alpha1.ClusterSet, error) {
+	byName := &v1alpha1.ClusterSetList{}
+	if err := m.client.List(ctx, byName, client.MatchingFields{clusterSetClusterIndex: cluster.Name}); err != nil {
+		return nil, err
+	}
+	bySelector := &v1alpha1.ClusterSetList{}
+	if err := m.client.List(ctx, bySelector, client.MatchingFields{clusterSetClusterIndex: selectorKey}); err != nil {
+		return nil, err
+	}
+	clusterSets := byName.Items
+	for _, clusterSet := range bySelector.Items {
+		if containsMap(cluster.GetLabels(), clusterSet.Spec.Selector.Labels) {
+			clusterSets = append(clusterSets, clusterSet)
+		}
+	}
+	return clusterSets, nil
+}
+
+// policiesOfCluster are the policies which assign cluster or schedule to the ClusterSets of cluster
+func (m *Monitor) policiesOfCluster(ctx context.Context, cluster *v1alpha1.Cluster) ([]v1alpha1.MultiClusterResourceSchedulePolicy, error) {
+	clusterSets, err := m.clusterSetsOfCluster(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{clusterKey(cluster.Name)}
+	for _, clusterSet := range clusterSets {
+		keys = append(keys, clusterSetKey(clusterSet.Name))
+	}
+
+	var policies []v1alpha1.MultiClusterResourceSchedulePolicy
+	seen := make(map[client.ObjectKey]bool)
+	for _, key := range keys {
+		policyList := &v1alpha1.MultiClusterResourceSchedulePolicyList{}
+		if err = m.client.List(ctx, policyList, client.MatchingFields{policyClusterIndex: key}); err != nil {
+			return nil, err
+		}
+		for _, policy := range policyList.Items {
+			if seen[client.ObjectKeyFromObject(&policy)] {
+				continue
+			}
+			seen[client.ObjectKeyFromObject(&policy)] = true
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+func containsMap(big, sub map[string]string) bool {
+	for k, v := range sub {
+		value, ok := big[k]
+		if !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
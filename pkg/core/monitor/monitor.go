@@ -6,130 +6,212 @@ import (
 	"sync"
 	"time"
 
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-
-	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
-
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
-	clusterController "harmonycloud.cn/stellaris/pkg/controller/cluster"
-	corecfg "harmonycloud.cn/stellaris/pkg/core/config"
-
 	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
 	multclusterclient "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterHealth "harmonycloud.cn/stellaris/pkg/common/cluster-health"
+	clusterController "harmonycloud.cn/stellaris/pkg/controller/cluster"
+	corecfg "harmonycloud.cn/stellaris/pkg/core/config"
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
 )
 
-var onec sync.Once
-var client *multclusterclient.Clientset
-var config *corecfg.Configuration
-
 var clusterMonitorLog = logf.Log.WithName("cluster_monitor")
 
-func StartCheckClusterStatus(mClient *multclusterclient.Clientset, cfg *corecfg.Configuration) {
-	onec.Do(func() {
-		client = mClient
-		config = cfg
-		checkClusterStatus()
-	})
+// Monitor changes the state of cluster when no heartbeat is received in time. Each cluster has a timer which is
+// reset by the status updates of heartbeat, the cluster is checked when its timer expires. It runs in leader only
+type Monitor struct {
+	cache      cache.Cache
+	client     client.Client
+	mClient    *multclusterclient.Clientset
+	thresholds clusterHealth.Thresholds
+	// resyncPeriod is how often the timers are reset from the cached clusters in case any update is missed
+	resyncPeriod time.Duration
+
+	queue  workqueue.RateLimitingInterface
+	lock   sync.Mutex
+	timers map[string]*time.Timer
 }
 
-func checkClusterStatus() {
-	ctx := context.Background()
-	for {
-		time.Sleep(config.ClusterStatusCheckPeriod)
-		clusterMonitorLog.Info("start check cluster status")
-		clusterList, err := client.MulticlusterV1alpha1().Clusters().List(ctx, metav1.ListOptions{})
-		if err != nil {
-			clusterMonitorLog.Error(err, "get cluster list failed")
-			continue
-		}
-
-		for _, cluster := range clusterList.Items {
-			if timeutils.NowTimeWithLoc().Sub(cluster.Status.LastReceiveHeartBeatTimestamp.Time) >= config.OnlineExpirationTime && cluster.Status.Status == v1alpha1.OnlineStatus {
-				err = policyReSchedule(ctx, &cluster)
-				if err != nil {
-					clusterMonitorLog.Error(err, "change policy reSchedule failed")
-					continue
-				}
-
-				clusterMonitorLog.Info(fmt.Sprintf("cluster(%s) is offline, last heartBeat time:%s, now time:%s", cluster.Name, cluster.Status.LastReceiveHeartBeatTimestamp.String(), metav1.Now().String()))
-				err = clusterController.OfflineCluster(ctx, client, &cluster)
-				if err != nil {
-					clusterMonitorLog.Error(err, fmt.Sprintf("change cluster(%s) status to offline failed", cluster.GetName()))
-					continue
-				}
-			}
-		}
+// NewMonitor registers the indexes of policies and ClusterSets, it must be called before manager is started
+func NewMonitor(mgr ctrl.Manager, mClient *multclusterclient.Clientset, cfg *corecfg.Configuration) (*Monitor, error) {
+	if err := setupIndexes(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return nil, err
 	}
+	return &Monitor{
+		cache:        mgr.GetCache(),
+		client:       mgr.GetClient(),
+		mClient:      mClient,
+		thresholds:   cfg.Thresholds(),
+		resyncPeriod: cfg.ClusterStatusCheckPeriod,
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "cluster_monitor"),
+		timers:       make(map[string]*time.Timer),
+	}, nil
 }
 
-func policyReSchedule(ctx context.Context, cluster *v1alpha1.Cluster) error {
-	policyList, err := client.MulticlusterV1alpha1().MultiClusterResourceSchedulePolicies(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+// NeedLeaderElection makes monitor run in leader only, the other replicas would race on the same transitions
+func (m *Monitor) NeedLeaderElection() bool {
+	return true
+}
+
+func (m *Monitor) Start(ctx context.Context) error {
+	defer m.queue.ShutDown()
+	defer m.stopTimers()
+
+	informer, err := m.cache.GetInformer(ctx, &v1alpha1.Cluster{})
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil
-		}
 		return err
 	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    m.onCluster,
+		UpdateFunc: func(_, obj interface{}) { m.onCluster(obj) },
+		DeleteFunc: m.onClusterDelete,
+	})
+	if !m.cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("wait for cluster cache to sync failed")
+	}
+	clusterMonitorLog.Info("start cluster monitor")
 
-	for _, policy := range policyList.Items {
-		if len(policy.Spec.FailoverPolicy) == 0 {
-			continue
-		}
-		if shouldReSchedule(ctx, &policy, cluster) {
-			policy.Spec.Reschedule = true
-			_, err = client.MulticlusterV1alpha1().MultiClusterResourceSchedulePolicies(policy.GetNamespace()).Update(ctx, &policy, metav1.UpdateOptions{})
-			if err != nil {
-				clusterMonitorLog.Error(err, fmt.Sprintf("update policy(%s:%s) reschedule failed", policy.GetNamespace(), policy.GetName()))
-				continue
-			}
-		}
+	go wait.UntilWithContext(ctx, m.worker, time.Second)
+	if m.resyncPeriod > 0 {
+		go wait.UntilWithContext(ctx, m.resync, m.resyncPeriod)
 	}
+	<-ctx.Done()
 	return nil
 }
 
-func shouldReSchedule(ctx context.Context, policy *v1alpha1.MultiClusterResourceSchedulePolicy, cluster *v1alpha1.Cluster) bool {
-	for _, item := range policy.Spec.Policy {
-		if policy.Spec.ClusterSource == v1alpha1.ClusterSourceTypeAssign {
-			if item.Name == cluster.GetName() {
-				return true
-			}
-		} else if policy.Spec.ClusterSource == v1alpha1.ClusterSourceTypeClusterset {
-			clusterSet, err := client.MulticlusterV1alpha1().ClusterSets().Get(ctx, policy.Spec.Clusterset, metav1.GetOptions{})
-			if err != nil {
-				clusterMonitorLog.Error(err, fmt.Sprintf("get clusterset(%s) failed", item.Name))
-				return false
-			}
-			if clusterSetContainsCluster(clusterSet, cluster) {
-				return true
-			}
-		}
+func (m *Monitor) onCluster(obj interface{}) {
+	if cluster, ok := obj.(*v1alpha1.Cluster); ok {
+		m.arm(cluster)
 	}
-	return false
 }
 
-func clusterSetContainsCluster(clusterSet *v1alpha1.ClusterSet, cluster *v1alpha1.Cluster) bool {
-	if len(clusterSet.Spec.Clusters) > 0 {
-		for _, c := range clusterSet.Spec.Clusters {
-			if c.Name == cluster.Name {
-				return true
-			}
-		}
-	} else if len(clusterSet.Spec.Selector.Labels) > 0 {
-		if containsMap(cluster.GetLabels(), clusterSet.Spec.Selector.Labels) {
-			return true
+func (m *Monitor) onClusterDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if cluster, ok := obj.(*v1alpha1.Cluster); ok {
+		m.disarm(cluster.Name)
+	}
+}
+
+// arm resets the timer of cluster to the time its state may change without heartbeat
+func (m *Monitor) arm(cluster *v1alpha1.Cluster) {
+	next, ok := m.thresholds.NextCheck(&cluster.Status)
+	if !ok {
+		m.disarm(cluster.Name)
+		return
+	}
+	name := cluster.Name
+	d := time.Until(next)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if timer, exist := m.timers[name]; exist {
+		timer.Reset(d)
+		return
+	}
+	m.timers[name] = time.AfterFunc(d, func() { m.queue.Add(name) })
+}
+
+func (m *Monitor) disarm(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if timer, exist := m.timers[name]; exist {
+		timer.Stop()
+		delete(m.timers, name)
+	}
+}
+
+func (m *Monitor) stopTimers() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for name, timer := range m.timers {
+		timer.Stop()
+		delete(m.timers, name)
+	}
+}
+
+func (m *Monitor) resync(ctx context.Context) {
+	clusterList := &v1alpha1.ClusterList{}
+	if err := m.client.List(ctx, clusterList); err != nil {
+		clusterMonitorLog.Error(err, "list cached clusters failed")
+		return
+	}
+	for i := range clusterList.Items {
+		m.arm(&clusterList.Items[i])
+	}
+}
+
+func (m *Monitor) worker(ctx context.Context) {
+	for m.processNext(ctx) {
+	}
+}
+
+func (m *Monitor) processNext(ctx context.Context) bool {
+	key, shutdown := m.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer m.queue.Done(key)
+
+	name := key.(string)
+	if err := m.checkCluster(ctx, name); err != nil {
+		clusterMonitorLog.Error(err, fmt.Sprintf("check cluster(%s) failed", name))
+		m.queue.AddRateLimited(key)
+		return true
+	}
+	m.queue.Forget(key)
+	return true
+}
+
+// checkCluster changes the state of cluster whose timer expires, the timer is armed again by the status update
+func (m *Monitor) checkCluster(ctx context.Context, name string) error {
+	cluster := &v1alpha1.Cluster{}
+	if err := m.client.Get(ctx, types.NamespacedName{Name: name}, cluster); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	transition := m.thresholds.OnCheck(&cluster.Status, timeutils.NowTimeWithLoc())
+	if transition == nil {
+		// a heartbeat came before the timer expired
+		m.arm(cluster)
+		return nil
+	}
+	// workloads are moved away only when cluster is offline, unknown cluster may come back soon
+	if transition.State == v1alpha1.OfflineStatus {
+		if err := m.policyReSchedule(ctx, cluster); err != nil {
+			return fmt.Errorf("change policy reSchedule failed: %s", err)
 		}
 	}
-	return false
+
+	clusterMonitorLog.Info(fmt.Sprintf("cluster(%s) is %s, last heartBeat time:%s, now time:%s", cluster.Name, transition.State, cluster.Status.LastReceiveHeartBeatTimestamp.String(), timeutils.NowTimeWithLoc().String()))
+	return clusterController.TransitCluster(ctx, m.mClient, cluster, transition)
 }
 
-func containsMap(big, sub map[string]string) bool {
-	for k, v := range sub {
-		value, ok := big[k]
-		if !ok || value != v {
-			return false
+// policyReSchedule reschedules the policies with failover which reference the offline cluster directly or by
+// ClusterSet
+func (m *Monitor) policyReSchedule(ctx context.Context, cluster *v1alpha1.Cluster) error {
+	policies, err := m.policiesOfCluster(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	for i := range policies {
+		policy := &policies[i]
+		if len(policy.Spec.FailoverPolicy) == 0 || policy.Spec.Reschedule {
+			continue
+		}
+		policy.Spec.Reschedule = true
+		if err = m.client.Update(ctx, policy); err != nil {
+			clusterMonitorLog.Error(err, fmt.Sprintf("update policy(%s:%s) reschedule failed", policy.GetNamespace(), policy.GetName()))
+			continue
 		}
 	}
-	return true
+	return nil
 }
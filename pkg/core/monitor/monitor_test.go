@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	clusterHealth "harmonycloud.cn/stellaris/pkg/common/cluster-health"
+)
+
+func TestIndexes(t *testing.T) {
+	assign := &v1alpha1.MultiClusterResourceSchedulePolicy{Spec: v1alpha1.MultiClusterResourceSchedulePolicySpec{
+		ClusterSource: v1alpha1.ClusterSourceTypeAssign,
+		Policy:        []v1alpha1.SchedulePolicy{{Name: "a"}, {Name: "b"}},
+	}}
+	if got := policyClusters(assign); !reflect.DeepEqual(got, []string{"cluster/a", "cluster/b"}) {
+		t.Errorf("assign policy keys = %v", got)
+	}
+	bySet := &v1alpha1.MultiClusterResourceSchedulePolicy{Spec: v1alpha1.MultiClusterResourceSchedulePolicySpec{
+		ClusterSource: v1alpha1.ClusterSourceTypeClusterset,
+		Clusterset:    "set",
+	}}
+	if got := policyClusters(bySet); !reflect.DeepEqual(got, []string{"clusterset/set"}) {
+		t.Errorf("clusterset policy keys = %v", got)
+	}
+
+	byName := &v1alpha1.ClusterSet{Spec: v1alpha1.ClusterSetSpec{Clusters: []v1alpha1.ClusterSetTarget{{Name: "a"}}}}
+	if got := clusterSetClusters(byName); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("ClusterSet keys = %v", got)
+	}
+	bySelector := &v1alpha1.ClusterSet{Spec: v1alpha1.ClusterSetSpec{Selector: v1alpha1.ClusterSetSelector{Labels: map[string]string{"env": "prod"}}}}
+	if got := clusterSetClusters(bySelector); !reflect.DeepEqual(got, []string{selectorKey}) {
+		t.Errorf("ClusterSet keys = %v", got)
+	}
+}
+
+func TestArm(t *testing.T) {
+	m := &Monitor{
+		thresholds: clusterHealth.Thresholds{UnknownAfter: time.Minute, OfflineAfter: 3 * time.Minute},
+		queue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		timers:     make(map[string]*time.Timer),
+	}
+	defer m.queue.ShutDown()
+	defer m.stopTimers()
+
+	late := &v1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "late"},
+		Status:     v1alpha1.ClusterStatus{Status: v1alpha1.OnlineStatus, LastReceiveHeartBeatTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+	}
+	m.arm(late)
+	recent := late.DeepCopy()
+	recent.Name = "recent"
+	recent.Status.LastReceiveHeartBeatTimestamp = metav1.Now()
+	m.arm(recent)
+	offline := late.DeepCopy()
+	offline.Name = "offline"
+	offline.Status.Status = v1alpha1.OfflineStatus
+	m.arm(offline)
+
+	key, _ := m.queue.Get()
+	if key != "late" {
+		t.Errorf("expired cluster = %v, want late", key)
+	}
+	m.queue.Done(key)
+	if _, exist := m.timers["offline"]; exist {
+		t.Errorf("offline cluster should not have timer")
+	}
+
+	// heartbeat resets the timer
+	late.Status.LastReceiveHeartBeatTimestamp = metav1.Now()
+	m.arm(late)
+	time.Sleep(50 * time.Millisecond)
+	if m.queue.Len() != 0 {
+		t.Errorf("queue length = %d, no cluster should expire", m.queue.Len())
+	}
+}
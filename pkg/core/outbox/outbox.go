@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/model"
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var outboxLog = logf.Log.WithName("core_outbox")
+
+const (
+	// LabelKey marks the Secrets which store outbox entries in cluster namespace
+	LabelKey = "multicluster.harmonycloud.cn/outbox"
+
+	entryNamePrefix    = "outbox-"
+	operationKey       = "operation"
+	sequenceKey        = "sequence"
+	clusterResourceKey = "clusterResource"
+
+	// maxEntrySize leaves room for the metadata of Secret under the size limit of object
+	maxEntrySize = corev1.MaxSecretSize - 16*1024
+)
+
+// Entry is the latest change of one ClusterResource which is not delivered to proxy
+type Entry struct {
+	Operation       model.ServiceResponseType
+	Sequence        int64
+	ClusterResource *v1alpha1.ClusterResource
+}
+
+// Outbox persists ClusterResource changes for offline proxies, every entry is stored in a Secret of cluster
+// namespace because ClusterResource may embed Secrets. Changes of the same ClusterResource are coalesced into
+// one entry. Nothing is cached, the entries may be written by any replica of core
+type Outbox struct {
+	kubeClient kubernetes.Interface
+}
+
+func New(kubeClient kubernetes.Interface) *Outbox {
+	return &Outbox{kubeClient: kubeClient}
+}
+
+// Enqueue stores the change of ClusterResource, it replaces the pending change of the same ClusterResource
+func (o *Outbox) Enqueue(ctx context.Context, clusterName string, operation model.ServiceResponseType, clusterResource *v1alpha1.ClusterResource) error {
+	data, err := json.Marshal(clusterResource)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxEntrySize {
+		return fmt.Errorf("clusterResource(%s) is %d bytes, larger than %d bytes of outbox entry", clusterResource.Name, len(data), maxEntrySize)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      entryName(clusterResource.Name),
+			Namespace: managerCommon.ClusterNamespace(clusterName),
+			Labels:    map[string]string{LabelKey: "true"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			operationKey:       []byte(operation.String()),
+			sequenceKey:        []byte(strconv.FormatInt(timeutils.NowTimeWithLoc().UnixNano(), 10)),
+			clusterResourceKey: data,
+		},
+	}
+	secrets := o.kubeClient.CoreV1().Secrets(secret.Namespace)
+	if _, err = secrets.Create(ctx, secret, metav1.CreateOptions{}); apierrors.IsAlreadyExists(err) {
+		var exist *corev1.Secret
+		exist, err = secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		exist.Data = secret.Data
+		_, err = secrets.Update(ctx, exist, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	outboxLog.Info(fmt.Sprintf("queue %s of clusterResource(%s) for cluster(%s)", operation, clusterResource.Name, clusterName))
+	return nil
+}
+
+// Remove drops the pending change of ClusterResource, false is returned when there is no pending change
+func (o *Outbox) Remove(ctx context.Context, clusterName, clusterResourceName string) (bool, error) {
+	err := o.kubeClient.CoreV1().Secrets(managerCommon.ClusterNamespace(clusterName)).Delete(ctx, entryName(clusterResourceName), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List returns the pending changes of cluster in the order they are queued
+func (o *Outbox) List(ctx context.Context, clusterName string) ([]*Entry, error) {
+	secrets, err := o.list(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Entry
+	for i := range secrets.Items {
+		entry, err := toEntry(&secrets.Items[i])
+		if err != nil {
+			outboxLog.Error(err, fmt.Sprintf("skip invalid outbox entry(%s:%s)", secrets.Items[i].Namespace, secrets.Items[i].Name))
+			continue
+		}
+		result = append(result, entry)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Sequence < result[j].Sequence
+	})
+	return result, nil
+}
+
+// Depth returns the number of pending changes of cluster
+func (o *Outbox) Depth(ctx context.Context, clusterName string) (int, error) {
+	secrets, err := o.list(ctx, clusterName)
+	if err != nil {
+		return 0, err
+	}
+	return len(secrets.Items), nil
+}
+
+func (o *Outbox) list(ctx context.Context, clusterName string) (*corev1.SecretList, error) {
+	return o.kubeClient.CoreV1().Secrets(managerCommon.ClusterNamespace(clusterName)).List(ctx, metav1.ListOptions{LabelSelector: LabelKey + "=true"})
+}
+
+func toEntry(secret *corev1.Secret) (*Entry, error) {
+	sequence, err := strconv.ParseInt(string(secret.Data[sequenceKey]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	clusterResource := &v1alpha1.ClusterResource{}
+	if err = json.Unmarshal(secret.Data[clusterResourceKey], clusterResource); err != nil {
+		return nil, err
+	}
+	return &Entry{
+		Operation:       model.ServiceResponseType(secret.Data[operationKey]),
+		Sequence:        sequence,
+		ClusterResource: clusterResource,
+	}, nil
+}
+
+// entryName is stable for one ClusterResource, name is hashed because the prefix may exceed the name length limit
+func entryName(clusterResourceName string) string {
+	sum := sha256.Sum256([]byte(clusterResourceName))
+	return entryNamePrefix + hex.EncodeToString(sum[:])[:16]
+}
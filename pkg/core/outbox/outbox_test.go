@@ -0,0 +1,67 @@
+package outbox
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newClusterResource(name string) *v1alpha1.ClusterResource {
+	clusterResource := &v1alpha1.ClusterResource{}
+	clusterResource.Name = name
+	return clusterResource
+}
+
+func TestOutboxCoalesceAndOrder(t *testing.T) {
+	ctx := context.TODO()
+	o := New(fake.NewSimpleClientset())
+
+	for _, item := range []struct {
+		name      string
+		operation model.ServiceResponseType
+	}{
+		{"a", model.ResourceUpdateOrCreate},
+		{"b", model.ResourceUpdateOrCreate},
+		{"a", model.ResourceDelete},
+	} {
+		if err := o.Enqueue(ctx, "cluster-a", item.operation, newClusterResource(item.name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if depth, _ := o.Depth(ctx, "cluster-a"); depth != 2 {
+		t.Fatalf("changes of the same ClusterResource should be coalesced, expect depth 2, but got %d", depth)
+	}
+	entries, err := o.List(ctx, "cluster-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ClusterResource.Name != "b" || entries[1].ClusterResource.Name != "a" ||
+		entries[1].Operation != model.ResourceDelete {
+		t.Fatalf("entries should be ordered by the latest change, but got %v", entries)
+	}
+
+	// another replica removes the entry, nothing is cached by outbox
+	other := New(o.kubeClient)
+	if removed, err := other.Remove(ctx, "cluster-a", "b"); err != nil || !removed {
+		t.Fatalf("expect b removed, but got %v %v", removed, err)
+	}
+	if removed, err := o.Remove(ctx, "cluster-a", "b"); err != nil || removed {
+		t.Fatalf("expect b already removed, but got %v %v", removed, err)
+	}
+	if depth, _ := o.Depth(ctx, "cluster-a"); depth != 1 {
+		t.Fatalf("expect depth 1 after remove, but got %d", depth)
+	}
+}
+
+func TestOutboxEntryTooLarge(t *testing.T) {
+	clusterResource := newClusterResource("large")
+	clusterResource.Spec.Resource = &runtime.RawExtension{Raw: []byte(`"` + strings.Repeat("x", maxEntrySize) + `"`)}
+	if err := New(fake.NewSimpleClientset()).Enqueue(context.TODO(), "cluster-a", model.ResourceUpdateOrCreate, clusterResource); err == nil {
+		t.Fatal("expect error for entry larger than the size limit")
+	}
+}
@@ -0,0 +1,243 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/config/forward"
+	timeutils "harmonycloud.cn/stellaris/pkg/utils/time"
+)
+
+var routeLog = logf.Log.WithName("core_route")
+
+const (
+	// leaseNamePrefix is the prefix of the Lease which records the replica holding the proxy stream
+	leaseNamePrefix = "stellaris-stream-"
+	// ClusterLabel marks the cluster of stream Lease
+	ClusterLabel = "multicluster.harmonycloud.cn/stream-cluster"
+
+	forwardTimeout = 10 * time.Second
+	// originExpiration is how long the origin of a forwarded response is kept for its ack
+	originExpiration = 30 * time.Minute
+)
+
+// ErrNoOwner means no live replica holds the stream of cluster
+var ErrNoOwner = errors.New("no core replica holds the proxy stream")
+
+type origin struct {
+	address string
+	time    time.Time
+}
+
+// Router records which core replica holds the stream of a proxy in a Lease per cluster,
+// and forwards messages to the owner replica over the internal grpc endpoint
+type Router struct {
+	kubeClient    kubernetes.Interface
+	namespace     string
+	identity      string
+	leaseDuration time.Duration
+	dialOptions   []grpc.DialOption
+
+	lock  sync.Mutex
+	conns map[string]*grpc.ClientConn
+	// origins maps the request id of forwarded response to the replica which sent it
+	origins map[string]origin
+}
+
+// New returns a router, identity is the internal address of this replica which other replicas dial
+func New(kubeClient kubernetes.Interface, namespace, identity string, leaseDuration time.Duration, dialOptions ...grpc.DialOption) *Router {
+	return &Router{
+		kubeClient:    kubeClient,
+		namespace:     namespace,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+		dialOptions:   dialOptions,
+		conns:         make(map[string]*grpc.ClientConn),
+		origins:       make(map[string]origin),
+	}
+}
+
+func (r *Router) Identity() string {
+	return r.identity
+}
+
+func leaseName(clusterName string) string {
+	return leaseNamePrefix + clusterName
+}
+
+// Claim records this replica as the holder of cluster stream, it also renews the Lease
+func (r *Router) Claim(ctx context.Context, clusterName string) error {
+	now := metav1.NewMicroTime(timeutils.NowTimeWithLoc())
+	durationSeconds := int32(r.leaseDuration.Seconds())
+	leases := r.kubeClient.CoordinationV1().Leases(r.namespace)
+
+	lease, err := leases.Get(ctx, leaseName(clusterName), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseName(clusterName),
+				Namespace: r.namespace,
+				Labels:    map[string]string{ClusterLabel: clusterName},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &r.identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != r.identity {
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.HolderIdentity = &r.identity
+		lease.Spec.AcquireTime = &now
+		lease.Spec.LeaseTransitions = &transitions
+		routeLog.Info(fmt.Sprintf("replica(%s) takes over the stream of cluster(%s)", r.identity, clusterName))
+	}
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// Release deletes the Lease when this replica still holds the stream of cluster
+func (r *Router) Release(ctx context.Context, clusterName string) error {
+	leases := r.kubeClient.CoordinationV1().Leases(r.namespace)
+	lease, err := leases.Get(ctx, leaseName(clusterName), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != r.identity {
+		return nil
+	}
+	err = leases.Delete(ctx, lease.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+	})
+	if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+// Owner returns the address of the replica which holds the stream of cluster
+func (r *Router) Owner(ctx context.Context, clusterName string) (string, error) {
+	lease, err := r.kubeClient.CoordinationV1().Leases(r.namespace).Get(ctx, leaseName(clusterName), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", ErrNoOwner
+		}
+		return "", err
+	}
+	if !leaseValid(lease, timeutils.NowTimeWithLoc()) {
+		return "", ErrNoOwner
+	}
+	return *lease.Spec.HolderIdentity, nil
+}
+
+func leaseValid(lease *coordinationv1.Lease, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || len(*spec.HolderIdentity) == 0 || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return false
+	}
+	return spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second).After(now)
+}
+
+// SendToProxy forwards the response to the replica at address which holds the proxy stream
+func (r *Router) SendToProxy(ctx context.Context, address string, res *config.Response) error {
+	client, err := r.client(address)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, forwardTimeout)
+	defer cancel()
+	_, err = client.SendToProxy(ctx, &forward.ProxyResponse{
+		Origin:      r.identity,
+		Type:        res.Type,
+		ClusterName: res.ClusterName,
+		Body:        res.Body,
+		RequestId:   res.RequestId,
+	})
+	return err
+}
+
+// HandleRequest forwards the proxy request to the replica at address
+func (r *Router) HandleRequest(ctx context.Context, address string, req *config.Request) error {
+	client, err := r.client(address)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, forwardTimeout)
+	defer cancel()
+	_, err = client.HandleRequest(ctx, &forward.ProxyRequest{
+		Type:        req.Type,
+		ClusterName: req.ClusterName,
+		Body:        req.Body,
+		RequestId:   req.RequestId,
+	})
+	return err
+}
+
+func (r *Router) client(address string) (forward.ForwardClient, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conn, ok := r.conns[address]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(address, r.dialOptions...)
+		if err != nil {
+			return nil, err
+		}
+		r.conns[address] = conn
+	}
+	return forward.NewForwardClient(conn), nil
+}
+
+// RememberOrigin keeps the replica which sent the response, so that the ack of it can be carried back
+func (r *Router) RememberOrigin(requestID, address string) {
+	if len(requestID) == 0 || len(address) == 0 || address == r.identity {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := timeutils.NowTimeWithLoc()
+	for id, o := range r.origins {
+		if now.Sub(o.time) > originExpiration {
+			delete(r.origins, id)
+		}
+	}
+	r.origins[requestID] = origin{address: address, time: now}
+}
+
+// TakeOrigin returns and forgets the replica which sent the response with requestID
+func (r *Router) TakeOrigin(requestID string) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	o, ok := r.origins[requestID]
+	if ok {
+		delete(r.origins, requestID)
+	}
+	return o.address, ok
+}
@@ -0,0 +1,58 @@
+package route
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStreamLeaseOwner(t *testing.T) {
+	ctx := context.TODO()
+	kubeClient := fake.NewSimpleClientset()
+	a := New(kubeClient, "stellaris-system", "10.0.0.1:8081", time.Minute)
+	b := New(kubeClient, "stellaris-system", "10.0.0.2:8081", time.Minute)
+
+	if _, err := a.Owner(ctx, "cluster-a"); err != ErrNoOwner {
+		t.Fatalf("expect no owner before stream is claimed, but got %v", err)
+	}
+	if err := a.Claim(ctx, "cluster-a"); err != nil {
+		t.Fatal(err)
+	}
+	if owner, err := b.Owner(ctx, "cluster-a"); err != nil || owner != a.Identity() {
+		t.Fatalf("expect owner %s, but got %s, %v", a.Identity(), owner, err)
+	}
+
+	// proxy reconnects to replica b, the stale replica a must not release the lease of b
+	if err := b.Claim(ctx, "cluster-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Release(ctx, "cluster-a"); err != nil {
+		t.Fatal(err)
+	}
+	if owner, err := a.Owner(ctx, "cluster-a"); err != nil || owner != b.Identity() {
+		t.Fatalf("expect owner %s, but got %s, %v", b.Identity(), owner, err)
+	}
+	if err := b.Release(ctx, "cluster-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Owner(ctx, "cluster-a"); err != ErrNoOwner {
+		t.Fatalf("expect no owner after stream is released, but got %v", err)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	r := New(fake.NewSimpleClientset(), "stellaris-system", "10.0.0.1:8081", time.Minute)
+	r.RememberOrigin("1", "10.0.0.2:8081")
+	r.RememberOrigin("2", r.Identity())
+	if origin, ok := r.TakeOrigin("1"); !ok || origin != "10.0.0.2:8081" {
+		t.Fatalf("expect origin of request 1, but got %s", origin)
+	}
+	if _, ok := r.TakeOrigin("1"); ok {
+		t.Fatal("origin should be forgotten after taken")
+	}
+	if _, ok := r.TakeOrigin("2"); ok {
+		t.Fatal("origin of this replica should not be remembered")
+	}
+}
@@ -0,0 +1,34 @@
+package stream
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type streamInfo struct {
+	ClusterName string    `json:"clusterName"`
+	Status      string    `json:"status"`
+	Expire      time.Time `json:"expire"`
+	Expired     bool      `json:"expired"`
+}
+
+// DebugHandler lists the streams in table as json
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streams := List()
+		infos := make([]streamInfo, 0, len(streams))
+		for i := range streams {
+			infos = append(infos, streamInfo{
+				ClusterName: streams[i].ClusterName,
+				Status:      streams[i].Status,
+				Expire:      streams[i].Expire,
+				Expired:     streams[i].isExpire(),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(infos); err != nil {
+			tableLog.Error(err, "write stream table failed")
+		}
+	})
+}
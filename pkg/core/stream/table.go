@@ -2,6 +2,7 @@ package stream
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -48,6 +49,41 @@ func Insert(clusterName string, stream *Stream) {
 	tableLog.Info(fmt.Sprintf("insert proxy(%s) stream success", clusterName))
 }
 
+// Replace always puts the stream into table, a new registered stream supersedes the old one of the cluster
+func Replace(clusterName string, stream *Stream) {
+	lock.Lock()
+	defer lock.Unlock()
+	table[clusterName] = stream
+	tableLog.Info(fmt.Sprintf("replace proxy(%s) stream success", clusterName))
+}
+
+// Remove deletes the stream of cluster, it does nothing when the cluster has registered a newer stream
+func Remove(clusterName string, stream config.Channel_EstablishServer) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	existStream, ok := table[clusterName]
+	if !ok || existStream.Stream != stream {
+		return false
+	}
+	delete(table, clusterName)
+	tableLog.Info(fmt.Sprintf("remove proxy(%s) stream success", clusterName))
+	return true
+}
+
+// List returns a copy of all streams in table
+func List() []Stream {
+	lock.RLock()
+	defer lock.RUnlock()
+	streams := make([]Stream, 0, len(table))
+	for _, s := range table {
+		streams = append(streams, *s)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].ClusterName < streams[j].ClusterName
+	})
+	return streams
+}
+
 func FindStream(clusterName string) *Stream {
 	lock.RLock()
 	defer lock.RUnlock()
@@ -0,0 +1,31 @@
+package stream
+
+import (
+	"testing"
+
+	"harmonycloud.cn/stellaris/config"
+)
+
+type fakeStream struct {
+	config.Channel_EstablishServer
+	id int
+}
+
+func TestRemoveKeepsNewerStream(t *testing.T) {
+	oldStream, newStream := &fakeStream{id: 1}, &fakeStream{id: 2}
+	Replace("cluster-a", &Stream{ClusterName: "cluster-a", Stream: oldStream, Status: OK})
+	Replace("cluster-a", &Stream{ClusterName: "cluster-a", Stream: newStream, Status: OK})
+
+	if Remove("cluster-a", oldStream) {
+		t.Fatal("old stream should not remove the newer registered stream")
+	}
+	if streams := List(); len(streams) != 1 || streams[0].Stream != newStream {
+		t.Fatalf("expect the newer stream in table, but got %v", streams)
+	}
+	if !Remove("cluster-a", newStream) {
+		t.Fatal("current stream should be removed")
+	}
+	if FindStream("cluster-a") != nil {
+		t.Fatal("stream should not be found after removed")
+	}
+}
@@ -0,0 +1,279 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	table "harmonycloud.cn/stellaris/pkg/core/stream"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+const (
+	// LogPathPrefix is the prefix of log path, the full path is
+	// /logs/namespaces/{namespace}/multiclusterresources/{name}/clusters/{cluster}
+	LogPathPrefix = "/logs/"
+	// logWindow is the number of chunks proxy can send before the caller reads them, it keeps the send queue
+	// of stream available for heartbeats
+	logWindow = 16
+)
+
+// ParseLogPath splits the log path into the MultiClusterResource and the cluster name
+func ParseLogPath(path string) (namespace, name, clusterName string, err error) {
+	parts := strings.Split(strings.TrimPrefix(path, LogPathPrefix), "/")
+	if !strings.HasPrefix(path, LogPathPrefix) || len(parts) != 6 || parts[0] != "namespaces" || parts[2] != "multiclusterresources" || parts[4] != "clusters" ||
+		len(parts[1]) == 0 || len(parts[3]) == 0 || len(parts[5]) == 0 {
+		return "", "", "", fmt.Errorf("path %s should be %snamespaces/{namespace}/multiclusterresources/{name}/clusters/{cluster}", path, LogPathPrefix)
+	}
+	return parts[1], parts[3], parts[5], nil
+}
+
+// DeliverLog passes the log chunk from proxy to the waiting caller
+func DeliverLog(chunk *model.LogResponse) error {
+	return Deliver(&model.TunnelResponse{
+		StreamID: chunk.StreamID,
+		Seq:      chunk.Seq,
+		Body:     chunk.Data,
+		Done:     chunk.Done,
+		Error:    chunk.Error,
+	})
+}
+
+// serveLogs streams container logs of the pods which belong to the propagated workload in member cluster
+func (s *Server) serveLogs(w http.ResponseWriter, r *http.Request) {
+	namespace, name, clusterName, err := ParseLogPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	user, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err = s.review(r.Context(), user, &authorizationv1.ResourceAttributes{
+		Group:       v1alpha1.SchemeGroupVersion.Group,
+		Resource:    "multiclusterresources",
+		Subresource: "log",
+		Namespace:   namespace,
+		Name:        name,
+		Verb:        "get",
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	stream := table.FindStream(clusterName)
+	if stream == nil {
+		s.forward(w, r, clusterName)
+		return
+	}
+	request, err := s.logRequest(r.Context(), namespace, name, clusterName)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if apierrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if err = setLogOptions(request, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sess := register(request.StreamID)
+	defer unregister(request.StreamID)
+	if err = sendLog(stream.Stream, clusterName, request); err != nil {
+		http.Error(w, fmt.Sprintf("send log request to proxy(%s) failed: %s", clusterName, err), http.StatusBadGateway)
+		return
+	}
+	tunnelLog.Info(fmt.Sprintf("stream logs of multiClusterResource(%s:%s) in cluster(%s) for %s", namespace, name, clusterName, user.Username))
+	writeLogs(r.Context(), w, stream.Stream, clusterName, request.StreamID, sess)
+}
+
+// logRequest finds the workload of the MultiClusterResource in the ClusterResources of the cluster
+func (s *Server) logRequest(ctx context.Context, namespace, name, clusterName string) (*model.LogRequest, error) {
+	if _, err := s.MultiClusterClient.MulticlusterV1alpha1().MultiClusterResources(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(labels.Set{managerCommon.MultiClusterResourceLabelName: name})
+	clusterResourceList, err := s.MultiClusterClient.MulticlusterV1alpha1().ClusterResources(managerCommon.ClusterNamespace(clusterName)).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, clusterResource := range clusterResourceList.Items {
+		if clusterResource.Spec.Resource == nil {
+			continue
+		}
+		request, err := logTarget(clusterResource.Spec.Resource.Raw)
+		if err != nil {
+			tunnelLog.Info(fmt.Sprintf("clusterResource(%s:%s) has no pods: %s", clusterResource.Namespace, clusterResource.Name, err))
+			continue
+		}
+		request.StreamID = uuid.NewString()
+		return request, nil
+	}
+	return nil, apierrors.NewNotFound(v1alpha1.Resource("clusterresources"), fmt.Sprintf("workload of %s/%s in cluster %s", namespace, name, clusterName))
+}
+
+// logTarget returns the pod itself, or the pod selector of the workload
+func logTarget(raw []byte) (*model.LogRequest, error) {
+	obj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw, &obj.Object); err != nil {
+		return nil, err
+	}
+	request := &model.LogRequest{Namespace: obj.GetNamespace()}
+	if len(request.Namespace) == 0 {
+		request.Namespace = metav1.NamespaceDefault
+	}
+	if obj.GetKind() == "Pod" {
+		request.Pod = obj.GetName()
+		return request, nil
+	}
+	selectorMap, found, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s has no pod selector", obj.GetKind())
+	}
+	labelSelector := &metav1.LabelSelector{}
+	b, err := json.Marshal(selectorMap)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, labelSelector); err != nil {
+		return nil, err
+	}
+	// selector of ReplicationController is a map of labels
+	if labelSelector.MatchLabels == nil && labelSelector.MatchExpressions == nil {
+		if err = json.Unmarshal(b, &labelSelector.MatchLabels); err != nil {
+			return nil, err
+		}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	if selector.Empty() {
+		return nil, errors.New("selector is empty")
+	}
+	request.LabelSelector = selector.String()
+	return request, nil
+}
+
+// setLogOptions reads the options of kubectl logs from query. The caller is authorized for the MultiClusterResource
+// only, so a pod is chosen only among the pods of workload, proxy rejects the pod which does not match the selector.
+// The pod of query is ignored when the workload is a pod
+func setLogOptions(request *model.LogRequest, r *http.Request) error {
+	query := r.URL.Query()
+	if pod := query.Get("pod"); len(pod) > 0 && len(request.LabelSelector) > 0 {
+		request.Pod = pod
+	}
+	request.Container = query.Get("container")
+	request.Follow = query.Get("follow") == "true"
+	request.Timestamps = query.Get("timestamps") == "true"
+	request.Previous = query.Get("previous") == "true"
+	for key, target := range map[string]**int64{"sinceSeconds": &request.SinceSeconds, "tailLines": &request.TailLines} {
+		value := query.Get(key)
+		if len(value) == 0 {
+			continue
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("%s should be a positive integer", key)
+		}
+		*target = &v
+	}
+	request.Credit = logWindow
+	return nil
+}
+
+// writeLogs writes the chunks to the caller, the credit is granted again after the caller reads half of the window
+func writeLogs(ctx context.Context, w http.ResponseWriter, stream config.Channel_EstablishServer, clusterName, streamID string, sess *session) {
+	flusher, _ := w.(http.Flusher)
+	cancel := func() {
+		if err := sendLog(stream, clusterName, &model.LogRequest{StreamID: streamID, Cancel: true}); err != nil {
+			tunnelLog.Error(err, fmt.Sprintf("cancel log stream %s of cluster(%s) failed", streamID, clusterName))
+		}
+	}
+	started := false
+	var consumed int64
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			return
+		case chunk, ok := <-sess.chunks:
+			if !ok {
+				tunnelLog.Error(ErrSlowCaller, fmt.Sprintf("log stream %s of cluster(%s) is broken", streamID, clusterName))
+				cancel()
+				return
+			}
+			if !started {
+				started = true
+				if len(chunk.Error) > 0 && len(chunk.Body) == 0 {
+					http.Error(w, chunk.Error, http.StatusBadGateway)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusOK)
+			}
+			if len(chunk.Body) > 0 {
+				if _, err := w.Write(chunk.Body); err != nil {
+					cancel()
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			if len(chunk.Error) > 0 {
+				tunnelLog.Error(errors.New(chunk.Error), fmt.Sprintf("log stream %s of cluster(%s) failed", streamID, clusterName))
+			}
+			if chunk.Done {
+				return
+			}
+			consumed++
+			if consumed < logWindow/2 {
+				continue
+			}
+			if err := sendLog(stream, clusterName, &model.LogRequest{StreamID: streamID, Credit: consumed}); err != nil {
+				tunnelLog.Error(err, fmt.Sprintf("grant credit to log stream %s of cluster(%s) failed", streamID, clusterName))
+				cancel()
+				return
+			}
+			consumed = 0
+		}
+	}
+}
+
+func sendLog(stream config.Channel_EstablishServer, clusterName string, request *model.LogRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return stream.Send(&config.Response{
+		Type:        model.Log.String(),
+		ClusterName: clusterName,
+		Body:        string(body),
+		RequestId:   request.StreamID,
+	})
+}
@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func TestParseLogPath(t *testing.T) {
+	namespace, name, clusterName, err := ParseLogPath("/logs/namespaces/default/multiclusterresources/nginx/clusters/member")
+	if err != nil || namespace != "default" || name != "nginx" || clusterName != "member" {
+		t.Fatalf("unexpected result %s %s %s %v", namespace, name, clusterName, err)
+	}
+	for _, path := range []string{"/logs/namespaces/default/multiclusterresources/nginx", "/logs/namespaces//multiclusterresources/nginx/clusters/member", "/clusters/member/proxy/api"} {
+		if _, _, _, err = ParseLogPath(path); err == nil {
+			t.Fatalf("path %s should be invalid", path)
+		}
+	}
+}
+
+func TestLogTarget(t *testing.T) {
+	cases := []struct {
+		raw      string
+		pod      string
+		selector string
+		invalid  bool
+	}{
+		{raw: `{"kind":"Pod","metadata":{"name":"nginx","namespace":"web"}}`, pod: "nginx"},
+		{raw: `{"kind":"Deployment","metadata":{"name":"nginx","namespace":"web"},"spec":{"selector":{"matchLabels":{"app":"nginx"}}}}`, selector: "app=nginx"},
+		{raw: `{"kind":"ReplicationController","metadata":{"name":"nginx","namespace":"web"},"spec":{"selector":{"app":"nginx"}}}`, selector: "app=nginx"},
+		{raw: `{"kind":"ConfigMap","metadata":{"name":"nginx","namespace":"web"}}`, invalid: true},
+	}
+	for _, c := range cases {
+		request, err := logTarget([]byte(c.raw))
+		if c.invalid {
+			if err == nil {
+				t.Fatalf("%s should have no pods", c.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", c.raw, err)
+		}
+		if request.Namespace != "web" || request.Pod != c.pod || request.LabelSelector != c.selector {
+			t.Fatalf("%s: unexpected target %+v", c.raw, request)
+		}
+	}
+}
+
+func TestSetLogOptionsPod(t *testing.T) {
+	r := httptest.NewRequest("GET", "/logs/namespaces/default/multiclusterresources/nginx/clusters/member?pod=foreign&tailLines=10", nil)
+
+	// the workload is a pod, the caller can not read another pod
+	request := &model.LogRequest{Namespace: "web", Pod: "nginx"}
+	if err := setLogOptions(request, r); err != nil {
+		t.Fatal(err)
+	}
+	if request.Pod != "nginx" || request.TailLines == nil || *request.TailLines != 10 {
+		t.Fatalf("pod of query should be ignored, got %+v", request)
+	}
+
+	// the pod is checked against the selector by proxy
+	request = &model.LogRequest{Namespace: "web", LabelSelector: "app=nginx"}
+	if err := setLogOptions(request, r); err != nil {
+		t.Fatal(err)
+	}
+	if request.Pod != "foreign" || request.LabelSelector != "app=nginx" {
+		t.Fatalf("pod of query should be sent with selector, got %+v", request)
+	}
+}
@@ -0,0 +1,368 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
+	"harmonycloud.cn/stellaris/pkg/core/route"
+	table "harmonycloud.cn/stellaris/pkg/core/stream"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var tunnelLog = logf.Log.WithName("core_tunnel")
+
+const (
+	// PathPrefix is the prefix of tunnel path, the full path is /clusters/{name}/proxy/{kubernetes api path}
+	PathPrefix = "/clusters/"
+	// MaxRequestBodySize keeps the request in one grpc message
+	MaxRequestBodySize = 3 << 20
+	// chunkBufferSize is the number of chunks which wait for the caller to read, the tunnel is broken when it is full
+	chunkBufferSize = 256
+	// forwardedHeader marks the request forwarded by another replica, so that it is not forwarded again
+	forwardedHeader = "X-Stellaris-Forwarded"
+)
+
+// ErrSlowCaller means the caller can not keep up with the response of member cluster
+var ErrSlowCaller = errors.New("caller is too slow to read the response")
+
+// session receives the chunks of one tunnelled request in order
+type session struct {
+	chunks  chan *model.TunnelResponse
+	next    int64
+	pending map[int64]*model.TunnelResponse
+	broken  bool
+}
+
+var (
+	lock     sync.Mutex
+	sessions = make(map[string]*session)
+)
+
+func register(streamID string) *session {
+	lock.Lock()
+	defer lock.Unlock()
+	s := &session{
+		chunks:  make(chan *model.TunnelResponse, chunkBufferSize),
+		pending: make(map[int64]*model.TunnelResponse),
+	}
+	sessions[streamID] = s
+	return s
+}
+
+func unregister(streamID string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(sessions, streamID)
+}
+
+// Deliver passes the chunk from proxy to the waiting caller, chunks may arrive out of order
+func Deliver(chunk *model.TunnelResponse) error {
+	lock.Lock()
+	defer lock.Unlock()
+	s, ok := sessions[chunk.StreamID]
+	// the chunks of a finished or cancelled request are dropped
+	if !ok || s.broken {
+		return nil
+	}
+	s.pending[chunk.Seq] = chunk
+	for {
+		item, ok := s.pending[s.next]
+		if !ok {
+			return nil
+		}
+		select {
+		case s.chunks <- item:
+		default:
+			s.broken = true
+			close(s.chunks)
+			return ErrSlowCaller
+		}
+		delete(s.pending, s.next)
+		s.next++
+	}
+}
+
+// Server serves Kubernetes API requests of member clusters, callers are authenticated and authorized
+// by the control plane, and requests are executed by proxy
+type Server struct {
+	KubeClient         kubernetes.Interface
+	MultiClusterClient versioned.Interface
+	// Router and PeerTransport forward the request to the replica holding the proxy stream, Router is nil in one replica
+	Router        *route.Router
+	PeerTransport http.RoundTripper
+	// PeerScheme and PeerPort are the scheme and port of tunnel endpoint of other replicas
+	PeerScheme string
+	PeerPort   int
+}
+
+// ParsePath splits the tunnel path into cluster name and the Kubernetes API path
+func ParsePath(path string) (clusterName, apiPath string, err error) {
+	if !strings.HasPrefix(path, PathPrefix) {
+		return "", "", fmt.Errorf("path %s is not a tunnel path", path)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(path, PathPrefix), "/", 3)
+	if len(parts) < 2 || len(parts[0]) == 0 || parts[1] != "proxy" {
+		return "", "", fmt.Errorf("path %s should be %s{name}/proxy/...", path, PathPrefix)
+	}
+	apiPath = "/"
+	if len(parts) == 3 {
+		apiPath += parts[2]
+	}
+	return parts[0], apiPath, nil
+}
+
+// Verb maps the http method to the verb checked in SubjectAccessReview
+func Verb(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, LogPathPrefix) {
+		s.serveLogs(w, r)
+		return
+	}
+	clusterName, apiPath, err := ParsePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if strings.EqualFold(r.Header.Get("Connection"), "upgrade") {
+		http.Error(w, "upgrade requests such as exec and port-forward are not supported by tunnel", http.StatusNotImplemented)
+		return
+	}
+	user, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err = s.authorize(r, user, clusterName); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	stream := table.FindStream(clusterName)
+	if stream == nil {
+		s.forward(w, r, clusterName)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxRequestBodySize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > MaxRequestBodySize {
+		http.Error(w, "request body is too large for tunnel", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	header := r.Header.Clone()
+	// the credential of control plane must not reach member cluster
+	header.Del("Authorization")
+	header.Del(forwardedHeader)
+	request := &model.TunnelRequest{
+		StreamID: uuid.NewString(),
+		Method:   r.Method,
+		Path:     apiPath,
+		RawQuery: r.URL.RawQuery,
+		Header:   header,
+		Body:     body,
+		User:     user.Username,
+		Groups:   user.Groups,
+	}
+	sess := register(request.StreamID)
+	defer unregister(request.StreamID)
+	if err = send(stream.Stream, clusterName, request); err != nil {
+		http.Error(w, fmt.Sprintf("send request to proxy(%s) failed: %s", clusterName, err), http.StatusBadGateway)
+		return
+	}
+	tunnelLog.Info(fmt.Sprintf("tunnel %s %s to cluster(%s) for %s", r.Method, apiPath, clusterName, user.Username))
+	s.writeResponse(r.Context(), w, stream.Stream, clusterName, request.StreamID, sess)
+}
+
+func (s *Server) writeResponse(ctx context.Context, w http.ResponseWriter, stream config.Channel_EstablishServer, clusterName, streamID string, sess *session) {
+	flusher, _ := w.(http.Flusher)
+	started := false
+	for {
+		select {
+		case <-ctx.Done():
+			// the caller goes away, such as a stopped watch
+			if err := send(stream, clusterName, &model.TunnelRequest{StreamID: streamID, Cancel: true}); err != nil {
+				tunnelLog.Error(err, fmt.Sprintf("cancel tunnel %s of cluster(%s) failed", streamID, clusterName))
+			}
+			return
+		case chunk, ok := <-sess.chunks:
+			if !ok {
+				tunnelLog.Error(ErrSlowCaller, fmt.Sprintf("tunnel %s of cluster(%s) is broken", streamID, clusterName))
+				_ = send(stream, clusterName, &model.TunnelRequest{StreamID: streamID, Cancel: true})
+				return
+			}
+			if !started {
+				started = true
+				if len(chunk.Error) > 0 && chunk.StatusCode == 0 {
+					http.Error(w, chunk.Error, http.StatusBadGateway)
+					return
+				}
+				for name, values := range chunk.Header {
+					for _, value := range values {
+						w.Header().Add(name, value)
+					}
+				}
+				w.WriteHeader(chunk.StatusCode)
+			}
+			if len(chunk.Body) > 0 {
+				if _, err := w.Write(chunk.Body); err != nil {
+					_ = send(stream, clusterName, &model.TunnelRequest{StreamID: streamID, Cancel: true})
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			if len(chunk.Error) > 0 {
+				tunnelLog.Error(errors.New(chunk.Error), fmt.Sprintf("tunnel %s of cluster(%s) failed", streamID, clusterName))
+			}
+			if chunk.Done {
+				return
+			}
+		}
+	}
+}
+
+func send(stream config.Channel_EstablishServer, clusterName string, request *model.TunnelRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return stream.Send(&config.Response{
+		Type:        model.Tunnel.String(),
+		ClusterName: clusterName,
+		Body:        string(body),
+		RequestId:   request.StreamID,
+	})
+}
+
+// forward proxies the request to the replica which holds the proxy stream
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, clusterName string) {
+	if s.Router == nil || len(r.Header.Get(forwardedHeader)) > 0 {
+		http.Error(w, fmt.Sprintf("proxy(%s) is not connected", clusterName), http.StatusServiceUnavailable)
+		return
+	}
+	owner, err := s.Router.Owner(r.Context(), clusterName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("find replica of proxy(%s) failed: %s", clusterName, err), http.StatusServiceUnavailable)
+		return
+	}
+	host, _, err := net.SplitHostPort(owner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	target := &url.URL{Scheme: s.PeerScheme, Host: net.JoinHostPort(host, fmt.Sprintf("%d", s.PeerPort))}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = s.PeerTransport
+	// watches are streamed as soon as the owner writes them
+	proxy.FlushInterval = -1
+	r.Header.Set(forwardedHeader, "true")
+	tunnelLog.Info(fmt.Sprintf("forward tunnel of cluster(%s) to replica(%s)", clusterName, target.Host))
+	proxy.ServeHTTP(w, r)
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", errors.New("bearer token is required")
+	}
+	return strings.TrimSpace(auth[len(prefix):]), nil
+}
+
+// authenticate reviews the bearer token with the apiserver of control plane
+func (s *Server) authenticate(r *http.Request) (*authenticationv1.UserInfo, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	review, err := s.KubeClient.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		if len(review.Status.Error) > 0 {
+			return nil, errors.New(review.Status.Error)
+		}
+		return nil, errors.New("token is not authenticated")
+	}
+	return &review.Status.User, nil
+}
+
+// authorize checks the caller can use the proxy subresource of Cluster with the verb of request
+func (s *Server) authorize(r *http.Request, user *authenticationv1.UserInfo, clusterName string) error {
+	return s.review(r.Context(), user, &authorizationv1.ResourceAttributes{
+		Group:       v1alpha1.SchemeGroupVersion.Group,
+		Resource:    "clusters",
+		Subresource: "proxy",
+		Name:        clusterName,
+		Verb:        Verb(r.Method),
+	})
+}
+
+// review checks the caller with SubjectAccessReview in control plane
+func (s *Server) review(ctx context.Context, user *authenticationv1.UserInfo, attributes *authorizationv1.ResourceAttributes) error {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	review, err := s.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	if !review.Status.Allowed {
+		resource := attributes.Resource + "/" + attributes.Subresource
+		if len(attributes.Namespace) > 0 {
+			return fmt.Errorf("user %s can not %s %s of %s/%s", user.Username, attributes.Verb, resource, attributes.Namespace, attributes.Name)
+		}
+		return fmt.Errorf("user %s can not %s %s of %s", user.Username, attributes.Verb, resource, attributes.Name)
+	}
+	return nil
+}
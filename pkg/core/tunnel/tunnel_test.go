@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"testing"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func TestParsePath(t *testing.T) {
+	clusterName, apiPath, err := ParsePath("/clusters/member/proxy/api/v1/namespaces")
+	if err != nil || clusterName != "member" || apiPath != "/api/v1/namespaces" {
+		t.Fatalf("unexpected result %s %s %v", clusterName, apiPath, err)
+	}
+	clusterName, apiPath, err = ParsePath("/clusters/member/proxy")
+	if err != nil || clusterName != "member" || apiPath != "/" {
+		t.Fatalf("unexpected result %s %s %v", clusterName, apiPath, err)
+	}
+	for _, path := range []string{"/api/v1", "/clusters/member", "/clusters//proxy/api", "/clusters/member/exec/api"} {
+		if _, _, err = ParsePath(path); err == nil {
+			t.Fatalf("path %s should be invalid", path)
+		}
+	}
+}
+
+func TestDeliverReorder(t *testing.T) {
+	s := register("reorder")
+	defer unregister("reorder")
+	for _, seq := range []int64{2, 0, 1} {
+		if err := Deliver(&model.TunnelResponse{StreamID: "reorder", Seq: seq}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for want := int64(0); want < 3; want++ {
+		if chunk := <-s.chunks; chunk.Seq != want {
+			t.Fatalf("expected seq %d, got %d", want, chunk.Seq)
+		}
+	}
+	if err := Deliver(&model.TunnelResponse{StreamID: "unknown"}); err != nil {
+		t.Fatalf("chunk of unknown stream should be dropped, got %v", err)
+	}
+}
+
+func TestDeliverSlowCaller(t *testing.T) {
+	register("slow")
+	defer unregister("slow")
+	var err error
+	for seq := int64(0); seq <= chunkBufferSize && err == nil; seq++ {
+		err = Deliver(&model.TunnelResponse{StreamID: "slow", Seq: seq})
+	}
+	if err != ErrSlowCaller {
+		t.Fatalf("expected ErrSlowCaller, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package model
+
+// AckRequest is sent by proxy after it handles a response which requires acknowledgement,
+// the request id of the ack request is the id of the handled response
+type AckRequest struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AggregateRequest carries all results of one rule in one policy, core removes the results which are not in it
+type AggregateRequest struct {
+	PolicyNamespace string            `json:"policyNamespace"`
+	PolicyName      string            `json:"policyName"`
+	RuleName        string            `json:"ruleName"`
+	Results         []AggregateResult `json:"results"`
+	// Error means the rule can not be evaluated at all, results are not reported then
+	Error string `json:"error,omitempty"`
+}
+
+type AggregateResult struct {
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace"`
+	Result    runtime.RawExtension `json:"result,omitempty"`
+	// Error is the message of rule evaluation failure
+	Error string `json:"error,omitempty"`
+}
+
+// SyncAggregateConfigResponse pushes the changed aggregate policies and rules to proxy
+type SyncAggregateConfigResponse struct {
+	Policies []*v1alpha1.MultiClusterResourceAggregatePolicy `json:"policies,omitempty"`
+	Rules    []*v1alpha1.MultiClusterResourceAggregateRule   `json:"rules,omitempty"`
+}
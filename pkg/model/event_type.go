@@ -0,0 +1,25 @@
+package model
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EventRequest is sent from proxy to core, it contains the Events of objects created from ClusterResources
+type EventRequest struct {
+	Events []ClusterEvent `json:"events,omitempty"`
+}
+
+// ClusterEvent is one Event in member cluster
+type ClusterEvent struct {
+	// ClusterResource is the name of ClusterResource which owns the involved object directly or through its owners
+	ClusterResource string `json:"clusterResource"`
+	// Kind, Namespace and Name are the involved object
+	Kind          string      `json:"kind,omitempty"`
+	Namespace     string      `json:"namespace,omitempty"`
+	Name          string      `json:"name,omitempty"`
+	Type          string      `json:"type,omitempty"`
+	Reason        string      `json:"reason,omitempty"`
+	Message       string      `json:"message,omitempty"`
+	Count         int32       `json:"count,omitempty"`
+	LastTimestamp metav1.Time `json:"lastTimestamp,omitempty"`
+}
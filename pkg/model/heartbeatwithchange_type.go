@@ -1,11 +1,32 @@
 package model
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
 
 type HeartbeatWithChangeRequest struct {
 	Healthy    bool        `json:"healthy"`
 	Addons     []Addon     `json:"addons"`
 	Conditions []Condition `json:"conditions"`
+	// Digest summarizes the ClusterResources in member cluster, core compares it to find silent desync
+	Digest *ResourceDigest `json:"digest,omitempty"`
+	// ResourceSummary is the capacity of member cluster, core keeps the last one when it is nil
+	ResourceSummary *v1alpha1.ClusterResourceSummary `json:"resourceSummary,omitempty"`
+}
+
+type ResourceDigest struct {
+	// Checksum covers all items, core only compares the items when it does not match
+	Checksum string       `json:"checksum"`
+	Items    []DigestItem `json:"items,omitempty"`
+}
+
+type DigestItem struct {
+	Name string `json:"name"`
+	// Namespace is the cluster namespace, ClusterResource keeps it in member cluster
+	Namespace string `json:"namespace,omitempty"`
+	SpecHash  string `json:"specHash"`
 }
 
 type Condition struct {
@@ -13,4 +34,6 @@ type Condition struct {
 	Message   string      `json:"message"`
 	Reason    string      `json:"reason"`
 	Type      string      `json:"type"`
+	// Status is True, False or Unknown, core takes the empty status as Unknown
+	Status metav1.ConditionStatus `json:"status,omitempty"`
 }
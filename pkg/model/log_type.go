@@ -0,0 +1,32 @@
+package model
+
+// LogRequest is sent from core to proxy, it starts streaming container logs, grants credit to a running stream or cancels it
+type LogRequest struct {
+	// StreamID identifies the log stream and all its chunks
+	StreamID string `json:"streamId"`
+	// Pod is the only pod to read, otherwise the pods in Namespace matched by LabelSelector are read
+	Namespace     string `json:"namespace,omitempty"`
+	Pod           string `json:"pod,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Container is required when the pod has more than one container
+	Container    string `json:"container,omitempty"`
+	Follow       bool   `json:"follow,omitempty"`
+	SinceSeconds *int64 `json:"sinceSeconds,omitempty"`
+	TailLines    *int64 `json:"tailLines,omitempty"`
+	Timestamps   bool   `json:"timestamps,omitempty"`
+	Previous     bool   `json:"previous,omitempty"`
+	// Credit is the number of chunks proxy can send more, proxy waits when it is used up
+	Credit int64 `json:"credit,omitempty"`
+	Cancel bool  `json:"cancel,omitempty"`
+}
+
+// LogResponse is sent from proxy to core, it contains complete lines of logs
+type LogResponse struct {
+	StreamID string `json:"streamId"`
+	// Seq orders the chunks, core handles requests of proxy concurrently
+	Seq  int64  `json:"seq"`
+	Data []byte `json:"data,omitempty"`
+	// Done is set in the last chunk
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
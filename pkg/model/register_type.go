@@ -2,16 +2,25 @@ package model
 
 type RegisterRequest struct {
 	Addons []Addon `json:"addons"`
+	// Token is the bootstrap token which proves the proxy may join as the cluster
+	Token string `json:"token,omitempty"`
+	// SessionToken is issued by core in the last register response, a valid one resumes the session without full resync
+	SessionToken string `json:"sessionToken,omitempty"`
 }
 
 type RegisterResponse struct {
 	ClusterResources                      []string `json:"clusterResources"`
 	MultiClusterResourceAggregatePolicies []string `json:"multiClusterResourceAggregatePolicies"`
 	MultiClusterResourceAggregateRules    []string `json:"multiClusterResourceAggregateRules"`
+	// SessionToken should be sent in the next register request to resume the session
+	SessionToken string `json:"sessionToken,omitempty"`
+	// Resumed means the session is resumed, resources are not sent and proxy should not resync
+	Resumed bool `json:"resumed,omitempty"`
 }
 
 func (r *RegisterResponse) IsEmpty() bool {
-	if len(r.ClusterResources) == 0 && len(r.MultiClusterResourceAggregateRules) == 0 && len(r.MultiClusterResourceAggregatePolicies) == 0 {
+	if len(r.ClusterResources) == 0 && len(r.MultiClusterResourceAggregateRules) == 0 && len(r.MultiClusterResourceAggregatePolicies) == 0 &&
+		len(r.SessionToken) == 0 && !r.Resumed {
 		return true
 	}
 	return false
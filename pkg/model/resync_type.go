@@ -0,0 +1,24 @@
+package model
+
+// ResyncRequest is sent by proxy after register, it is the inventory of ClusterResources in member cluster
+type ResyncRequest struct {
+	Inventory []ResyncItem `json:"inventory"`
+}
+
+type ResyncItem struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Generation is the generation of ClusterResource in the cluster which reports the item
+	Generation int64 `json:"generation"`
+	// ObservedReceiveGeneration is the generation of ClusterResource in member cluster which the status is observed at
+	ObservedReceiveGeneration int64 `json:"observedReceiveGeneration"`
+}
+
+// ResyncResponse is the authoritative set of ClusterResources of the cluster in control plane
+type ResyncResponse struct {
+	Resources []ResyncItem `json:"resources"`
+	// Orphans do not exist in control plane, proxy should delete them
+	Orphans []ResyncItem `json:"orphans,omitempty"`
+	// Stale are the ones whose status in control plane is older than the member cluster, proxy should report them again
+	Stale []ResyncItem `json:"stale,omitempty"`
+}
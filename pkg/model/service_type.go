@@ -7,6 +7,14 @@ const (
 	Heartbeat ServiceRequestType = "Heartbeat"
 	Resource  ServiceRequestType = "Resource"
 	Aggregate ServiceRequestType = "Aggregate"
+	Ack       ServiceRequestType = "Ack"
+	Resync    ServiceRequestType = "Resync"
+	// TunnelChunk carries the response of a tunnelled Kubernetes API request
+	TunnelChunk ServiceRequestType = "TunnelChunk"
+	// LogChunk carries container logs read by proxy
+	LogChunk ServiceRequestType = "LogChunk"
+	// Event carries the Events of objects created from ClusterResources
+	Event ServiceRequestType = "Event"
 )
 
 func (s ServiceRequestType) String() string {
@@ -16,16 +24,26 @@ func (s ServiceRequestType) String() string {
 type ServiceResponseType string
 
 const (
-	Unknown                     ServiceResponseType = "Unknown"
-	Error                       ServiceResponseType = "Error"
-	RegisterSuccess             ServiceResponseType = "RegisterSuccess"
-	RegisterFailed              ServiceResponseType = "RegisterFailed"
-	HeartbeatSuccess            ServiceResponseType = "HeartbeatSuccess"
-	HeartbeatFailed             ServiceResponseType = "HeartbeatFailed"
-	ResourceUpdateOrCreate      ServiceResponseType = "ResourceUpdateOrCreate"
-	ResourceDelete              ServiceResponseType = "ResourceDelete"
-	ResourceStatusUpdateSuccess ServiceResponseType = "ResourceStatusUpdateSuccess"
-	ResourceStatusUpdateFailed  ServiceResponseType = "ResourceStatusUpdateFailed"
+	Unknown                       ServiceResponseType = "Unknown"
+	Error                         ServiceResponseType = "Error"
+	RegisterSuccess               ServiceResponseType = "RegisterSuccess"
+	RegisterFailed                ServiceResponseType = "RegisterFailed"
+	HeartbeatSuccess              ServiceResponseType = "HeartbeatSuccess"
+	HeartbeatFailed               ServiceResponseType = "HeartbeatFailed"
+	ResourceUpdateOrCreate        ServiceResponseType = "ResourceUpdateOrCreate"
+	ResourceDelete                ServiceResponseType = "ResourceDelete"
+	ResourceStatusUpdateSuccess   ServiceResponseType = "ResourceStatusUpdateSuccess"
+	ResourceStatusUpdateFailed    ServiceResponseType = "ResourceStatusUpdateFailed"
+	AggregateSuccess              ServiceResponseType = "AggregateSuccess"
+	AggregateFailed               ServiceResponseType = "AggregateFailed"
+	AggregateConfigUpdateOrCreate ServiceResponseType = "AggregateConfigUpdateOrCreate"
+	AggregateConfigDelete         ServiceResponseType = "AggregateConfigDelete"
+	ResyncSuccess                 ServiceResponseType = "ResyncSuccess"
+	ResyncFailed                  ServiceResponseType = "ResyncFailed"
+	// Tunnel asks proxy to execute a Kubernetes API request in member cluster
+	Tunnel ServiceResponseType = "Tunnel"
+	// Log asks proxy to stream container logs, or grants credit to the stream
+	Log ServiceResponseType = "Log"
 )
 
 func (s ServiceResponseType) String() string {
@@ -0,0 +1,30 @@
+package model
+
+// TunnelRequest is sent from core to proxy, it is one Kubernetes API request to the member cluster
+type TunnelRequest struct {
+	// StreamID identifies the request and all chunks of its response
+	StreamID string              `json:"streamId"`
+	Method   string              `json:"method,omitempty"`
+	Path     string              `json:"path,omitempty"`
+	RawQuery string              `json:"rawQuery,omitempty"`
+	Header   map[string][]string `json:"header,omitempty"`
+	Body     []byte              `json:"body,omitempty"`
+	// User and Groups are the caller authenticated by core, proxy impersonates them when it is enabled
+	User   string   `json:"user,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	// Cancel stops the request, it is sent when the caller goes away during a watch
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// TunnelResponse is one chunk of the response, status and header are only set in the first chunk
+type TunnelResponse struct {
+	StreamID string `json:"streamId"`
+	// Seq orders the chunks, core handles requests of proxy concurrently
+	Seq        int64               `json:"seq"`
+	StatusCode int                 `json:"statusCode,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       []byte              `json:"body,omitempty"`
+	// Done is set in the last chunk
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,766 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"harmonycloud.cn/stellaris/config"
+	v2 "harmonycloud.cn/stellaris/config/v2"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The handlers of core and proxy work on v1 string messages, v2 typed messages are converted at the stream
+// boundary, so that both protocols can be served during migration.
+
+// RequestToV2 converts a v1 request to the v2 typed request
+func RequestToV2(req *config.Request) (*v2.Request, error) {
+	result := &v2.Request{ClusterName: req.ClusterName, RequestId: req.RequestId}
+	switch req.Type {
+	case model.Register.String():
+		data := &model.RegisterRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		addons, err := addonsToV2(data.Addons)
+		if err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Register{Register: &v2.RegisterRequest{Addons: addons, Token: data.Token, SessionToken: data.SessionToken}}
+	case model.Heartbeat.String():
+		data := &model.HeartbeatWithChangeRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		heartbeat, err := heartbeatToV2(data)
+		if err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Heartbeat{Heartbeat: heartbeat}
+	case model.Resource.String():
+		data := &model.ResourceRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		resource := &v2.ResourceRequest{}
+		for _, item := range data.ClusterResourceStatusList {
+			resource.ClusterResourceStatusList = append(resource.ClusterResourceStatusList, &v2.ClusterResourceStatus{
+				Name:                      item.Name,
+				Namespace:                 item.Namespace,
+				ObservedReceiveGeneration: item.Status.ObservedReceiveGeneration,
+				Phase:                     string(item.Status.Phase),
+				Message:                   item.Status.Message,
+			})
+		}
+		result.Payload = &v2.Request_Resource{Resource: resource}
+	case model.Aggregate.String():
+		data := &model.AggregateRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		aggregate := &v2.AggregateRequest{
+			PolicyNamespace: data.PolicyNamespace,
+			PolicyName:      data.PolicyName,
+			RuleName:        data.RuleName,
+			Error:           data.Error,
+		}
+		for _, item := range data.Results {
+			aggregate.Results = append(aggregate.Results, &v2.AggregateResult{
+				Name:      item.Name,
+				Namespace: item.Namespace,
+				Result:    item.Result.Raw,
+				Error:     item.Error,
+			})
+		}
+		result.Payload = &v2.Request_Aggregate{Aggregate: aggregate}
+	case model.Ack.String():
+		data := &model.AckRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Ack{Ack: &v2.AckRequest{Success: data.Success, Message: data.Message}}
+	case model.Resync.String():
+		data := &model.ResyncRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Resync{Resync: &v2.ResyncRequest{Inventory: resyncItemsToV2(data.Inventory)}}
+	case model.TunnelChunk.String():
+		data := &model.TunnelResponse{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Tunnel{Tunnel: &v2.TunnelResponse{
+			StreamId:   data.StreamID,
+			Seq:        data.Seq,
+			StatusCode: int32(data.StatusCode),
+			Header:     headerToV2(data.Header),
+			Body:       data.Body,
+			Done:       data.Done,
+			Error:      data.Error,
+		}}
+	case model.Event.String():
+		data := &model.EventRequest{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Event{Event: &v2.EventRequest{Events: eventsToV2(data.Events)}}
+	case model.LogChunk.String():
+		data := &model.LogResponse{}
+		if err := unmarshalBody(req.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Request_Log{Log: &v2.LogResponse{
+			StreamId: data.StreamID,
+			Seq:      data.Seq,
+			Data:     data.Data,
+			Done:     data.Done,
+			Error:    data.Error,
+		}}
+	default:
+		return nil, fmt.Errorf("request type %s is not supported by protocol v2", req.Type)
+	}
+	return result, nil
+}
+
+// RequestToV1 converts a v2 typed request to the v1 request
+func RequestToV1(req *v2.Request) (*config.Request, error) {
+	var typ model.ServiceRequestType
+	var body interface{}
+	switch payload := req.Payload.(type) {
+	case *v2.Request_Register:
+		addons, err := addonsToV1(payload.Register.Addons)
+		if err != nil {
+			return nil, err
+		}
+		typ = model.Register
+		body = &model.RegisterRequest{Addons: addons, Token: payload.Register.Token, SessionToken: payload.Register.SessionToken}
+	case *v2.Request_Heartbeat:
+		heartbeat, err := heartbeatToV1(payload.Heartbeat)
+		if err != nil {
+			return nil, err
+		}
+		typ = model.Heartbeat
+		body = heartbeat
+	case *v2.Request_Resource:
+		resource := &model.ResourceRequest{}
+		for _, item := range payload.Resource.ClusterResourceStatusList {
+			resource.ClusterResourceStatusList = append(resource.ClusterResourceStatusList, model.ClusterResourceStatus{
+				Name:      item.Name,
+				Namespace: item.Namespace,
+				Status: v1alpha1.ClusterResourceStatus{
+					ObservedReceiveGeneration: item.ObservedReceiveGeneration,
+					Phase:                     common.MultiClusterResourcePhase(item.Phase),
+					Message:                   item.Message,
+				},
+			})
+		}
+		typ = model.Resource
+		body = resource
+	case *v2.Request_Aggregate:
+		aggregate := &model.AggregateRequest{
+			PolicyNamespace: payload.Aggregate.PolicyNamespace,
+			PolicyName:      payload.Aggregate.PolicyName,
+			RuleName:        payload.Aggregate.RuleName,
+			Error:           payload.Aggregate.Error,
+		}
+		for _, item := range payload.Aggregate.Results {
+			aggregate.Results = append(aggregate.Results, model.AggregateResult{
+				Name:      item.Name,
+				Namespace: item.Namespace,
+				Result:    runtime.RawExtension{Raw: item.Result},
+				Error:     item.Error,
+			})
+		}
+		typ = model.Aggregate
+		body = aggregate
+	case *v2.Request_Ack:
+		typ = model.Ack
+		body = &model.AckRequest{Success: payload.Ack.Success, Message: payload.Ack.Message}
+	case *v2.Request_Resync:
+		typ = model.Resync
+		body = &model.ResyncRequest{Inventory: resyncItemsToV1(payload.Resync.Inventory)}
+	case *v2.Request_Tunnel:
+		typ = model.TunnelChunk
+		body = &model.TunnelResponse{
+			StreamID:   payload.Tunnel.StreamId,
+			Seq:        payload.Tunnel.Seq,
+			StatusCode: int(payload.Tunnel.StatusCode),
+			Header:     headerToV1(payload.Tunnel.Header),
+			Body:       payload.Tunnel.Body,
+			Done:       payload.Tunnel.Done,
+			Error:      payload.Tunnel.Error,
+		}
+	case *v2.Request_Event:
+		typ = model.Event
+		body = &model.EventRequest{Events: eventsToV1(payload.Event.Events)}
+	case *v2.Request_Log:
+		typ = model.LogChunk
+		body = &model.LogResponse{
+			StreamID: payload.Log.StreamId,
+			Seq:      payload.Log.Seq,
+			Data:     payload.Log.Data,
+			Done:     payload.Log.Done,
+			Error:    payload.Log.Error,
+		}
+	default:
+		return nil, errors.New("request payload is empty")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Request{Type: typ.String(), ClusterName: req.ClusterName, Body: string(data), RequestId: req.RequestId}, nil
+}
+
+// ResponseToV2 converts a v1 response to the v2 typed response
+func ResponseToV2(res *config.Response) (*v2.Response, error) {
+	result := &v2.Response{ClusterName: res.ClusterName, RequestId: res.RequestId}
+	switch res.Type {
+	case model.RegisterSuccess.String():
+		register := &v2.RegisterResponse{Success: true}
+		if len(res.Body) > 0 {
+			data := &model.RegisterResponse{}
+			if err := json.Unmarshal([]byte(res.Body), data); err != nil {
+				return nil, err
+			}
+			register.ClusterResources = stringsToBytes(data.ClusterResources)
+			register.MultiClusterResourceAggregatePolicies = stringsToBytes(data.MultiClusterResourceAggregatePolicies)
+			register.MultiClusterResourceAggregateRules = stringsToBytes(data.MultiClusterResourceAggregateRules)
+			register.SessionToken = data.SessionToken
+			register.Resumed = data.Resumed
+		}
+		result.Payload = &v2.Response_Register{Register: register}
+	case model.RegisterFailed.String():
+		result.Payload = &v2.Response_Register{Register: &v2.RegisterResponse{Message: res.Body}}
+	case model.HeartbeatSuccess.String():
+		heartbeatResponse := &v2.HeartbeatResponse{Success: true}
+		if len(res.Body) > 0 {
+			data := &model.HeartbeatWithChangeRequest{}
+			if err := json.Unmarshal([]byte(res.Body), data); err != nil {
+				return nil, err
+			}
+			heartbeat, err := heartbeatToV2(data)
+			if err != nil {
+				return nil, err
+			}
+			heartbeatResponse.Heartbeat = heartbeat
+		}
+		result.Payload = &v2.Response_Heartbeat{Heartbeat: heartbeatResponse}
+	case model.HeartbeatFailed.String():
+		result.Payload = &v2.Response_Heartbeat{Heartbeat: &v2.HeartbeatResponse{Message: res.Body}}
+	case model.ResourceStatusUpdateSuccess.String():
+		result.Payload = &v2.Response_Resource{Resource: &v2.ResourceResponse{Success: true, Message: res.Body}}
+	case model.ResourceStatusUpdateFailed.String():
+		result.Payload = &v2.Response_Resource{Resource: &v2.ResourceResponse{Message: res.Body}}
+	case model.AggregateSuccess.String():
+		result.Payload = &v2.Response_Aggregate{Aggregate: &v2.AggregateResponse{Success: true, Message: res.Body}}
+	case model.AggregateFailed.String():
+		result.Payload = &v2.Response_Aggregate{Aggregate: &v2.AggregateResponse{Message: res.Body}}
+	case model.ResourceUpdateOrCreate.String(), model.ResourceDelete.String():
+		data := &model.SyncResourceResponse{}
+		if err := unmarshalBody(res.Body, data); err != nil {
+			return nil, err
+		}
+		sync := &v2.ResourceSync{Operation: v2.ResourceSyncOperation_UpdateOrCreate}
+		if res.Type == model.ResourceDelete.String() {
+			sync.Operation = v2.ResourceSyncOperation_Delete
+		}
+		for _, clusterResource := range data.ClusterResourceList {
+			b, err := json.Marshal(clusterResource)
+			if err != nil {
+				return nil, err
+			}
+			sync.ClusterResources = append(sync.ClusterResources, b)
+		}
+		result.Payload = &v2.Response_ResourceSync{ResourceSync: sync}
+	case model.AggregateConfigUpdateOrCreate.String(), model.AggregateConfigDelete.String():
+		data := &model.SyncAggregateConfigResponse{}
+		if err := unmarshalBody(res.Body, data); err != nil {
+			return nil, err
+		}
+		sync := &v2.AggregateConfigSync{Operation: v2.ResourceSyncOperation_UpdateOrCreate}
+		if res.Type == model.AggregateConfigDelete.String() {
+			sync.Operation = v2.ResourceSyncOperation_Delete
+		}
+		for _, policy := range data.Policies {
+			b, err := json.Marshal(policy)
+			if err != nil {
+				return nil, err
+			}
+			sync.Policies = append(sync.Policies, b)
+		}
+		for _, rule := range data.Rules {
+			b, err := json.Marshal(rule)
+			if err != nil {
+				return nil, err
+			}
+			sync.Rules = append(sync.Rules, b)
+		}
+		result.Payload = &v2.Response_AggregateConfigSync{AggregateConfigSync: sync}
+	case model.ResyncSuccess.String():
+		data := &model.ResyncResponse{}
+		if err := unmarshalBody(res.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Response_Resync{Resync: &v2.ResyncResponse{
+			Success:   true,
+			Resources: resyncItemsToV2(data.Resources),
+			Orphans:   resyncItemsToV2(data.Orphans),
+			Stale:     resyncItemsToV2(data.Stale),
+		}}
+	case model.ResyncFailed.String():
+		result.Payload = &v2.Response_Resync{Resync: &v2.ResyncResponse{Message: res.Body}}
+	case model.Tunnel.String():
+		data := &model.TunnelRequest{}
+		if err := unmarshalBody(res.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Response_Tunnel{Tunnel: &v2.TunnelRequest{
+			StreamId: data.StreamID,
+			Method:   data.Method,
+			Path:     data.Path,
+			RawQuery: data.RawQuery,
+			Header:   headerToV2(data.Header),
+			Body:     data.Body,
+			User:     data.User,
+			Groups:   data.Groups,
+			Cancel:   data.Cancel,
+		}}
+	case model.Log.String():
+		data := &model.LogRequest{}
+		if err := unmarshalBody(res.Body, data); err != nil {
+			return nil, err
+		}
+		result.Payload = &v2.Response_Log{Log: &v2.LogRequest{
+			StreamId:      data.StreamID,
+			Namespace:     data.Namespace,
+			Pod:           data.Pod,
+			LabelSelector: data.LabelSelector,
+			Container:     data.Container,
+			Follow:        data.Follow,
+			SinceSeconds:  int64Value(data.SinceSeconds),
+			TailLines:     int64Value(data.TailLines),
+			Timestamps:    data.Timestamps,
+			Previous:      data.Previous,
+			Credit:        data.Credit,
+			Cancel:        data.Cancel,
+		}}
+	case model.Error.String():
+		result.Payload = &v2.Response_Error{Error: &v2.Error{Message: res.Body}}
+	default:
+		return nil, fmt.Errorf("response type %s is not supported by protocol v2", res.Type)
+	}
+	return result, nil
+}
+
+// ResponseToV1 converts a v2 typed response to the v1 response
+func ResponseToV1(res *v2.Response) (*config.Response, error) {
+	result := &config.Response{ClusterName: res.ClusterName, RequestId: res.RequestId}
+	switch payload := res.Payload.(type) {
+	case *v2.Response_Register:
+		if !payload.Register.Success {
+			result.Type = model.RegisterFailed.String()
+			result.Body = payload.Register.Message
+			break
+		}
+		result.Type = model.RegisterSuccess.String()
+		data := &model.RegisterResponse{
+			ClusterResources:                      bytesToStrings(payload.Register.ClusterResources),
+			MultiClusterResourceAggregatePolicies: bytesToStrings(payload.Register.MultiClusterResourceAggregatePolicies),
+			MultiClusterResourceAggregateRules:    bytesToStrings(payload.Register.MultiClusterResourceAggregateRules),
+			SessionToken:                          payload.Register.SessionToken,
+			Resumed:                               payload.Register.Resumed,
+		}
+		if !data.IsEmpty() {
+			b, err := json.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			result.Body = string(b)
+		}
+	case *v2.Response_Heartbeat:
+		if !payload.Heartbeat.Success {
+			result.Type = model.HeartbeatFailed.String()
+			result.Body = payload.Heartbeat.Message
+			break
+		}
+		result.Type = model.HeartbeatSuccess.String()
+		if payload.Heartbeat.Heartbeat != nil {
+			heartbeat, err := heartbeatToV1(payload.Heartbeat.Heartbeat)
+			if err != nil {
+				return nil, err
+			}
+			b, err := json.Marshal(heartbeat)
+			if err != nil {
+				return nil, err
+			}
+			result.Body = string(b)
+		}
+	case *v2.Response_Resource:
+		result.Type = model.ResourceStatusUpdateFailed.String()
+		if payload.Resource.Success {
+			result.Type = model.ResourceStatusUpdateSuccess.String()
+		}
+		result.Body = payload.Resource.Message
+	case *v2.Response_Aggregate:
+		result.Type = model.AggregateFailed.String()
+		if payload.Aggregate.Success {
+			result.Type = model.AggregateSuccess.String()
+		}
+		result.Body = payload.Aggregate.Message
+	case *v2.Response_ResourceSync:
+		result.Type = model.ResourceUpdateOrCreate.String()
+		if payload.ResourceSync.Operation == v2.ResourceSyncOperation_Delete {
+			result.Type = model.ResourceDelete.String()
+		}
+		data := &model.SyncResourceResponse{}
+		for _, item := range payload.ResourceSync.ClusterResources {
+			clusterResource := &v1alpha1.ClusterResource{}
+			if err := json.Unmarshal(item, clusterResource); err != nil {
+				return nil, err
+			}
+			data.ClusterResourceList = append(data.ClusterResourceList, clusterResource)
+		}
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		result.Body = string(b)
+	case *v2.Response_AggregateConfigSync:
+		result.Type = model.AggregateConfigUpdateOrCreate.String()
+		if payload.AggregateConfigSync.Operation == v2.ResourceSyncOperation_Delete {
+			result.Type = model.AggregateConfigDelete.String()
+		}
+		data := &model.SyncAggregateConfigResponse{}
+		for _, item := range payload.AggregateConfigSync.Policies {
+			policy := &v1alpha1.MultiClusterResourceAggregatePolicy{}
+			if err := json.Unmarshal(item, policy); err != nil {
+				return nil, err
+			}
+			data.Policies = append(data.Policies, policy)
+		}
+		for _, item := range payload.AggregateConfigSync.Rules {
+			rule := &v1alpha1.MultiClusterResourceAggregateRule{}
+			if err := json.Unmarshal(item, rule); err != nil {
+				return nil, err
+			}
+			data.Rules = append(data.Rules, rule)
+		}
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		result.Body = string(b)
+	case *v2.Response_Resync:
+		if !payload.Resync.Success {
+			result.Type = model.ResyncFailed.String()
+			result.Body = payload.Resync.Message
+			break
+		}
+		result.Type = model.ResyncSuccess.String()
+		b, err := json.Marshal(&model.ResyncResponse{
+			Resources: resyncItemsToV1(payload.Resync.Resources),
+			Orphans:   resyncItemsToV1(payload.Resync.Orphans),
+			Stale:     resyncItemsToV1(payload.Resync.Stale),
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Body = string(b)
+	case *v2.Response_Tunnel:
+		result.Type = model.Tunnel.String()
+		b, err := json.Marshal(&model.TunnelRequest{
+			StreamID: payload.Tunnel.StreamId,
+			Method:   payload.Tunnel.Method,
+			Path:     payload.Tunnel.Path,
+			RawQuery: payload.Tunnel.RawQuery,
+			Header:   headerToV1(payload.Tunnel.Header),
+			Body:     payload.Tunnel.Body,
+			User:     payload.Tunnel.User,
+			Groups:   payload.Tunnel.Groups,
+			Cancel:   payload.Tunnel.Cancel,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Body = string(b)
+	case *v2.Response_Log:
+		result.Type = model.Log.String()
+		b, err := json.Marshal(&model.LogRequest{
+			StreamID:      payload.Log.StreamId,
+			Namespace:     payload.Log.Namespace,
+			Pod:           payload.Log.Pod,
+			LabelSelector: payload.Log.LabelSelector,
+			Container:     payload.Log.Container,
+			Follow:        payload.Log.Follow,
+			SinceSeconds:  int64Pointer(payload.Log.SinceSeconds),
+			TailLines:     int64Pointer(payload.Log.TailLines),
+			Timestamps:    payload.Log.Timestamps,
+			Previous:      payload.Log.Previous,
+			Credit:        payload.Log.Credit,
+			Cancel:        payload.Log.Cancel,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Body = string(b)
+	case *v2.Response_Error:
+		result.Type = model.Error.String()
+		result.Body = payload.Error.Message
+	default:
+		return nil, errors.New("response payload is empty")
+	}
+	return result, nil
+}
+
+func unmarshalBody(body string, v interface{}) error {
+	if len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal([]byte(body), v)
+}
+
+func addonsToV2(addons []model.Addon) ([]*v2.Addon, error) {
+	var result []*v2.Addon
+	for _, addon := range addons {
+		item := &v2.Addon{Name: addon.Name}
+		if addon.Properties != nil {
+			b, err := json.Marshal(addon.Properties)
+			if err != nil {
+				return nil, err
+			}
+			item.Properties = b
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func addonsToV1(addons []*v2.Addon) ([]model.Addon, error) {
+	var result []model.Addon
+	for _, addon := range addons {
+		item := model.Addon{Name: addon.Name}
+		if len(addon.Properties) > 0 {
+			if err := json.Unmarshal(addon.Properties, &item.Properties); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func heartbeatToV2(heartbeat *model.HeartbeatWithChangeRequest) (*v2.HeartbeatRequest, error) {
+	addons, err := addonsToV2(heartbeat.Addons)
+	if err != nil {
+		return nil, err
+	}
+	result := &v2.HeartbeatRequest{Healthy: heartbeat.Healthy, Addons: addons}
+	if heartbeat.Digest != nil {
+		result.Digest = &v2.ResourceDigest{Checksum: heartbeat.Digest.Checksum}
+		for _, item := range heartbeat.Digest.Items {
+			result.Digest.Items = append(result.Digest.Items, &v2.DigestItem{Name: item.Name, Namespace: item.Namespace, SpecHash: item.SpecHash})
+		}
+	}
+	if summary := heartbeat.ResourceSummary; summary != nil {
+		result.ResourceSummary = &v2.ResourceSummary{
+			Nodes:            summary.Nodes,
+			SchedulableNodes: summary.SchedulableNodes,
+			Allocatable:      resourceListToV2(summary.Allocatable),
+			Allocated:        resourceListToV2(summary.Allocated),
+		}
+	}
+	for _, condition := range heartbeat.Conditions {
+		result.Conditions = append(result.Conditions, &v2.Condition{
+			Timestamp: timestamppb.New(condition.Timestamp.Time),
+			Message:   condition.Message,
+			Reason:    condition.Reason,
+			Type:      condition.Type,
+			Status:    string(condition.Status),
+		})
+	}
+	return result, nil
+}
+
+func heartbeatToV1(heartbeat *v2.HeartbeatRequest) (*model.HeartbeatWithChangeRequest, error) {
+	addons, err := addonsToV1(heartbeat.Addons)
+	if err != nil {
+		return nil, err
+	}
+	result := &model.HeartbeatWithChangeRequest{Healthy: heartbeat.Healthy, Addons: addons}
+	if heartbeat.Digest != nil {
+		result.Digest = &model.ResourceDigest{Checksum: heartbeat.Digest.Checksum}
+		for _, item := range heartbeat.Digest.Items {
+			result.Digest.Items = append(result.Digest.Items, model.DigestItem{Name: item.Name, Namespace: item.Namespace, SpecHash: item.SpecHash})
+		}
+	}
+	if summary := heartbeat.ResourceSummary; summary != nil {
+		result.ResourceSummary = &v1alpha1.ClusterResourceSummary{
+			Nodes:            summary.Nodes,
+			SchedulableNodes: summary.SchedulableNodes,
+		}
+		if result.ResourceSummary.Allocatable, err = resourceListToV1(summary.Allocatable); err != nil {
+			return nil, err
+		}
+		if result.ResourceSummary.Allocated, err = resourceListToV1(summary.Allocated); err != nil {
+			return nil, err
+		}
+	}
+	for _, condition := range heartbeat.Conditions {
+		result.Conditions = append(result.Conditions, model.Condition{
+			Timestamp: metav1.NewTime(condition.Timestamp.AsTime()),
+			Message:   condition.Message,
+			Reason:    condition.Reason,
+			Type:      condition.Type,
+			Status:    metav1.ConditionStatus(condition.Status),
+		})
+	}
+	return result, nil
+}
+
+func resourceListToV2(list corev1.ResourceList) []*v2.ResourceQuantity {
+	var result []*v2.ResourceQuantity
+	for name, quantity := range list {
+		result = append(result, &v2.ResourceQuantity{Name: string(name), Value: quantity.String()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func resourceListToV1(quantities []*v2.ResourceQuantity) (corev1.ResourceList, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+	result := make(corev1.ResourceList, len(quantities))
+	for _, item := range quantities {
+		quantity, err := resource.ParseQuantity(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: %s", item.Name, err)
+		}
+		result[corev1.ResourceName(item.Name)] = quantity
+	}
+	return result, nil
+}
+
+func resyncItemsToV2(items []model.ResyncItem) []*v2.ResyncItem {
+	var result []*v2.ResyncItem
+	for _, item := range items {
+		result = append(result, &v2.ResyncItem{
+			Name:                      item.Name,
+			Namespace:                 item.Namespace,
+			Generation:                item.Generation,
+			ObservedReceiveGeneration: item.ObservedReceiveGeneration,
+		})
+	}
+	return result
+}
+
+func resyncItemsToV1(items []*v2.ResyncItem) []model.ResyncItem {
+	var result []model.ResyncItem
+	for _, item := range items {
+		result = append(result, model.ResyncItem{
+			Name:                      item.Name,
+			Namespace:                 item.Namespace,
+			Generation:                item.Generation,
+			ObservedReceiveGeneration: item.ObservedReceiveGeneration,
+		})
+	}
+	return result
+}
+
+func headerToV2(header map[string][]string) []*v2.Header {
+	var result []*v2.Header
+	for name, values := range header {
+		result = append(result, &v2.Header{Name: name, Values: values})
+	}
+	return result
+}
+
+func headerToV1(header []*v2.Header) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+	result := make(map[string][]string, len(header))
+	for _, item := range header {
+		result[item.Name] = item.Values
+	}
+	return result
+}
+
+func eventsToV2(events []model.ClusterEvent) []*v2.ClusterEvent {
+	var result []*v2.ClusterEvent
+	for _, event := range events {
+		result = append(result, &v2.ClusterEvent{
+			ClusterResource: event.ClusterResource,
+			Kind:            event.Kind,
+			Namespace:       event.Namespace,
+			Name:            event.Name,
+			Type:            event.Type,
+			Reason:          event.Reason,
+			Message:         event.Message,
+			Count:           event.Count,
+			LastTimestamp:   timestamppb.New(event.LastTimestamp.Time),
+		})
+	}
+	return result
+}
+
+func eventsToV1(events []*v2.ClusterEvent) []model.ClusterEvent {
+	var result []model.ClusterEvent
+	for _, event := range events {
+		result = append(result, model.ClusterEvent{
+			ClusterResource: event.ClusterResource,
+			Kind:            event.Kind,
+			Namespace:       event.Namespace,
+			Name:            event.Name,
+			Type:            event.Type,
+			Reason:          event.Reason,
+			Message:         event.Message,
+			Count:           event.Count,
+			LastTimestamp:   metav1.NewTime(event.LastTimestamp.AsTime()),
+		})
+	}
+	return result
+}
+
+// int64Value and int64Pointer convert the optional integers, 0 means unset in protocol v2
+func int64Value(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func int64Pointer(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func stringsToBytes(items []string) [][]byte {
+	var result [][]byte
+	for _, item := range items {
+		result = append(result, []byte(item))
+	}
+	return result
+}
+
+func bytesToStrings(items [][]byte) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, string(item))
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRequestRoundTrip(t *testing.T) {
+	now := metav1.Unix(metav1.Now().Unix(), 0)
+	bodies := map[model.ServiceRequestType]interface{}{
+		model.Register: &model.RegisterRequest{
+			Addons: []model.Addon{{Name: "etcd", Properties: map[string]interface{}{"healthy": true}}},
+			Token:  "abcdef.0123456789abcdef",
+		},
+		model.Heartbeat: &model.HeartbeatWithChangeRequest{
+			Healthy:    true,
+			Conditions: []model.Condition{{Timestamp: now, Message: "ok", Reason: "Ready", Type: "APIServerReady", Status: metav1.ConditionTrue}},
+			Digest:     &model.ResourceDigest{Checksum: "sum", Items: []model.DigestItem{{Name: "a", Namespace: "b", SpecHash: "hash"}}},
+			ResourceSummary: &v1alpha1.ClusterResourceSummary{
+				Nodes:            3,
+				SchedulableNodes: 2,
+				Allocatable:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8"), corev1.ResourceMemory: resource.MustParse("16Gi")},
+				Allocated:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2500m"), corev1.ResourcePods: resource.MustParse("12")},
+			},
+		},
+		model.Aggregate: &model.AggregateRequest{
+			PolicyNamespace: "default",
+			PolicyName:      "policy",
+			RuleName:        "rule",
+			Results:         []model.AggregateResult{{Name: "a", Namespace: "b", Result: runtime.RawExtension{Raw: []byte(`{"replicas":1}`)}}, {Name: "c", Namespace: "b", Error: "rule failed"}},
+		},
+		model.Ack: &model.AckRequest{Message: "apply failed"},
+		model.TunnelChunk: &model.TunnelResponse{
+			StreamID:   "stream",
+			Seq:        1,
+			StatusCode: 200,
+			Header:     map[string][]string{"Content-Type": {"application/json"}},
+			Body:       []byte(`{"kind":"PodList"}`),
+		},
+		model.Event: &model.EventRequest{Events: []model.ClusterEvent{{
+			ClusterResource: "nginx.apps.v1.deployment",
+			Kind:            "Pod",
+			Namespace:       "default",
+			Name:            "nginx-6799fc88d8-h8k2x",
+			Type:            "Warning",
+			Reason:          "Failed",
+			Message:         "Failed to pull image",
+			Count:           3,
+			LastTimestamp:   metav1.NewTime(time.Unix(1700000000, 0)),
+		}}},
+		model.LogChunk: &model.LogResponse{StreamID: "stream", Seq: 2, Data: []byte("line\n"), Done: true},
+		model.Resync: &model.ResyncRequest{
+			Inventory: []model.ResyncItem{{Name: "a", Namespace: "b", Generation: 2, ObservedReceiveGeneration: 1}},
+		},
+	}
+	for typ, body := range bodies {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &config.Request{Type: typ.String(), ClusterName: "cluster-a", Body: string(b), RequestId: "id"}
+		data, err := RequestToV2(req)
+		if err != nil {
+			t.Fatalf("%s: convert to v2 failed: %s", typ, err)
+		}
+		result, err := RequestToV1(data)
+		if err != nil {
+			t.Fatalf("%s: convert to v1 failed: %s", typ, err)
+		}
+		if result.Type != req.Type || result.ClusterName != req.ClusterName || result.RequestId != req.RequestId {
+			t.Fatalf("%s: got %s(%s) from cluster %s", typ, result.Type, result.RequestId, result.ClusterName)
+		}
+		expected := reflect.New(reflect.TypeOf(body).Elem()).Interface()
+		actual := reflect.New(reflect.TypeOf(body).Elem()).Interface()
+		if err = json.Unmarshal(b, expected); err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal([]byte(result.Body), actual); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("%s: body changed after round trip, expected %s, got %s", typ, req.Body, result.Body)
+		}
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	cases := []*config.Response{
+		{Type: model.RegisterFailed.String(), ClusterName: "cluster-a", Body: "token is invalid"},
+		{Type: model.ResourceStatusUpdateSuccess.String(), ClusterName: "cluster-a"},
+		{Type: model.AggregateFailed.String(), ClusterName: "cluster-a", Body: "rule not found"},
+		{Type: model.Error.String(), ClusterName: "cluster-a", Body: "bad request"},
+	}
+	configSync, _ := json.Marshal(&model.SyncAggregateConfigResponse{
+		Rules: []*v1alpha1.MultiClusterResourceAggregateRule{{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rule"}}},
+	})
+	cases = append(cases, &config.Response{Type: model.AggregateConfigDelete.String(), ClusterName: "cluster-a", Body: string(configSync)})
+	tunnel, _ := json.Marshal(&model.TunnelRequest{StreamID: "stream", Method: "GET", Path: "/api/v1/pods", RawQuery: "watch=true", User: "admin", Groups: []string{"system:masters"}})
+	cases = append(cases, &config.Response{Type: model.Tunnel.String(), ClusterName: "cluster-a", Body: string(tunnel)})
+	tailLines := int64(10)
+	log, _ := json.Marshal(&model.LogRequest{StreamID: "stream", Namespace: "default", LabelSelector: "app=nginx", Follow: true, TailLines: &tailLines, Credit: 16})
+	cases = append(cases, &config.Response{Type: model.Log.String(), ClusterName: "cluster-a", Body: string(log)})
+	for _, res := range cases {
+		data, err := ResponseToV2(res)
+		if err != nil {
+			t.Fatalf("%s: convert to v2 failed: %s", res.Type, err)
+		}
+		result, err := ResponseToV1(data)
+		if err != nil {
+			t.Fatalf("%s: convert to v1 failed: %s", res.Type, err)
+		}
+		if !reflect.DeepEqual(res, result) {
+			t.Fatalf("%s: response changed after round trip, got %v", res.Type, result)
+		}
+	}
+	if _, err := ResponseToV2(&config.Response{Type: "Unknown"}); err == nil {
+		t.Fatal("unknown response type should not be converted")
+	}
+}
@@ -0,0 +1,119 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultSendQueueSize is the number of messages which can wait to be sent on one stream
+const DefaultSendQueueSize = 128
+
+// ErrSendQueueFull is returned when the peer can not keep up with the messages sent to it
+var ErrSendQueueFull = errors.New("send queue of stream is full")
+
+type sendItem struct {
+	msg    interface{}
+	result chan error
+}
+
+// sendQueue sends the messages of one stream in a single goroutine, grpc does not allow concurrent
+// SendMsg on a stream, and messages are sent in the order they are queued
+type sendQueue struct {
+	ctx   context.Context
+	items chan *sendItem
+}
+
+func newSendQueue(ctx context.Context, size int, sendMsg func(interface{}) error) *sendQueue {
+	q := &sendQueue{
+		ctx:   ctx,
+		items: make(chan *sendItem, size),
+	}
+	go q.loop(sendMsg)
+	return q
+}
+
+func (q *sendQueue) loop(sendMsg func(interface{}) error) {
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case item := <-q.items:
+			item.result <- sendMsg(item.msg)
+		}
+	}
+}
+
+// send queues the message and waits until it is sent
+func (q *sendQueue) send(msg interface{}) error {
+	item := &sendItem{msg: msg, result: make(chan error, 1)}
+	select {
+	case <-q.ctx.Done():
+		return q.ctx.Err()
+	case q.items <- item:
+	default:
+		return ErrSendQueueFull
+	}
+	select {
+	case <-q.ctx.Done():
+		return q.ctx.Err()
+	case err := <-item.result:
+		return err
+	}
+}
+
+type serialServerStream struct {
+	grpc.ServerStream
+	queue *sendQueue
+}
+
+func (s *serialServerStream) SendMsg(m interface{}) error {
+	return s.queue.send(m)
+}
+
+// SerialStreamServerInterceptor serializes the sends on every server stream with a bounded queue
+func SerialStreamServerInterceptor(size int) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serialServerStream{
+			ServerStream: ss,
+			queue:        newSendQueue(ss.Context(), size, ss.SendMsg),
+		})
+	}
+}
+
+type serialClientStream struct {
+	grpc.ClientStream
+	queue  *sendQueue
+	cancel context.CancelFunc
+}
+
+func (s *serialClientStream) SendMsg(m interface{}) error {
+	return s.queue.send(m)
+}
+
+// RecvMsg stops the send loop when the stream is finished
+func (s *serialClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+// SerialStreamClientInterceptor serializes the sends on every client stream with a bounded queue
+func SerialStreamClientInterceptor(size int) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &serialClientStream{
+			ClientStream: cs,
+			queue:        newSendQueue(ctx, size, cs.SendMsg),
+			cancel:       cancel,
+		}, nil
+	}
+}
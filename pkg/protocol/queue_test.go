@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestSendQueueKeepsOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var sent []int
+	q := newSendQueue(ctx, 10, func(m interface{}) error {
+		sent = append(sent, m.(int))
+		return nil
+	})
+	for i := 0; i < 5; i++ {
+		if err := q.send(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, v := range sent {
+		if v != i {
+			t.Fatalf("expect messages sent in order, but got %v", sent)
+		}
+	}
+}
+
+func TestSendQueueFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	taken := make(chan struct{})
+	block := make(chan struct{})
+	q := newSendQueue(ctx, 1, func(m interface{}) error {
+		taken <- struct{}{}
+		<-block
+		return nil
+	})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = q.send(0)
+	}()
+	// wait until the first message is taken by send loop, then the second one fills the queue
+	select {
+	case <-taken:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("first message is not taken by send loop")
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = q.send(1)
+	}()
+	if err := wait.Poll(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return len(q.items) == 1, nil
+	}); err != nil {
+		t.Fatalf("second message is not queued: %v", err)
+	}
+	if err := q.send(2); err != ErrSendQueueFull {
+		t.Fatalf("expect ErrSendQueueFull, but got %v", err)
+	}
+	close(block)
+	<-taken
+	wg.Wait()
+}
@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"harmonycloud.cn/stellaris/config"
+	v2 "harmonycloud.cn/stellaris/config/v2"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var protocolLog = logf.Log.WithName("protocol")
+
+const (
+	// VersionV1 is the protocol which carries json body in string field
+	VersionV1 = "v1"
+	// VersionV2 is the protocol with typed payload
+	VersionV2 = "v2"
+)
+
+// serverStream adapts a v2 server stream to the v1 stream used by core handlers
+type serverStream struct {
+	v2.Channel_EstablishServer
+}
+
+// NewServerStream returns a v1 server stream which reads and writes on the v2 stream
+func NewServerStream(stream v2.Channel_EstablishServer) config.Channel_EstablishServer {
+	return &serverStream{Channel_EstablishServer: stream}
+}
+
+func (s *serverStream) Send(res *config.Response) error {
+	data, err := ResponseToV2(res)
+	if err != nil {
+		return err
+	}
+	return s.Channel_EstablishServer.Send(data)
+}
+
+// Recv skips the request which can not be converted, so that one bad message does not break the stream
+func (s *serverStream) Recv() (*config.Request, error) {
+	for {
+		req, err := s.Channel_EstablishServer.Recv()
+		if err != nil {
+			return nil, err
+		}
+		data, err := RequestToV1(req)
+		if err != nil {
+			protocolLog.Error(err, "convert request failed", "cluster", req.ClusterName)
+			if sendErr := s.Channel_EstablishServer.Send(&v2.Response{
+				ClusterName: req.ClusterName,
+				Payload:     &v2.Response_Error{Error: &v2.Error{Message: err.Error()}},
+			}); sendErr != nil {
+				return nil, sendErr
+			}
+			continue
+		}
+		return data, nil
+	}
+}
+
+// clientStream adapts a v2 client stream to the v1 stream used by proxy
+type clientStream struct {
+	v2.Channel_EstablishClient
+}
+
+// NewClientStream returns a v1 client stream which reads and writes on the v2 stream
+func NewClientStream(stream v2.Channel_EstablishClient) config.Channel_EstablishClient {
+	return &clientStream{Channel_EstablishClient: stream}
+}
+
+func (s *clientStream) Send(req *config.Request) error {
+	data, err := RequestToV2(req)
+	if err != nil {
+		return err
+	}
+	return s.Channel_EstablishClient.Send(data)
+}
+
+// Recv skips the response which can not be converted, so that one bad message does not break the stream
+func (s *clientStream) Recv() (*config.Response, error) {
+	for {
+		res, err := s.Channel_EstablishClient.Recv()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ResponseToV1(res)
+		if err != nil {
+			protocolLog.Error(err, "convert response failed")
+			continue
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,80 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+	cueRender "harmonycloud.cn/stellaris/pkg/utils/cue-render"
+)
+
+var aggregateLog = logf.Log.WithName("proxy_aggregate")
+
+// FindRule returns the rule referenced by policy, the rule in the namespace of policy is preferred,
+// otherwise the name must be unique in all namespaces
+func FindRule(rules []v1alpha1.MultiClusterResourceAggregateRule, policyNamespace, name string) (*v1alpha1.MultiClusterResourceAggregateRule, error) {
+	var found *v1alpha1.MultiClusterResourceAggregateRule
+	for i := range rules {
+		if rules[i].Name != name {
+			continue
+		}
+		if rules[i].Namespace == policyNamespace {
+			return &rules[i], nil
+		}
+		if found != nil {
+			return nil, fmt.Errorf("rule %s is found in more than one namespace", name)
+		}
+		found = &rules[i]
+	}
+	if found == nil {
+		return nil, fmt.Errorf("rule %s is not found", name)
+	}
+	return found, nil
+}
+
+// RuleGroupVersionKind returns the gvk of resources which rule aggregates
+func RuleGroupVersionKind(rule *v1alpha1.MultiClusterResourceAggregateRule) (schema.GroupVersionKind, error) {
+	ref := rule.Spec.ResourceRef
+	if ref == nil || len(ref.Version) == 0 || len(ref.Kind) == 0 {
+		return schema.GroupVersionKind{}, fmt.Errorf("resourceRef of rule %s:%s is invalid", rule.Namespace, rule.Name)
+	}
+	return schema.GroupVersionKind{Group: ref.Group, Version: ref.Version, Kind: ref.Kind}, nil
+}
+
+// NewRequest renders the objects which match the policy limit
+func NewRequest(policy *v1alpha1.MultiClusterResourceAggregatePolicy, rule *v1alpha1.MultiClusterResourceAggregateRule, objects []*unstructured.Unstructured) *model.AggregateRequest {
+	request := &model.AggregateRequest{
+		PolicyNamespace: policy.Namespace,
+		PolicyName:      policy.Name,
+		RuleName:        rule.Name,
+		Results:         []model.AggregateResult{},
+	}
+	for _, object := range objects {
+		if !MatchLimit(policy.Spec.Limit, object.GetNamespace(), object.GetName()) {
+			continue
+		}
+		request.Results = append(request.Results, Render(rule, object))
+	}
+	return request
+}
+
+// Render evaluates the cue of rule with the object, the error is reported in the result
+func Render(rule *v1alpha1.MultiClusterResourceAggregateRule, object *unstructured.Unstructured) model.AggregateResult {
+	result := model.AggregateResult{
+		Name:      object.GetName(),
+		Namespace: object.GetNamespace(),
+	}
+	data, err := cueRender.RenderCue(object, rule.Spec.Rule.Cue, "")
+	if err != nil {
+		aggregateLog.Error(err, fmt.Sprintf("render %s(%s:%s) with rule(%s:%s) failed", object.GetKind(), object.GetNamespace(), object.GetName(), rule.Namespace, rule.Name))
+		result.Error = err.Error()
+		return result
+	}
+	result.Result = runtime.RawExtension{Raw: data}
+	return result
+}
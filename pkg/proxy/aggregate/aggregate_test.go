@@ -0,0 +1,70 @@
+package aggregate
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+func newDeployment(namespace, name string, replicas int64) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("apps/v1")
+	object.SetKind("Deployment")
+	object.SetNamespace(namespace)
+	object.SetName(name)
+	_ = unstructured.SetNestedField(object.Object, replicas, "spec", "replicas")
+	return object
+}
+
+func TestNewRequest(t *testing.T) {
+	policy := &v1alpha1.MultiClusterResourceAggregatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "stellaris-harmonycloud-cn-cluster-a", Name: "policy"},
+		Spec: v1alpha1.MultiClusterResourceAggregatePolicySpec{
+			Limit: &v1alpha1.MultiClusterResourceAggregatePolicyLimit{
+				Requests: []v1alpha1.MultiClusterResourceAggregatePolicyLimitRule{{Namespaces: "default"}},
+				Ignores: []v1alpha1.MultiClusterResourceAggregatePolicyLimitRule{
+					{Namespaces: "*", NameMatch: v1alpha1.MultiClusterResourceAggregatePolicyLimitRuleMatch{Regexp: "^ignored-"}},
+				},
+			},
+		},
+	}
+	rule := &v1alpha1.MultiClusterResourceAggregateRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "replicas"},
+		Spec: v1alpha1.MultiClusterResourceAggregateRuleSpec{
+			Rule: v1alpha1.MultiClusterResourceAggregateRuleRule{Cue: "output: replicas: context.spec.replicas\n"},
+		},
+	}
+	request := NewRequest(policy, rule, []*unstructured.Unstructured{
+		newDeployment("default", "web", 2),
+		newDeployment("default", "ignored-web", 1),
+		newDeployment("kube-system", "dns", 1),
+	})
+	if len(request.Results) != 1 || request.Results[0].Name != "web" {
+		t.Fatalf("expect only default/web is aggregated, but got %v", request.Results)
+	}
+	if result := string(request.Results[0].Result.Raw); result != `{"replicas":2}` {
+		t.Fatalf("expect rendered replicas, but got %s", result)
+	}
+
+	rule.Spec.Rule.Cue = "output: replicas: context.spec.notExist\n"
+	request = NewRequest(policy, rule, []*unstructured.Unstructured{newDeployment("default", "web", 2)})
+	if len(request.Results) != 1 || len(request.Results[0].Error) == 0 {
+		t.Fatalf("expect rule evaluation error in result, but got %v", request.Results)
+	}
+}
+
+func TestFindRule(t *testing.T) {
+	rules := []v1alpha1.MultiClusterResourceAggregateRule{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "rule"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "rule"}},
+	}
+	if rule, err := FindRule(rules, "b", "rule"); err != nil || rule.Namespace != "b" {
+		t.Fatalf("expect rule in policy namespace, but got %v, %v", rule, err)
+	}
+	if _, err := FindRule(rules, "c", "rule"); err == nil {
+		t.Fatal("expect error when rule name is ambiguous")
+	}
+}
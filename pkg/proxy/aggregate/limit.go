@@ -0,0 +1,55 @@
+package aggregate
+
+import (
+	"regexp"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+// allNamespaces in limit rule matches the resources of every namespace
+const allNamespaces = "*"
+
+// MatchLimit checks whether the resource is aggregated by the policy limit, a resource matches when it
+// matches one of the requests (or requests is empty) and none of the ignores
+func MatchLimit(limit *v1alpha1.MultiClusterResourceAggregatePolicyLimit, namespace, name string) bool {
+	if limit == nil {
+		return true
+	}
+	if len(limit.Requests) > 0 && !matchAnyRule(limit.Requests, namespace, name) {
+		return false
+	}
+	return !matchAnyRule(limit.Ignores, namespace, name)
+}
+
+func matchAnyRule(rules []v1alpha1.MultiClusterResourceAggregatePolicyLimitRule, namespace, name string) bool {
+	for _, rule := range rules {
+		if matchRule(rule, namespace, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRule(rule v1alpha1.MultiClusterResourceAggregatePolicyLimitRule, namespace, name string) bool {
+	if len(rule.Namespaces) > 0 && rule.Namespaces != allNamespaces && rule.Namespaces != namespace {
+		return false
+	}
+	match := rule.NameMatch
+	if len(match.Regexp) == 0 && len(match.List) == 0 {
+		return true
+	}
+	for _, item := range match.List {
+		if item == name {
+			return true
+		}
+	}
+	if len(match.Regexp) > 0 {
+		matched, err := regexp.MatchString(match.Regexp, name)
+		if err != nil {
+			aggregateLog.Error(err, "invalid name regexp in policy limit")
+			return false
+		}
+		return matched
+	}
+	return false
+}
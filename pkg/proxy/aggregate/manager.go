@@ -0,0 +1,268 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+const (
+	// aggregateDelay coalesces the events of resources into one aggregate request
+	aggregateDelay = 2 * time.Second
+	resyncPeriod   = 10 * time.Minute
+)
+
+// pair is one rule of one policy, it is aggregated as a whole
+type pair struct {
+	policy *v1alpha1.MultiClusterResourceAggregatePolicy
+	rule   *v1alpha1.MultiClusterResourceAggregateRule
+	gvk    schema.GroupVersionKind
+	// err is the reason why the rule can not be evaluated
+	err error
+}
+
+type gvkInformer struct {
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+	pairs    sets.String
+}
+
+// Manager keeps one dynamic informer for every gvk referenced by rules, and aggregates the rule of a policy
+// again when the resources of it change
+type Manager struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+	send   func(*model.AggregateRequest) error
+
+	lock      sync.Mutex
+	pairs     map[string]*pair
+	informers map[schema.GroupVersionKind]*gvkInformer
+	queue     workqueue.RateLimitingInterface
+}
+
+var defaultManager *Manager
+
+func NewManager(client dynamic.Interface, mapper meta.RESTMapper, send func(*model.AggregateRequest) error) *Manager {
+	m := &Manager{
+		client:    client,
+		mapper:    mapper,
+		send:      send,
+		pairs:     make(map[string]*pair),
+		informers: make(map[schema.GroupVersionKind]*gvkInformer),
+		queue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "aggregate"),
+	}
+	defaultManager = m
+	return m
+}
+
+// ResyncAll aggregates all rules again, it is called after proxy registers to core
+func ResyncAll() {
+	if defaultManager == nil {
+		return
+	}
+	defaultManager.lock.Lock()
+	defer defaultManager.lock.Unlock()
+	for key := range defaultManager.pairs {
+		defaultManager.queue.Add(key)
+	}
+}
+
+func pairKey(policyNamespace, policyName, ruleName string) string {
+	return policyNamespace + "/" + policyName + "/" + ruleName
+}
+
+func policyPrefix(policyNamespace, policyName string) string {
+	return policyNamespace + "/" + policyName + "/"
+}
+
+// SetPolicy replaces the rules of policy, rules which can not be resolved are reported as errors
+func (m *Manager) SetPolicy(policy *v1alpha1.MultiClusterResourceAggregatePolicy, rules []v1alpha1.MultiClusterResourceAggregateRule) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	desired := sets.NewString()
+	for _, ruleName := range policy.Spec.AggregateRules {
+		key := pairKey(policy.Namespace, policy.Name, ruleName)
+		desired.Insert(key)
+		p := &pair{policy: policy.DeepCopy()}
+		rule, err := FindRule(rules, policy.Namespace, ruleName)
+		if err == nil {
+			p.rule = rule.DeepCopy()
+			p.gvk, err = RuleGroupVersionKind(rule)
+		}
+		if err == nil {
+			err = m.watchLocked(p.gvk, key)
+		}
+		if err != nil {
+			p.rule = &v1alpha1.MultiClusterResourceAggregateRule{ObjectMeta: metav1.ObjectMeta{Name: ruleName}}
+			p.err = err
+		}
+		if old, ok := m.pairs[key]; ok && old.gvk != p.gvk {
+			m.unwatchLocked(old.gvk, key)
+		}
+		m.pairs[key] = p
+		m.queue.Add(key)
+	}
+
+	prefix := policyPrefix(policy.Namespace, policy.Name)
+	for key, p := range m.pairs {
+		if strings.HasPrefix(key, prefix) && !desired.Has(key) {
+			m.unwatchLocked(p.gvk, key)
+			delete(m.pairs, key)
+		}
+	}
+}
+
+// RemovePolicy stops aggregating the policy, the informers which are not used any more are stopped
+func (m *Manager) RemovePolicy(policyNamespace, policyName string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	prefix := policyPrefix(policyNamespace, policyName)
+	for key, p := range m.pairs {
+		if strings.HasPrefix(key, prefix) {
+			m.unwatchLocked(p.gvk, key)
+			delete(m.pairs, key)
+		}
+	}
+}
+
+func (m *Manager) watchLocked(gvk schema.GroupVersionKind, key string) error {
+	if inf, ok := m.informers[gvk]; ok {
+		inf.pairs.Insert(key)
+		return nil
+	}
+	mapping, err := m.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return err
+	}
+	informer := dynamicinformer.NewFilteredDynamicInformer(m.client, mapping.Resource, metav1.NamespaceAll, resyncPeriod, cache.Indexers{}, nil).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { m.enqueueGVK(gvk) },
+		UpdateFunc: func(oldObj, newObj interface{}) { m.enqueueGVK(gvk) },
+		DeleteFunc: func(obj interface{}) { m.enqueueGVK(gvk) },
+	})
+	inf := &gvkInformer{informer: informer, stop: make(chan struct{}), pairs: sets.NewString(key)}
+	m.informers[gvk] = inf
+	go informer.Run(inf.stop)
+	aggregateLog.Info(fmt.Sprintf("start informer of %s", gvk.String()))
+	return nil
+}
+
+func (m *Manager) unwatchLocked(gvk schema.GroupVersionKind, key string) {
+	inf, ok := m.informers[gvk]
+	if !ok {
+		return
+	}
+	inf.pairs.Delete(key)
+	if inf.pairs.Len() > 0 {
+		return
+	}
+	close(inf.stop)
+	delete(m.informers, gvk)
+	aggregateLog.Info(fmt.Sprintf("stop informer of %s", gvk.String()))
+}
+
+func (m *Manager) enqueueGVK(gvk schema.GroupVersionKind) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	inf, ok := m.informers[gvk]
+	if !ok {
+		return
+	}
+	for _, key := range inf.pairs.List() {
+		m.queue.AddAfter(key, aggregateDelay)
+	}
+}
+
+// Start runs the aggregate worker until ctx is done
+func (m *Manager) Start(ctx context.Context) error {
+	defer m.queue.ShutDown()
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		for m.processNextItem() {
+		}
+	}, time.Second)
+	<-ctx.Done()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for gvk, inf := range m.informers {
+		close(inf.stop)
+		delete(m.informers, gvk)
+	}
+	return nil
+}
+
+func (m *Manager) processNextItem() bool {
+	item, shutdown := m.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer m.queue.Done(item)
+
+	request, ready := m.newRequest(item.(string))
+	if request == nil {
+		m.queue.Forget(item)
+		return true
+	}
+	if !ready {
+		m.queue.AddAfter(item, aggregateDelay)
+		return true
+	}
+	if err := m.send(request); err != nil {
+		aggregateLog.Error(err, fmt.Sprintf("send aggregate request of policy(%s:%s) failed", request.PolicyNamespace, request.PolicyName))
+		m.queue.AddRateLimited(item)
+		return true
+	}
+	m.queue.Forget(item)
+	return true
+}
+
+// newRequest builds the request from informer cache, ready is false when the informer is not synced yet
+func (m *Manager) newRequest(key string) (request *model.AggregateRequest, ready bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p, ok := m.pairs[key]
+	if !ok {
+		return nil, true
+	}
+	if p.err != nil {
+		return &model.AggregateRequest{
+			PolicyNamespace: p.policy.Namespace,
+			PolicyName:      p.policy.Name,
+			RuleName:        p.rule.Name,
+			Error:           p.err.Error(),
+		}, true
+	}
+	inf, ok := m.informers[p.gvk]
+	if !ok {
+		return nil, true
+	}
+	if !inf.informer.HasSynced() {
+		return &model.AggregateRequest{}, false
+	}
+	var objects []*unstructured.Unstructured
+	for _, item := range inf.informer.GetStore().List() {
+		if object, ok := item.(*unstructured.Unstructured); ok {
+			object = object.DeepCopy()
+			object.SetGroupVersionKind(p.gvk)
+			objects = append(objects, object)
+		}
+	}
+	return NewRequest(p.policy, p.rule, objects), true
+}
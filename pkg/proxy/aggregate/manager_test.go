@@ -0,0 +1,86 @@
+package aggregate
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func newRule(name, kind string) v1alpha1.MultiClusterResourceAggregateRule {
+	return v1alpha1.MultiClusterResourceAggregateRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1alpha1.MultiClusterResourceAggregateRuleSpec{
+			ResourceRef: &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind},
+		},
+	}
+}
+
+func TestManagerInformerLifecycle(t *testing.T) {
+	deploymentGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deploymentGVK, meta.RESTScopeNamespace)
+	m := NewManager(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), mapper, func(*model.AggregateRequest) error { return nil })
+
+	rules := []v1alpha1.MultiClusterResourceAggregateRule{newRule("replicas", "Deployment"), newRule("unknown", "NotExist")}
+	policy := &v1alpha1.MultiClusterResourceAggregatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "policy"},
+		Spec:       v1alpha1.MultiClusterResourceAggregatePolicySpec{AggregateRules: []string{"replicas", "unknown", "missing"}},
+	}
+	m.SetPolicy(policy, rules)
+	if len(m.pairs) != 3 || len(m.informers) != 1 {
+		t.Fatalf("expect 3 rules and 1 informer, but got %d rules and %d informers", len(m.pairs), len(m.informers))
+	}
+	for _, ruleName := range []string{"unknown", "missing"} {
+		request, _ := m.newRequest(pairKey("default", "policy", ruleName))
+		if request == nil || len(request.Error) == 0 {
+			t.Fatalf("expect rule error of %s, but got %v", ruleName, request)
+		}
+	}
+
+	policy.Spec.AggregateRules = []string{"missing"}
+	m.SetPolicy(policy, rules)
+	if len(m.pairs) != 1 || len(m.informers) != 0 {
+		t.Fatalf("expect informer stopped when no rule uses it, but got %d rules and %d informers", len(m.pairs), len(m.informers))
+	}
+	m.RemovePolicy("default", "policy")
+	if len(m.pairs) != 0 {
+		t.Fatalf("expect no rules after policy removed, but got %d", len(m.pairs))
+	}
+}
+
+func TestMatchLimit(t *testing.T) {
+	limit := &v1alpha1.MultiClusterResourceAggregatePolicyLimit{
+		Requests: []v1alpha1.MultiClusterResourceAggregatePolicyLimitRule{
+			{Namespaces: "default", NameMatch: v1alpha1.MultiClusterResourceAggregatePolicyLimitRuleMatch{List: []string{"a", "b"}}},
+			{Namespaces: "app", NameMatch: v1alpha1.MultiClusterResourceAggregatePolicyLimitRuleMatch{Regexp: "^web-"}},
+		},
+		Ignores: []v1alpha1.MultiClusterResourceAggregatePolicyLimitRule{
+			{Namespaces: "*", NameMatch: v1alpha1.MultiClusterResourceAggregatePolicyLimitRuleMatch{List: []string{"b"}}},
+		},
+	}
+	for _, item := range []struct {
+		namespace, name string
+		match           bool
+	}{
+		{"default", "a", true},
+		{"default", "b", false},
+		{"default", "c", false},
+		{"app", "web-1", true},
+		{"app", "api-1", false},
+		{"other", "a", false},
+	} {
+		if MatchLimit(limit, item.namespace, item.name) != item.match {
+			t.Errorf("expect match of %s/%s is %v", item.namespace, item.name, item.match)
+		}
+	}
+	if !MatchLimit(nil, "any", "any") {
+		t.Error("expect everything matches empty limit")
+	}
+}
@@ -0,0 +1,115 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+const (
+	APIServerReadyType = "APIServerReady"
+	EtcdHealthyType    = "EtcdHealthy"
+)
+
+// APIServerProbe reports the checks of apiserver /readyz which are failed, /healthz is used by the apiserver
+// older than 1.16
+type APIServerProbe struct {
+	client rest.Interface
+}
+
+func NewAPIServerProbe(client rest.Interface) *APIServerProbe {
+	return &APIServerProbe{client: client}
+}
+
+func (p *APIServerProbe) Name() string {
+	return APIServerReadyType
+}
+
+func (p *APIServerProbe) Probe(ctx context.Context) ([]model.Condition, error) {
+	code, body, err := getHealth(ctx, p.client, "/readyz", "/healthz", true)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusOK {
+		return []model.Condition{newCondition(APIServerReadyType, metav1.ConditionTrue, "APIServerReady", "all readyz checks passed")}, nil
+	}
+	failed := failedChecks(body)
+	if len(failed) == 0 {
+		return []model.Condition{newCondition(APIServerReadyType, metav1.ConditionFalse, "APIServerNotReady", fmt.Sprintf("readyz responded with %d", code))}, nil
+	}
+	return []model.Condition{newCondition(APIServerReadyType, metav1.ConditionFalse, "APIServerNotReady", "failed checks: "+strings.Join(failed, ", "))}, nil
+}
+
+// EtcdProbe reports the etcd check of apiserver, it is the storage of member cluster
+type EtcdProbe struct {
+	client rest.Interface
+}
+
+func NewEtcdProbe(client rest.Interface) *EtcdProbe {
+	return &EtcdProbe{client: client}
+}
+
+func (p *EtcdProbe) Name() string {
+	return EtcdHealthyType
+}
+
+func (p *EtcdProbe) Probe(ctx context.Context) ([]model.Condition, error) {
+	code, body, err := getHealth(ctx, p.client, "/readyz/etcd", "/healthz/etcd", false)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusOK {
+		return []model.Condition{newCondition(EtcdHealthyType, metav1.ConditionTrue, "EtcdHealthy", "etcd check passed")}, nil
+	}
+	message := strings.TrimSpace(string(body))
+	if len(message) == 0 {
+		message = fmt.Sprintf("etcd check responded with %d", code)
+	}
+	return []model.Condition{newCondition(EtcdHealthyType, metav1.ConditionFalse, "EtcdUnhealthy", message)}, nil
+}
+
+// getHealth requests the health path, the fallback path is requested when it is not found. error is only
+// returned when apiserver can not be reached
+func getHealth(ctx context.Context, client rest.Interface, path, fallback string, verbose bool) (int, []byte, error) {
+	var code int
+	var body []byte
+	for _, p := range []string{path, fallback} {
+		request := client.Get().AbsPath(p)
+		if verbose {
+			request = request.Param("verbose", "true")
+		}
+		result := request.Do(ctx)
+		result.StatusCode(&code)
+		var err error
+		body, err = result.Raw()
+		if code == 0 {
+			return 0, nil, err
+		}
+		if code != http.StatusNotFound {
+			break
+		}
+	}
+	return code, body, nil
+}
+
+// failedChecks reads the names of failed checks from the verbose output, the lines are like
+// "[-]etcd failed: reason withheld"
+func failedChecks(body []byte) []string {
+	var failed []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if !strings.HasPrefix(line, "[-]") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "[-]"))
+		if len(fields) > 0 {
+			failed = append(failed, fields[0])
+		}
+	}
+	return failed
+}
@@ -0,0 +1,56 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
+)
+
+const (
+	CertificateValidType = "ProxyCertificateValid"
+
+	// certificateExpiringPeriod is how long before expiry the certificate is reported as expiring
+	certificateExpiringPeriod = 30 * 24 * time.Hour
+)
+
+// CertificateProbe reports the expiry of the grpc client certificate of proxy, nothing is reported when mutual tls
+// is disabled
+type CertificateProbe struct {
+	store *certificate.Store
+}
+
+func NewCertificateProbe(store *certificate.Store) *CertificateProbe {
+	return &CertificateProbe{store: store}
+}
+
+func (p *CertificateProbe) Name() string {
+	return CertificateValidType
+}
+
+func (p *CertificateProbe) Probe(ctx context.Context) ([]model.Condition, error) {
+	if p.store == nil {
+		return nil, nil
+	}
+	notAfter, err := p.store.NotAfter()
+	if err != nil {
+		return nil, err
+	}
+	return []model.Condition{certificateCondition(notAfter, time.Now())}, nil
+}
+
+func certificateCondition(notAfter, now time.Time) model.Condition {
+	expiry := notAfter.UTC().Format(time.RFC3339)
+	switch {
+	case !now.Before(notAfter):
+		return newCondition(CertificateValidType, metav1.ConditionFalse, "CertificateExpired", fmt.Sprintf("certificate expired at %s", expiry))
+	case notAfter.Sub(now) < certificateExpiringPeriod:
+		return newCondition(CertificateValidType, metav1.ConditionTrue, "CertificateExpiring", fmt.Sprintf("certificate expires at %s", expiry))
+	default:
+		return newCondition(CertificateValidType, metav1.ConditionTrue, "CertificateValid", fmt.Sprintf("certificate expires at %s", expiry))
+	}
+}
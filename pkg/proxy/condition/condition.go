@@ -1,9 +1,84 @@
 package condition
 
-import "harmonycloud.cn/stellaris/pkg/model"
+import (
+	"context"
+	"sync"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var conditionLog = logf.Log.WithName("proxy_condition")
+
+const (
+	// probeTimeout limits one probe, so that a hanging probe does not delay the heartbeat
+	probeTimeout = 10 * time.Second
+	// ProbeFailedReason is the reason of the condition when the probe itself fails
+	ProbeFailedReason = "ProbeFailed"
+)
+
+// Probe checks one aspect of member cluster, the type of condition reported on failure is Name
+type Probe interface {
+	Name() string
+	Probe(ctx context.Context) ([]model.Condition, error)
+}
+
+var (
+	lock   sync.RWMutex
+	probes []Probe
+)
+
+// Register adds probes whose conditions are sent in heartbeat
+func Register(p ...Probe) {
+	lock.Lock()
+	defer lock.Unlock()
+	probes = append(probes, p...)
+}
+
+// GetProxyCondition runs all registered probes concurrently, the conditions are in order of registration
 func GetProxyCondition() []model.Condition {
+	lock.RLock()
+	registered := probes
+	lock.RUnlock()
+
+	results := make([][]model.Condition, len(registered))
+	var wg sync.WaitGroup
+	for i, probe := range registered {
+		wg.Add(1)
+		go func(i int, probe Probe) {
+			defer wg.Done()
+			results[i] = run(probe)
+		}(i, probe)
+	}
+	wg.Wait()
+
 	var conditions []model.Condition
-	// TODO get proxy cluster Condition
+	for _, result := range results {
+		conditions = append(conditions, result...)
+	}
+	return conditions
+}
+
+func run(probe Probe) []model.Condition {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	conditions, err := probe.Probe(ctx)
+	if err != nil {
+		conditionLog.Error(err, "probe "+probe.Name()+" failed")
+		return []model.Condition{newCondition(probe.Name(), metav1.ConditionUnknown, ProbeFailedReason, err.Error())}
+	}
 	return conditions
 }
+
+func newCondition(conditionType string, status metav1.ConditionStatus, reason, message string) model.Condition {
+	return model.Condition{
+		Timestamp: metav1.Now(),
+		Type:      conditionType,
+		Status:    status,
+		Reason:    reason,
+		Message:   message,
+	}
+}
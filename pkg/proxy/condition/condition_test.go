@@ -0,0 +1,132 @@
+package condition
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func newNode(name string, ready bool, pressure ...corev1.NodeConditionType) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: status})
+	for _, conditionType := range pressure {
+		node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{Type: conditionType, Status: corev1.ConditionTrue})
+	}
+	return node
+}
+
+func reasons(conditions []model.Condition) map[string]string {
+	result := make(map[string]string)
+	for _, condition := range conditions {
+		result[condition.Type] = condition.Reason
+	}
+	return result
+}
+
+func TestNodeConditions(t *testing.T) {
+	got := reasons(nodeConditions([]corev1.Node{
+		newNode("node-1", true),
+		newNode("node-2", false, corev1.NodeDiskPressure),
+	}))
+	want := map[string]string{
+		NodesReadyType:          "NodesNotReady",
+		NodesMemoryPressureType: "NoPressure",
+		NodesDiskPressureType:   "NodesUnderPressure",
+		NodesPIDPressureType:    "NoPressure",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nodeConditions() = %v, want %v", got, want)
+	}
+}
+
+func TestFailedChecks(t *testing.T) {
+	body := "[+]ping ok\n[-]etcd failed: reason withheld\n[+]log ok\n[-]poststarthook/crd-informer-synced failed: reason withheld\nreadyz check failed\n"
+	got := failedChecks([]byte(body))
+	want := []string{"etcd", "poststarthook/crd-informer-synced"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("failedChecks() = %v, want %v", got, want)
+	}
+}
+
+func TestAPIServerProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/readyz/etcd":
+			// the apiserver is older than 1.16
+			http.NotFound(w, r)
+		case "/healthz/etcd":
+			w.Write([]byte("ok"))
+		case "/readyz":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("[+]ping ok\n[-]etcd failed: reason withheld\nreadyz check failed\n"))
+		}
+	}))
+	defer server.Close()
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := kubeClient.Discovery().RESTClient()
+
+	conditions, err := NewAPIServerProbe(client).Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conditions) != 1 || conditions[0].Reason != "APIServerNotReady" || conditions[0].Message != "failed checks: etcd" {
+		t.Errorf("apiserver conditions = %+v", conditions)
+	}
+
+	conditions, err = NewEtcdProbe(client).Probe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conditions) != 1 || conditions[0].Reason != "EtcdHealthy" {
+		t.Errorf("etcd conditions = %+v", conditions)
+	}
+}
+
+func TestCertificateCondition(t *testing.T) {
+	now := time.Now()
+	tests := map[time.Duration]string{
+		-time.Hour:          "CertificateExpired",
+		24 * time.Hour:      "CertificateExpiring",
+		90 * 24 * time.Hour: "CertificateValid",
+	}
+	for remaining, want := range tests {
+		if got := certificateCondition(now.Add(remaining), now).Reason; got != want {
+			t.Errorf("certificateCondition(%s) = %s, want %s", remaining, got, want)
+		}
+	}
+}
+
+type failedProbe struct{}
+
+func (failedProbe) Name() string {
+	return "Failed"
+}
+
+func (failedProbe) Probe(ctx context.Context) ([]model.Condition, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestRunFailedProbe(t *testing.T) {
+	conditions := run(failedProbe{})
+	if len(conditions) != 1 || conditions[0].Type != "Failed" || conditions[0].Reason != ProbeFailedReason {
+		t.Errorf("run() = %+v", conditions)
+	}
+}
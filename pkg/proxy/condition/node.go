@@ -0,0 +1,104 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+const (
+	NodesReadyType          = "NodesReady"
+	NodesMemoryPressureType = "NodesMemoryPressure"
+	NodesDiskPressureType   = "NodesDiskPressure"
+	NodesPIDPressureType    = "NodesPIDPressure"
+
+	// maxListedNodes limits the node names in message
+	maxListedNodes = 5
+)
+
+// pressureTypes maps the node condition to the cluster condition
+var pressureTypes = []struct {
+	node    corev1.NodeConditionType
+	cluster string
+}{
+	{node: corev1.NodeMemoryPressure, cluster: NodesMemoryPressureType},
+	{node: corev1.NodeDiskPressure, cluster: NodesDiskPressureType},
+	{node: corev1.NodePIDPressure, cluster: NodesPIDPressureType},
+}
+
+// NodeProbe reports the readiness and the pressure of nodes, nodes are read from the cache of manager
+type NodeProbe struct {
+	client client.Client
+}
+
+func NewNodeProbe(c client.Client) *NodeProbe {
+	return &NodeProbe{client: c}
+}
+
+func (p *NodeProbe) Name() string {
+	return NodesReadyType
+}
+
+func (p *NodeProbe) Probe(ctx context.Context) ([]model.Condition, error) {
+	nodeList := &corev1.NodeList{}
+	if err := p.client.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+	return nodeConditions(nodeList.Items), nil
+}
+
+func nodeConditions(nodes []corev1.Node) []model.Condition {
+	var notReady []string
+	for i := range nodes {
+		if !nodeReady(&nodes[i]) {
+			notReady = append(notReady, nodes[i].Name)
+		}
+	}
+	var conditions []model.Condition
+	if len(notReady) == 0 {
+		conditions = append(conditions, newCondition(NodesReadyType, metav1.ConditionTrue, "NodesReady", fmt.Sprintf("all %d nodes are ready", len(nodes))))
+	} else {
+		conditions = append(conditions, newCondition(NodesReadyType, metav1.ConditionFalse, "NodesNotReady",
+			fmt.Sprintf("%d of %d nodes are not ready: %s", len(notReady), len(nodes), listNodes(notReady))))
+	}
+
+	for _, pressure := range pressureTypes {
+		var names []string
+		for _, node := range nodes {
+			for _, condition := range node.Status.Conditions {
+				if condition.Type == pressure.node && condition.Status == corev1.ConditionTrue {
+					names = append(names, node.Name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			conditions = append(conditions, newCondition(pressure.cluster, metav1.ConditionFalse, "NoPressure", fmt.Sprintf("no node has %s", pressure.node)))
+			continue
+		}
+		conditions = append(conditions, newCondition(pressure.cluster, metav1.ConditionTrue, "NodesUnderPressure",
+			fmt.Sprintf("%d nodes have %s: %s", len(names), pressure.node, listNodes(names))))
+	}
+	return conditions
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func listNodes(names []string) string {
+	if len(names) <= maxListedNodes {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedNodes], ", "), len(names)-maxListedNodes)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	multclusterclient "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -9,6 +10,8 @@ type DefaultConfig struct {
 	Cfg              *Configuration
 	ProxyClient      *multclusterclient.Clientset
 	ControllerClient client.Client
+	// CertStore holds the proxy client certificate, nil means mutual tls is disabled
+	CertStore *certificate.Store
 }
 
 var ProxyConfig *DefaultConfig
@@ -8,6 +8,12 @@ type Configuration struct {
 	CoreAddress      string
 	AddonPath        string
 	AddonLoadTimeout time.Duration
+	// TLSServerName is used to verify the core certificate, default is the host of CoreAddress
+	TLSServerName string
+	// BootstrapToken is sent in register request when core requires bootstrap token
+	BootstrapToken string
+	// ProtocolVersion is the version of channel protocol, v1 or v2
+	ProtocolVersion string
 }
 
 func DefaultConfiguration() *Configuration {
@@ -0,0 +1,278 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var forwarderLog = logf.Log.WithName("proxy_event_forwarder")
+
+const (
+	// batchPeriod is how long the Events are collected before they are sent in one request
+	batchPeriod = 5 * time.Second
+	// maxBatchSize sends the batch at once when it is full
+	maxBatchSize = 100
+	// maxPending drops the oldest Events when core can not be reached for a long time
+	maxPending = 1000
+	// maxOwnerDepth is the length of owner chain checked, such as Pod, ReplicaSet, Deployment and ClusterResource
+	maxOwnerDepth = 4
+	// ownerCacheTTL is how long the owner of an object is cached, the owner of an object does not change normally
+	ownerCacheTTL = 10 * time.Minute
+)
+
+type ownerEntry struct {
+	// clusterResource is empty when the object is not created from a ClusterResource
+	clusterResource string
+	expire          time.Time
+}
+
+// Forwarder watches Events in member cluster, and sends the ones involving objects created from ClusterResources
+// to core in batches
+type Forwarder struct {
+	kubeClient kubernetes.Interface
+	client     dynamic.Interface
+	mapper     meta.RESTMapper
+	send       func(*model.EventRequest) error
+	// started filters the Events which happened before proxy starts, they have been forwarded by last proxy
+	started time.Time
+
+	// events are resolved by one worker, the informer is not blocked by the requests of owners
+	events chan *corev1.Event
+	// owners caches the ClusterResource of every object on the owner chains, it is only used by the worker
+	owners map[types.UID]ownerEntry
+
+	lock    sync.Mutex
+	pending []model.ClusterEvent
+	full    chan struct{}
+}
+
+func NewForwarder(kubeClient kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, send func(*model.EventRequest) error) *Forwarder {
+	return &Forwarder{
+		kubeClient: kubeClient,
+		client:     client,
+		mapper:     mapper,
+		send:       send,
+		events:     make(chan *corev1.Event, maxPending),
+		owners:     make(map[types.UID]ownerEntry),
+		full:       make(chan struct{}, 1),
+	}
+}
+
+// Start runs the informer of Events until ctx is done, it is a Runnable of manager
+func (f *Forwarder) Start(ctx context.Context) error {
+	f.started = time.Now()
+	factory := informers.NewSharedInformerFactory(f.kubeClient, 0)
+	informer := factory.Core().V1().Events().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			f.enqueue(obj.(*corev1.Event))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(*corev1.Event).ResourceVersion == newObj.(*corev1.Event).ResourceVersion {
+				return
+			}
+			f.enqueue(newObj.(*corev1.Event))
+		},
+	})
+	factory.Start(ctx.Done())
+	go f.worker(ctx)
+
+	ticker := time.NewTicker(batchPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-f.full:
+		}
+		f.flush()
+	}
+}
+
+// enqueue hands the Event to worker, it is dropped when the worker can not keep up
+func (f *Forwarder) enqueue(event *corev1.Event) {
+	if lastTimestamp(event).Before(f.started) {
+		return
+	}
+	select {
+	case f.events <- event:
+	default:
+		forwarderLog.V(4).Info(fmt.Sprintf("drop event of %s %s/%s, too many events to resolve", event.InvolvedObject.Kind, event.InvolvedObject.Namespace, event.InvolvedObject.Name))
+	}
+}
+
+func (f *Forwarder) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-f.events:
+			f.forward(ctx, event)
+		}
+	}
+}
+
+// forward adds the Event to the batch when its object is created from a ClusterResource
+func (f *Forwarder) forward(ctx context.Context, event *corev1.Event) {
+	clusterResource := f.clusterResourceOf(ctx, event.InvolvedObject)
+	if len(clusterResource) == 0 {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.pending = append(f.pending, model.ClusterEvent{
+		ClusterResource: clusterResource,
+		Kind:            event.InvolvedObject.Kind,
+		Namespace:       event.InvolvedObject.Namespace,
+		Name:            event.InvolvedObject.Name,
+		Type:            event.Type,
+		Reason:          event.Reason,
+		Message:         event.Message,
+		Count:           event.Count,
+		LastTimestamp:   metav1.NewTime(lastTimestamp(event)),
+	})
+	if len(f.pending) > maxPending {
+		f.pending = f.pending[len(f.pending)-maxPending:]
+	}
+	if len(f.pending) >= maxBatchSize {
+		select {
+		case f.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush sends the pending Events, the batch is sent without lock so that the worker is not blocked by core.
+// The batch is put back to be sent again when core is not reachable
+func (f *Forwarder) flush() {
+	for {
+		f.lock.Lock()
+		size := len(f.pending)
+		if size > maxBatchSize {
+			size = maxBatchSize
+		}
+		batch := f.pending[:size:size]
+		f.pending = f.pending[size:]
+		f.lock.Unlock()
+		if size == 0 {
+			return
+		}
+
+		if err := f.send(&model.EventRequest{Events: batch}); err != nil {
+			f.lock.Lock()
+			f.pending = append(batch, f.pending...)
+			if len(f.pending) > maxPending {
+				f.pending = f.pending[len(f.pending)-maxPending:]
+			}
+			forwarderLog.Error(err, fmt.Sprintf("send events failed, pending:%d", len(f.pending)))
+			f.lock.Unlock()
+			return
+		}
+	}
+}
+
+// clusterResourceOf follows the controller of object until a ClusterResource is found, the owners on the way are
+// cached too, so the Pods of the same ReplicaSet only get themselves
+func (f *Forwarder) clusterResourceOf(ctx context.Context, ref corev1.ObjectReference) string {
+	now := time.Now()
+	if clusterResource, ok := f.cachedOwner(ref.UID, now); ok {
+		return clusterResource
+	}
+
+	clusterResource, chain, err := f.resolve(ctx, ref, now)
+	if err != nil {
+		// the object may be deleted already, the Event is dropped and the result is not cached
+		forwarderLog.V(4).Info(fmt.Sprintf("resolve owner of %s %s/%s failed: %s", ref.Kind, ref.Namespace, ref.Name, err))
+		return ""
+	}
+	// expired entries are dropped here, so that the cache does not grow with deleted objects
+	for uid, item := range f.owners {
+		if now.After(item.expire) {
+			delete(f.owners, uid)
+		}
+	}
+	for _, uid := range chain {
+		if len(uid) > 0 {
+			f.owners[uid] = ownerEntry{clusterResource: clusterResource, expire: now.Add(ownerCacheTTL)}
+		}
+	}
+	return clusterResource
+}
+
+func (f *Forwarder) cachedOwner(uid types.UID, now time.Time) (string, bool) {
+	if len(uid) == 0 {
+		return "", false
+	}
+	entry, ok := f.owners[uid]
+	if !ok || now.After(entry.expire) {
+		return "", false
+	}
+	return entry.clusterResource, true
+}
+
+// resolve returns the ClusterResource of object and the uids of objects got on the way
+func (f *Forwarder) resolve(ctx context.Context, ref corev1.ObjectReference, now time.Time) (string, []types.UID, error) {
+	chain := []types.UID{ref.UID}
+	apiVersion, kind, namespace, name := ref.APIVersion, ref.Kind, ref.Namespace, ref.Name
+	for i := 0; i < maxOwnerDepth; i++ {
+		gv, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			return "", nil, err
+		}
+		mapping, err := f.mapper.RESTMapping(gv.WithKind(kind).GroupKind(), gv.Version)
+		if err != nil {
+			return "", nil, err
+		}
+		var resource dynamic.ResourceInterface = f.client.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resource = f.client.Resource(mapping.Resource).Namespace(namespace)
+		}
+		obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		owner := metav1.GetControllerOf(obj)
+		if owner == nil {
+			return "", chain, nil
+		}
+		if owner.Kind == v1alpha1.ClusterResourceGroupVersionKind.Kind && owner.APIVersion == v1alpha1.SchemeGroupVersion.String() {
+			return owner.Name, chain, nil
+		}
+		if clusterResource, ok := f.cachedOwner(owner.UID, now); ok {
+			return clusterResource, chain, nil
+		}
+		chain = append(chain, owner.UID)
+		apiVersion, kind, name = owner.APIVersion, owner.Kind, owner.Name
+	}
+	return "", chain, nil
+}
+
+func lastTimestamp(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,107 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func newObject(apiVersion, kind, name string, owner *metav1.OwnerReference) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	if owner != nil {
+		obj.SetOwnerReferences([]metav1.OwnerReference{*owner})
+	}
+	return obj
+}
+
+func controllerRef(apiVersion, kind, name string) *metav1.OwnerReference {
+	controller := true
+	return &metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID(kind + "-" + name), Controller: &controller}
+}
+
+func TestForwarder(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newObject("apps/v1", "Deployment", "nginx", controllerRef(v1alpha1.SchemeGroupVersion.String(), "ClusterResource", "nginx.apps.v1.deployment")),
+		newObject("apps/v1", "ReplicaSet", "nginx-6799fc88d8", controllerRef("apps/v1", "Deployment", "nginx")),
+		newObject("v1", "Pod", "nginx-6799fc88d8-h8k2x", controllerRef("apps/v1", "ReplicaSet", "nginx-6799fc88d8")),
+		newObject("v1", "Pod", "nginx-6799fc88d8-m9xlp", controllerRef("apps/v1", "ReplicaSet", "nginx-6799fc88d8")),
+		newObject("v1", "Pod", "standalone", nil),
+	)
+	var sent []*model.EventRequest
+	var sendErr error
+	f := NewForwarder(nil, client, mapper, func(request *model.EventRequest) error {
+		if sendErr != nil {
+			return sendErr
+		}
+		sent = append(sent, request)
+		return nil
+	})
+	f.started = time.Now().Add(-time.Minute)
+	ctx := context.Background()
+
+	newEvent := func(uid, name string, lastTimestamp time.Time) *corev1.Event {
+		return &corev1.Event{
+			InvolvedObject: corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: name, UID: types.UID("pod-" + uid)},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "Failed",
+			Message:        "Failed to pull image",
+			LastTimestamp:  metav1.NewTime(lastTimestamp),
+		}
+	}
+	f.forward(ctx, newEvent("1", "nginx-6799fc88d8-h8k2x", time.Now()))
+	f.forward(ctx, newEvent("2", "standalone", time.Now()))
+	f.forward(ctx, newEvent("4", "deleted", time.Now()))
+	// the event before proxy starts is filtered before it is queued
+	f.enqueue(newEvent("3", "nginx-6799fc88d8-h8k2x", time.Now().Add(-time.Hour)))
+	if len(f.events) != 0 {
+		t.Fatalf("old event should not be queued")
+	}
+	sendErr = errors.New("core is not reachable")
+	f.flush()
+	if len(f.pending) != 1 {
+		t.Fatalf("events should be kept when send fails, got %d", len(f.pending))
+	}
+	sendErr = nil
+	f.flush()
+
+	if len(sent) != 1 || len(sent[0].Events) != 1 {
+		t.Fatalf("expected one event of the pod created from ClusterResource, got %v", sent)
+	}
+	if sent[0].Events[0].ClusterResource != "nginx.apps.v1.deployment" {
+		t.Fatalf("unexpected clusterResource %s", sent[0].Events[0].ClusterResource)
+	}
+	if len(f.pending) != 0 {
+		t.Fatalf("pending events should be sent, got %d", len(f.pending))
+	}
+
+	// the ReplicaSet and Deployment are cached, only the new pod is got
+	actions := len(client.Actions())
+	f.forward(ctx, newEvent("5", "nginx-6799fc88d8-m9xlp", time.Now()))
+	if got := len(client.Actions()) - actions; got != 1 {
+		t.Fatalf("expected only the pod is got, but got %d requests", got)
+	}
+	if len(f.pending) != 1 || f.pending[0].ClusterResource != "nginx.apps.v1.deployment" {
+		t.Fatalf("unexpected pending events %v", f.pending)
+	}
+}
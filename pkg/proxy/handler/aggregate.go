@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/model"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var aggregateLog = logf.Log.WithName("proxy_aggregate_response")
+
+func RecvAggregateResponse(response *config.Response) {
+	if response.Type == model.AggregateFailed.String() {
+		aggregateLog.Error(errors.New(response.Body), fmt.Sprintf("core failed to save aggregate request(%s)", response.RequestId))
+		return
+	}
+	aggregateLog.Info(fmt.Sprintf("core saved aggregate request(%s)", response.RequestId))
+}
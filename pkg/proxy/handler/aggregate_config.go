@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	multclusterclient "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	"harmonycloud.cn/stellaris/pkg/model"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+)
+
+var aggregateConfigLog = logf.Log.WithName("proxy_aggregate_config")
+
+// RecvAggregateConfigResponse applies the policies and rules pushed by core when they are changed
+func RecvAggregateConfigResponse(response *config.Response) {
+	body := &model.SyncAggregateConfigResponse{}
+	if err := json.Unmarshal([]byte(response.Body), body); err != nil {
+		aggregateConfigLog.Error(err, "unmarshal aggregate config failed")
+		return
+	}
+	ctx := context.Background()
+	proxyClient := proxy_cfg.ProxyConfig.ProxyClient
+	for _, policy := range body.Policies {
+		var err error
+		if response.Type == model.AggregateConfigDelete.String() {
+			err = deletePolicy(ctx, proxyClient, policy.Namespace, policy.Name)
+		} else {
+			err = applyPolicy(ctx, proxyClient, policy)
+		}
+		if err != nil {
+			aggregateConfigLog.Error(err, fmt.Sprintf("%s policy(%s:%s) failed", response.Type, policy.Namespace, policy.Name))
+		}
+	}
+	for _, rule := range body.Rules {
+		var err error
+		if response.Type == model.AggregateConfigDelete.String() {
+			err = deleteRule(ctx, proxyClient, rule.Namespace, rule.Name)
+		} else {
+			err = applyRule(ctx, proxyClient, rule)
+		}
+		if err != nil {
+			aggregateConfigLog.Error(err, fmt.Sprintf("%s rule(%s:%s) failed", response.Type, rule.Namespace, rule.Name))
+		}
+	}
+}
+
+// cleanObjectMeta drops the fields which belong to the object in control plane
+func cleanObjectMeta(meta *metav1.ObjectMeta) {
+	meta.ResourceVersion = ""
+	meta.UID = ""
+	meta.ManagedFields = nil
+	meta.OwnerReferences = nil
+	meta.CreationTimestamp = metav1.Time{}
+	meta.DeletionTimestamp = nil
+	meta.Finalizers = nil
+}
+
+func applyPolicy(ctx context.Context, proxyClient *multclusterclient.Clientset, policy *v1alpha1.MultiClusterResourceAggregatePolicy) error {
+	policy = policy.DeepCopy()
+	cleanObjectMeta(&policy.ObjectMeta)
+	client := proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(policy.Namespace)
+	exist, err := client.Get(ctx, policy.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(ctx, policy, metav1.CreateOptions{})
+		return err
+	}
+	exist.Labels = policy.Labels
+	exist.Annotations = policy.Annotations
+	exist.Spec = policy.Spec
+	_, err = client.Update(ctx, exist, metav1.UpdateOptions{})
+	return err
+}
+
+func deletePolicy(ctx context.Context, proxyClient *multclusterclient.Clientset, namespace, name string) error {
+	err := proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func applyRule(ctx context.Context, proxyClient *multclusterclient.Clientset, rule *v1alpha1.MultiClusterResourceAggregateRule) error {
+	rule = rule.DeepCopy()
+	cleanObjectMeta(&rule.ObjectMeta)
+	client := proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregateRules(rule.Namespace)
+	exist, err := client.Get(ctx, rule.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(ctx, rule, metav1.CreateOptions{})
+		return err
+	}
+	exist.Labels = rule.Labels
+	exist.Annotations = rule.Annotations
+	exist.Spec = rule.Spec
+	_, err = client.Update(ctx, exist, metav1.UpdateOptions{})
+	return err
+}
+
+func deleteRule(ctx context.Context, proxyClient *multclusterclient.Clientset, namespace, name string) error {
+	err := proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregateRules(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// prunePolicies deletes the local policies which do not exist in core any more, core only pushes the policies of
+// cluster namespace so the others are left alone
+func prunePolicies(ctx context.Context, proxyClient *multclusterclient.Clientset, upstream sets.String) error {
+	namespace := managerCommon.ClusterNamespace(proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	policyList, err := proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range policyList.Items {
+		if upstream.Has(item.Namespace + "/" + item.Name) {
+			continue
+		}
+		if err = deletePolicy(ctx, proxyClient, item.Namespace, item.Name); err != nil {
+			return err
+		}
+		registerLog.Info(fmt.Sprintf("delete policy(%s:%s), it does not exist in core", item.Namespace, item.Name))
+	}
+	return nil
+}
+
+// pruneRules deletes the local rules which do not exist in core any more
+func pruneRules(ctx context.Context, proxyClient *multclusterclient.Clientset, upstream sets.String) error {
+	ruleList, err := proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregateRules(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range ruleList.Items {
+		if upstream.Has(item.Namespace + "/" + item.Name) {
+			continue
+		}
+		if err = deleteRule(ctx, proxyClient, item.Namespace, item.Name); err != nil {
+			return err
+		}
+		registerLog.Info(fmt.Sprintf("delete rule(%s:%s), it does not exist in core", item.Namespace, item.Name))
+	}
+	return nil
+}
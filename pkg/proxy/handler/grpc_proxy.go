@@ -11,13 +11,14 @@ import (
 	clusterResourceController "harmonycloud.cn/stellaris/pkg/controller/cluster-resource"
 
 	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"harmonycloud.cn/stellaris/config"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"harmonycloud.cn/stellaris/pkg/proxy/aggregate"
 	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
 	multclusterclient "harmonycloud.cn/stellaris/pkg/client/clientset/versioned"
 	"harmonycloud.cn/stellaris/pkg/model"
 )
@@ -31,8 +32,11 @@ func RecvRegisterResponse(response *config.Response) {
 	if response.Type != model.RegisterSuccess.String() {
 		err = errors.New(response.Body)
 		registerLog.Error(err, "response is not register success")
+		send.SetSessionToken("")
 		return
 	}
+	// core accepts the stream, the next reconnection starts from the initial interval
+	proxy_stream.ResetBackoff()
 
 	registerLog.Info(fmt.Sprintf("start send heartbeat"))
 	go send.HeartbeatStart()
@@ -40,6 +44,13 @@ func RecvRegisterResponse(response *config.Response) {
 	err = dealResponse(proxy_cfg.ProxyConfig.ProxyClient, response)
 	if err != nil {
 		registerLog.Error(err, "deal response failed")
+		return
+	}
+	// core may miss the aggregate requests while proxy is offline
+	aggregate.ResyncAll()
+	// ClusterResources deleted in core while proxy is offline are found by resync, also when the session is resumed
+	if err = sendResync(); err != nil {
+		registerLog.Error(err, "send resync request failed")
 	}
 }
 
@@ -52,6 +63,11 @@ func dealResponse(proxyClient *multclusterclient.Clientset, response *config.Res
 	if err != nil {
 		return err
 	}
+	send.SetSessionToken(resources.SessionToken)
+	if resources.Resumed {
+		registerLog.Info("session is resumed, skip resync resources")
+		return nil
+	}
 	return syncResource(proxyClient, resources)
 }
 
@@ -96,7 +112,9 @@ func syncClusterResourcesList(ctx context.Context, proxyClient *multclusterclien
 	return nil
 }
 
+// syncPolicys applies the policies of register response, and deletes the ones which are removed in core
 func syncPolicys(ctx context.Context, proxyClient *multclusterclient.Clientset, policyList []string) error {
+	upstream := sets.NewString()
 	for _, str := range policyList {
 		policy := &v1alpha1.MultiClusterResourceAggregatePolicy{}
 		err := json.Unmarshal([]byte(str), policy)
@@ -104,21 +122,17 @@ func syncPolicys(ctx context.Context, proxyClient *multclusterclient.Clientset,
 			registerLog.Error(err, "get policy failed")
 			return err
 		}
-		_, err = proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(policy.GetNamespace()).Create(ctx, policy, metav1.CreateOptions{})
-		if err != nil {
-			if !apierrors.IsAlreadyExists(err) {
-				return err
-			}
-			_, err = proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregatePolicies(policy.GetNamespace()).Update(ctx, policy, metav1.UpdateOptions{})
-			if err != nil {
-				return err
-			}
+		if err = applyPolicy(ctx, proxyClient, policy); err != nil {
+			return err
 		}
+		upstream.Insert(policy.Namespace + "/" + policy.Name)
 	}
-	return nil
+	return prunePolicies(ctx, proxyClient, upstream)
 }
 
+// syncRules applies the rules of register response, and deletes the ones which are removed in core
 func syncRules(ctx context.Context, proxyClient *multclusterclient.Clientset, ruleList []string) error {
+	upstream := sets.NewString()
 	for _, str := range ruleList {
 		rule := &v1alpha1.MultiClusterResourceAggregateRule{}
 		err := json.Unmarshal([]byte(str), rule)
@@ -126,16 +140,10 @@ func syncRules(ctx context.Context, proxyClient *multclusterclient.Clientset, ru
 			registerLog.Error(err, "get rule failed")
 			return err
 		}
-		_, err = proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregateRules(rule.GetNamespace()).Create(ctx, rule, metav1.CreateOptions{})
-		if err != nil {
-			if !apierrors.IsAlreadyExists(err) {
-				return err
-			}
-			_, err = proxyClient.MulticlusterV1alpha1().MultiClusterResourceAggregateRules(rule.GetNamespace()).Update(ctx, rule, metav1.UpdateOptions{})
-			if err != nil {
-				return err
-			}
+		if err = applyRule(ctx, proxyClient, rule); err != nil {
+			return err
 		}
+		upstream.Insert(rule.Namespace + "/" + rule.Name)
 	}
-	return nil
+	return pruneRules(ctx, proxyClient, upstream)
 }
@@ -10,7 +10,9 @@ import (
 
 	"harmonycloud.cn/stellaris/config"
 	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	proxy_send "harmonycloud.cn/stellaris/pkg/proxy/send"
 	"harmonycloud.cn/stellaris/pkg/model"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -24,23 +26,35 @@ func RecvSyncResourceResponse(response *config.Response) {
 	case model.ResourceStatusUpdateSuccess.String():
 		resourceLog.Info(fmt.Sprintf("cluster resource status update success"))
 	case model.ResourceUpdateOrCreate.String():
-		syncClusterResource(response)
+		ackSyncResource(response, syncClusterResource(response))
 	case model.ResourceDelete.String():
-		syncClusterResource(response)
+		ackSyncResource(response, syncClusterResource(response))
 	}
 }
 
-func syncClusterResource(response *config.Response) {
+// ackSyncResource tells core the result of resource sync, so that core stops retrying
+func ackSyncResource(response *config.Response, syncErr error) {
+	if len(response.RequestId) == 0 {
+		return
+	}
+	if err := proxy_send.SendAck(proxy_cfg.ProxyConfig.Cfg.ClusterName, response.RequestId, syncErr); err != nil {
+		resourceLog.Error(err, fmt.Sprintf("send ack of request(%s) failed", response.RequestId))
+	}
+}
+
+func syncClusterResource(response *config.Response) error {
 	resourceRes := &model.SyncResourceResponse{}
 	err := json.Unmarshal([]byte(response.Body), resourceRes)
 	if err != nil {
 		resourceLog.Error(err, fmt.Sprintf("sync proxy(%s) clusterResource failed", response.ClusterName))
-		return
+		return err
 	}
 	ctx := context.Background()
+	var errs []error
 	for _, clusterResource := range resourceRes.ClusterResourceList {
 		resource, err := clusterResourceController.GetClusterResourceObjectForRawExtension(clusterResource)
 		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
 		clusterResource.SetNamespace(resource.GetNamespace())
@@ -48,6 +62,7 @@ func syncClusterResource(response *config.Response) {
 			err = clusterResourceController.SyncProxyClusterResource(ctx, proxy_cfg.ProxyConfig.ProxyClient, clusterResource)
 			if err != nil {
 				resourceLog.Error(err, fmt.Sprintf("updateOrCreate ClusterResource(%s:%s) failed", clusterResource.Namespace, clusterResource.Name))
+				errs = append(errs, err)
 				continue
 			} else {
 				resourceLog.Info(fmt.Sprintf("updateOrCreate ClusterResource(%s:%s) success", clusterResource.Namespace, clusterResource.Name))
@@ -57,10 +72,12 @@ func syncClusterResource(response *config.Response) {
 			err = clusterResourceController.DeleteProxyClusterResource(ctx, proxy_cfg.ProxyConfig.ProxyClient, clusterResource)
 			if err != nil {
 				resourceLog.Error(err, fmt.Sprintf("delete ClusterResource(%s:%s) failed", clusterResource.Namespace, clusterResource.Name))
+				errs = append(errs, err)
 				continue
 			} else {
 				resourceLog.Info(fmt.Sprintf("delete ClusterResource(%s:%s) success", clusterResource.Namespace, clusterResource.Name))
 			}
 		}
 	}
+	return utilerrors.NewAggregate(errs)
 }
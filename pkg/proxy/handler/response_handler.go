@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"errors"
-	"time"
+	"context"
 
-	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/proxy/send"
 
 	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
 	"harmonycloud.cn/stellaris/pkg/model"
@@ -25,22 +25,32 @@ const (
 )
 */
 
+// RecvResponse keeps the stream to core, every new stream is registered first, then responses are dispatched
+// until the stream is broken
 func RecvResponse() {
+	ctx := context.Background()
 	for {
-		stream := proxy_stream.GetConnection()
-		if stream == nil {
-			err := errors.New("get stream failed")
-			registerLog.Error(err, "recv response")
+		stream, err := proxy_stream.Connect(ctx)
+		if err != nil {
+			registerLog.Error(err, "connect to core failed")
+			return
+		}
+		if err = send.Register(); err != nil {
+			registerLog.Error(err, "register failed")
 			proxy_stream.SetEmptyConnection()
-			time.Sleep(proxy_cfg.ProxyConfig.Cfg.HeartbeatPeriod)
 			continue
 		}
+		recvResponse(stream)
+		proxy_stream.SetEmptyConnection()
+	}
+}
+
+func recvResponse(stream config.Channel_EstablishClient) {
+	for {
 		response, err := stream.Recv()
 		if err != nil {
 			registerLog.Error(err, "recv response failed")
-			proxy_stream.SetEmptyConnection()
-			time.Sleep(proxy_cfg.ProxyConfig.Cfg.HeartbeatPeriod)
-			continue
+			return
 		}
 		switch response.Type {
 		case model.Unknown.String():
@@ -61,7 +71,22 @@ func RecvResponse() {
 			RecvSyncResourceResponse(response)
 		case model.ResourceStatusUpdateFailed.String():
 			RecvSyncResourceResponse(response)
-
+		case model.AggregateSuccess.String():
+			RecvAggregateResponse(response)
+		case model.AggregateFailed.String():
+			RecvAggregateResponse(response)
+		case model.AggregateConfigUpdateOrCreate.String():
+			RecvAggregateConfigResponse(response)
+		case model.AggregateConfigDelete.String():
+			RecvAggregateConfigResponse(response)
+		case model.ResyncSuccess.String():
+			RecvResyncResponse(response)
+		case model.ResyncFailed.String():
+			RecvResyncResponse(response)
+		case model.Tunnel.String():
+			RecvTunnelResponse(response)
+		case model.Log.String():
+			RecvLogResponse(response)
 		}
 	}
 }
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
+	clusterResourceController "harmonycloud.cn/stellaris/pkg/controller/cluster-resource"
+	"harmonycloud.cn/stellaris/pkg/model"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	"harmonycloud.cn/stellaris/pkg/proxy/send"
+)
+
+var resyncLog = logf.Log.WithName("proxy_resync")
+
+// sendResync reports the inventory of ClusterResources after register, so that core can find the orphans
+// which are deleted in control plane while proxy is offline. Only the cluster namespace is reported, the other
+// cluster namespaces belong to other clusters when proxy runs in control plane
+func sendResync() error {
+	clusterNamespace := managerCommon.ClusterNamespace(proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	clusterResourceList, err := proxy_cfg.ProxyConfig.ProxyClient.MulticlusterV1alpha1().ClusterResources(clusterNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	request := &model.ResyncRequest{}
+	for _, item := range clusterResourceList.Items {
+		request.Inventory = append(request.Inventory, model.ResyncItem{
+			Name:                      item.Name,
+			Namespace:                 item.Namespace,
+			Generation:                item.Generation,
+			ObservedReceiveGeneration: item.Status.ObservedReceiveGeneration,
+		})
+	}
+	return send.SendResyncRequest(request)
+}
+
+// RecvResyncResponse deletes the orphans and reports the status which is stale in core
+func RecvResyncResponse(response *config.Response) {
+	if response.Type == model.ResyncFailed.String() {
+		resyncLog.Error(errors.New(response.Body), "core failed to resync")
+		return
+	}
+	data := &model.ResyncResponse{}
+	if err := json.Unmarshal([]byte(response.Body), data); err != nil {
+		resyncLog.Error(err, "unmarshal resync response failed")
+		return
+	}
+	ctx := context.Background()
+	proxyClient := proxy_cfg.ProxyConfig.ProxyClient
+	for _, item := range data.Orphans {
+		clusterResource := &v1alpha1.ClusterResource{ObjectMeta: metav1.ObjectMeta{Namespace: item.Namespace, Name: item.Name}}
+		if err := clusterResourceController.DeleteProxyClusterResource(ctx, proxyClient, clusterResource); err != nil {
+			resyncLog.Error(err, fmt.Sprintf("delete orphan ClusterResource(%s:%s) failed", item.Namespace, item.Name))
+			continue
+		}
+		resyncLog.Info(fmt.Sprintf("delete orphan ClusterResource(%s:%s), it does not exist in core", item.Namespace, item.Name))
+	}
+
+	var stale []*v1alpha1.ClusterResource
+	for _, item := range data.Stale {
+		clusterResource, err := proxyClient.MulticlusterV1alpha1().ClusterResources(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+		if err != nil {
+			resyncLog.Error(err, fmt.Sprintf("get stale ClusterResource(%s:%s) failed", item.Namespace, item.Name))
+			continue
+		}
+		stale = append(stale, clusterResource)
+	}
+	if len(stale) == 0 {
+		return
+	}
+	if err := clusterResourceController.ReportClusterResourceStatus(stale); err != nil {
+		resyncLog.Error(err, "report stale ClusterResource status failed")
+		return
+	}
+	resyncLog.Info(fmt.Sprintf("report status of %d stale ClusterResources", len(stale)))
+}
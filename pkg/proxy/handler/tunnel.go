@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/proxy/tunnel"
+)
+
+var tunnelLog = logf.Log.WithName("proxy_tunnel_response")
+
+// RecvTunnelResponse runs or cancels the Kubernetes API request tunnelled by core
+func RecvTunnelResponse(response *config.Response) {
+	request := &model.TunnelRequest{}
+	if err := json.Unmarshal([]byte(response.Body), request); err != nil {
+		tunnelLog.Error(err, "unmarshal tunnel request failed")
+		return
+	}
+	tunnel.Handle(request)
+}
+
+// RecvLogResponse starts, grants credit to or cancels the log stream requested by core
+func RecvLogResponse(response *config.Response) {
+	request := &model.LogRequest{}
+	if err := json.Unmarshal([]byte(response.Body), request); err != nil {
+		tunnelLog.Error(err, "unmarshal log request failed")
+		return
+	}
+	tunnel.HandleLog(request)
+}
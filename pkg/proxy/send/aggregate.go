@@ -0,0 +1,19 @@
+package send
+
+import (
+	"fmt"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	"harmonycloud.cn/stellaris/pkg/utils/common"
+)
+
+// SendAggregateRequest sends all results of one rule in one policy to core
+func SendAggregateRequest(data *model.AggregateRequest) error {
+	request, err := common.GenerateRequest(model.Aggregate.String(), data, proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	resourceLog.Info(fmt.Sprintf("send aggregate request of policy(%s:%s) rule(%s), results:%d", data.PolicyNamespace, data.PolicyName, data.RuleName, len(data.Results)))
+	return SendSyncResourceRequest(request)
+}
@@ -0,0 +1,25 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
+	"harmonycloud.cn/stellaris/pkg/utils/common"
+)
+
+// SendEventRequest sends a batch of Events, core does not reply to it
+func SendEventRequest(data *model.EventRequest) error {
+	request, err := common.GenerateRequest(model.Event.String(), data, proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	stream := proxy_stream.GetConnection()
+	if stream == nil {
+		return errors.New("stream is not connected")
+	}
+	resourceLog.V(4).Info(fmt.Sprintf("send event request, events:%d", len(data.Events)))
+	return stream.Send(request)
+}
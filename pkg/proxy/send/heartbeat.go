@@ -1,6 +1,7 @@
 package send
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sync"
@@ -11,9 +12,15 @@ import (
 	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
 	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
 	clusterHealth "harmonycloud.cn/stellaris/pkg/common/cluster-health"
+	managerCommon "harmonycloud.cn/stellaris/pkg/common"
 	"harmonycloud.cn/stellaris/pkg/model"
 	"harmonycloud.cn/stellaris/pkg/utils/proxy"
 	"harmonycloud.cn/stellaris/pkg/utils/common"
+	"harmonycloud.cn/stellaris/pkg/utils/capacity"
+	"harmonycloud.cn/stellaris/pkg/utils/digest"
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -51,6 +58,17 @@ func (heartbeat *HeartbeatObject) start() {
 		}
 		heartbeatWithChange.Conditions = conditions
 		heartbeatWithChange.Healthy = healthy
+		// core ignores the digest when it is nil
+		resources, err := resourceDigest()
+		if err != nil {
+			heartbeatLog.Error(err, "get resource digest failed")
+		}
+		heartbeatWithChange.Digest = resources
+		summary, err := resourceSummary()
+		if err != nil {
+			heartbeatLog.Error(err, "get resource summary failed")
+		}
+		heartbeatWithChange.ResourceSummary = summary
 		request, err := common.GenerateRequest(model.Heartbeat.String(), heartbeatWithChange, proxy_cfg.ProxyConfig.Cfg.ClusterName)
 		if err != nil {
 			heartbeatLog.Error(err, "create Heartbeat request failed")
@@ -69,6 +87,31 @@ func (heartbeat *HeartbeatObject) start() {
 	}
 }
 
+// resourceDigest summarizes the ClusterResources of cluster namespace in member cluster, core finds the silent
+// desync with it
+func resourceDigest() (*model.ResourceDigest, error) {
+	clusterNamespace := managerCommon.ClusterNamespace(proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	clusterResourceList, err := proxy_cfg.ProxyConfig.ProxyClient.MulticlusterV1alpha1().ClusterResources(clusterNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return digest.New(clusterResourceList.Items)
+}
+
+// resourceSummary is the capacity of member cluster, nodes and pods are read from the cache of manager
+func resourceSummary() (*v1alpha1.ClusterResourceSummary, error) {
+	ctx := context.Background()
+	nodeList := &corev1.NodeList{}
+	if err := proxy_cfg.ProxyConfig.ControllerClient.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+	podList := &corev1.PodList{}
+	if err := proxy_cfg.ProxyConfig.ControllerClient.List(ctx, podList); err != nil {
+		return nil, err
+	}
+	return capacity.Summarize(nodeList.Items, podList.Items), nil
+}
+
 func SetLastHeartbeat(request *model.HeartbeatWithChangeRequest) {
 	heartbeat.LastHeartbeat = request
 }
@@ -0,0 +1,18 @@
+package send
+
+import (
+	"context"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	"harmonycloud.cn/stellaris/pkg/utils/common"
+)
+
+// SendLogChunk sends one chunk of container logs, the number of chunks in flight is limited by the credit from core
+func SendLogChunk(ctx context.Context, chunk *model.LogResponse) error {
+	request, err := common.GenerateRequest(model.LogChunk.String(), chunk, proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	return sendWhenQueueAvailable(ctx, request)
+}
@@ -3,6 +3,7 @@ package send
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"harmonycloud.cn/stellaris/pkg/proxy/addons"
 	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
@@ -15,6 +16,24 @@ import (
 
 var registerLog = logf.Log.WithName("proxy_send_register")
 
+var sessionLock sync.Mutex
+
+// sessionToken is issued by core in the last register response
+var sessionToken string
+
+// SetSessionToken saves the session token, empty token makes the next register resync fully
+func SetSessionToken(token string) {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+	sessionToken = token
+}
+
+func getSessionToken() string {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+	return sessionToken
+}
+
 func Register() error {
 	registerLog.Info(fmt.Sprintf("start register cluster(%s)", proxy_cfg.ProxyConfig.Cfg.ClusterName))
 	stream := proxy_stream.GetConnection()
@@ -23,7 +42,10 @@ func Register() error {
 		registerLog.Error(err, "register")
 		return err
 	}
-	addonInfo := &model.RegisterRequest{}
+	addonInfo := &model.RegisterRequest{
+		Token:        proxy_cfg.ProxyConfig.Cfg.BootstrapToken,
+		SessionToken: getSessionToken(),
+	}
 	if proxy_cfg.ProxyConfig.Cfg.AddonPath != "" {
 		addonConfig, err := proxy.GetAddonConfig(proxy_cfg.ProxyConfig.Cfg.AddonPath)
 		if err != nil {
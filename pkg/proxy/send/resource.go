@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"harmonycloud.cn/stellaris/config"
 	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
 	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/utils/common"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -37,5 +39,20 @@ func NewResourceRequest(resType model.ServiceRequestType, clusterName string, bo
 		Type:        resType.String(),
 		ClusterName: clusterName,
 		Body:        body,
+		RequestId:   uuid.NewString(),
 	}, nil
 }
+
+// SendAck tells core whether the response with requestID is applied
+func SendAck(clusterName, requestID string, applyErr error) error {
+	ack := &model.AckRequest{Success: applyErr == nil}
+	if applyErr != nil {
+		ack.Message = applyErr.Error()
+	}
+	request, err := common.GenerateRequest(model.Ack.String(), ack, clusterName)
+	if err != nil {
+		return err
+	}
+	request.RequestId = requestID
+	return SendSyncResourceRequest(request)
+}
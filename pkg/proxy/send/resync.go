@@ -0,0 +1,19 @@
+package send
+
+import (
+	"fmt"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	"harmonycloud.cn/stellaris/pkg/utils/common"
+)
+
+// SendResyncRequest reports the inventory of ClusterResources, core replies with the ones to delete or report again
+func SendResyncRequest(data *model.ResyncRequest) error {
+	request, err := common.GenerateRequest(model.Resync.String(), data, proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	resourceLog.Info(fmt.Sprintf("send resync request, inventory:%d", len(data.Inventory)))
+	return SendSyncResourceRequest(request)
+}
@@ -0,0 +1,46 @@
+package send
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"harmonycloud.cn/stellaris/config"
+	"harmonycloud.cn/stellaris/pkg/model"
+	"harmonycloud.cn/stellaris/pkg/protocol"
+	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
+	proxy_stream "harmonycloud.cn/stellaris/pkg/proxy/stream"
+	"harmonycloud.cn/stellaris/pkg/utils/common"
+)
+
+// tunnelRetryInterval is how long a chunk waits when the send queue of stream is full
+const tunnelRetryInterval = 50 * time.Millisecond
+
+// SendTunnelChunk sends one chunk of tunnelled response, it waits while the send queue is full so that
+// a large response does not break the tunnel
+func SendTunnelChunk(ctx context.Context, chunk *model.TunnelResponse) error {
+	request, err := common.GenerateRequest(model.TunnelChunk.String(), chunk, proxy_cfg.ProxyConfig.Cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	return sendWhenQueueAvailable(ctx, request)
+}
+
+// sendWhenQueueAvailable retries the request until the send queue of stream has room for it
+func sendWhenQueueAvailable(ctx context.Context, request *config.Request) error {
+	for {
+		stream := proxy_stream.GetConnection()
+		if stream == nil {
+			return errors.New("stream is not connected")
+		}
+		err := stream.Send(request)
+		if !errors.Is(err, protocol.ErrSendQueueFull) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tunnelRetryInterval):
+		}
+	}
+}
@@ -3,48 +3,139 @@ package stream
 import (
 	"context"
 	"sync"
-	"sync/atomic"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 
 	proxy_cfg "harmonycloud.cn/stellaris/pkg/proxy/config"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"harmonycloud.cn/stellaris/config"
+	v2 "harmonycloud.cn/stellaris/config/v2"
+	"harmonycloud.cn/stellaris/pkg/protocol"
+	"harmonycloud.cn/stellaris/pkg/utils/certificate"
+)
+
+const (
+	reconnectInitialInterval = time.Second
+	reconnectMaxInterval     = time.Minute
+	// reconnectJitterFactor spreads the reconnection of proxies after core restarts
+	reconnectJitterFactor = 0.5
 )
 
-var initialized uint32
 var mux sync.Mutex
+var conn *grpc.ClientConn
 var stream config.Channel_EstablishClient
 
+// failures is the number of continuous failed connections, it decides the reconnect backoff
+var failures int
+
+// GetConnection returns the current stream, nil means proxy is disconnected and Connect is reconnecting
 func GetConnection() config.Channel_EstablishClient {
-	if atomic.LoadUint32(&initialized) == 1 {
-		return stream
-	}
 	mux.Lock()
 	defer mux.Unlock()
-	if initialized == 0 {
-		s, err := getConnection()
-		if err == nil {
-			stream = s
-			atomic.StoreUint32(&initialized, 1)
-		} else {
+	return stream
+}
+
+// Connect establishes a new stream, it retries with exponential backoff and jitter until it succeeds or ctx is done
+func Connect(ctx context.Context) (config.Channel_EstablishClient, error) {
+	for {
+		mux.Lock()
+		delay := reconnectDelay(failures)
+		mux.Unlock()
+		if delay > 0 {
+			klog.InfoS("Reconnect to core later", "delay", delay)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		c, s, err := getConnection(ctx)
+		mux.Lock()
+		if err != nil {
+			failures++
+			mux.Unlock()
 			klog.ErrorS(err, "Unable to get grpc connection")
+			continue
 		}
+		closeConnectionLocked()
+		conn, stream = c, s
+		mux.Unlock()
+		return s, nil
 	}
-	return stream
 }
 
-func getConnection() (config.Channel_EstablishClient, error) {
-	conn, err := grpc.Dial(proxy_cfg.ProxyConfig.Cfg.CoreAddress, grpc.WithInsecure())
+// ResetBackoff is called when core accepts the stream, so that the next reconnection starts from the initial interval
+func ResetBackoff() {
+	mux.Lock()
+	defer mux.Unlock()
+	failures = 0
+}
+
+// SetEmptyConnection closes the broken stream, the next Connect backs off if the stream is not accepted by core
+func SetEmptyConnection() {
+	mux.Lock()
+	defer mux.Unlock()
+	closeConnectionLocked()
+	failures++
+}
+
+func closeConnectionLocked() {
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			klog.ErrorS(err, "Close grpc connection failed")
+		}
+	}
+	conn, stream = nil, nil
+}
+
+// reconnectDelay is zero for the first attempt, then doubles until it reaches the max interval
+func reconnectDelay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := reconnectInitialInterval
+	for i := 1; i < failures && delay < reconnectMaxInterval; i++ {
+		delay *= 2
+	}
+	if delay > reconnectMaxInterval {
+		delay = reconnectMaxInterval
+	}
+	return wait.Jitter(delay, reconnectJitterFactor)
+}
+
+func getConnection(ctx context.Context) (*grpc.ClientConn, config.Channel_EstablishClient, error) {
+	c, err := grpc.Dial(proxy_cfg.ProxyConfig.Cfg.CoreAddress, transportCredentials(),
+		grpc.WithStreamInterceptor(protocol.SerialStreamClientInterceptor(protocol.DefaultSendQueueSize)))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	var s config.Channel_EstablishClient
+	if proxy_cfg.ProxyConfig.Cfg.ProtocolVersion == protocol.VersionV1 {
+		s, err = config.NewChannelClient(c).Establish(ctx)
+	} else {
+		var v2Stream v2.Channel_EstablishClient
+		v2Stream, err = v2.NewChannelClient(c).Establish(ctx)
+		if err == nil {
+			s = protocol.NewClientStream(v2Stream)
+		}
 	}
-	grpcClient := config.NewChannelClient(conn)
-	return grpcClient.Establish(context.Background())
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return c, s, nil
 }
 
-func SetEmptyConnection() {
-	atomic.StoreUint32(&initialized, 0)
-	stream = nil
+// transportCredentials builds tls credentials from the latest certificate on every dial
+func transportCredentials() grpc.DialOption {
+	certStore := proxy_cfg.ProxyConfig.CertStore
+	if certStore == nil {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(certificate.ClientTLSConfig(certStore, proxy_cfg.ProxyConfig.Cfg.TLSServerName)))
 }
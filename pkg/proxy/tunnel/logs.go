@@ -0,0 +1,288 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+const (
+	// maxLogCredit limits the credit granted by core, chunks beyond it are never in flight
+	maxLogCredit = 64
+	// logWindowLines is the number of lines read ahead from all sources
+	logWindowLines = 1024
+)
+
+// logStream is one running log stream, a chunk is only sent when there is credit
+type logStream struct {
+	cancel  context.CancelFunc
+	credits chan struct{}
+}
+
+// grant adds credit to the stream, the credit beyond maxLogCredit is dropped
+func (l *logStream) grant(credit int64) {
+	for i := int64(0); i < credit; i++ {
+		select {
+		case l.credits <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
+
+// logSource is one container whose logs are streamed
+type logSource struct {
+	pod       string
+	container string
+}
+
+// HandleLog starts, grants credit to or cancels the log stream with the default executor
+func HandleLog(request *model.LogRequest) {
+	if defaultExecutor == nil {
+		tunnelLog.Info(fmt.Sprintf("tunnel is not enabled, drop log request %s", request.StreamID))
+		return
+	}
+	defaultExecutor.HandleLog(request)
+}
+
+func (e *Executor) HandleLog(request *model.LogRequest) {
+	e.lock.Lock()
+	stream, ok := e.logs[request.StreamID]
+	e.lock.Unlock()
+	if ok {
+		if request.Cancel {
+			tunnelLog.Info(fmt.Sprintf("cancel log stream %s", request.StreamID))
+			stream.cancel()
+			return
+		}
+		stream.grant(request.Credit)
+		return
+	}
+	// the stream is finished
+	if request.Cancel || len(request.Namespace) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream = &logStream{cancel: cancel, credits: make(chan struct{}, maxLogCredit)}
+	stream.grant(request.Credit)
+	e.lock.Lock()
+	e.logs[request.StreamID] = stream
+	e.lock.Unlock()
+	go func() {
+		defer func() {
+			e.lock.Lock()
+			delete(e.logs, request.StreamID)
+			e.lock.Unlock()
+			cancel()
+		}()
+		if err := e.streamLogs(ctx, request, stream); err != nil && ctx.Err() == nil {
+			tunnelLog.Error(err, fmt.Sprintf("stream logs of %s failed", request.StreamID))
+		}
+	}()
+}
+
+// streamLogs reads the logs of all sources concurrently, the lines are prefixed with pod and container
+// when there are more than one source
+func (e *Executor) streamLogs(ctx context.Context, request *model.LogRequest, stream *logStream) error {
+	var seq int64
+	sendChunk := func(chunk *model.LogResponse) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stream.credits:
+		}
+		chunk.StreamID = request.StreamID
+		chunk.Seq = seq
+		seq++
+		return e.sendLog(ctx, chunk)
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(e.restConfig)
+	if err != nil {
+		return sendChunk(&model.LogResponse{Done: true, Error: err.Error()})
+	}
+	sources, err := logSources(ctx, kubeClient, request)
+	if err != nil {
+		return sendChunk(&model.LogResponse{Done: true, Error: err.Error()})
+	}
+
+	lines := make(chan []byte, logWindowLines)
+	errs := make(chan error, len(sources))
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source logSource) {
+			defer wg.Done()
+			prefix := ""
+			if len(sources) > 1 {
+				prefix = fmt.Sprintf("[%s/%s] ", source.pod, source.container)
+			}
+			if err := readLogs(ctx, kubeClient, request, source, prefix, lines); err != nil {
+				errs <- fmt.Errorf("%s/%s: %s", source.pod, source.container, err)
+			}
+		}(source)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+		close(errs)
+	}()
+
+	buf := make([]byte, 0, chunkSize)
+	for {
+		line, ok := <-lines
+		if ok {
+			buf = append(buf, line...)
+			buf, ok = drainLines(lines, buf)
+		}
+		// a line longer than chunkSize is split
+		for len(buf) > chunkSize {
+			if err = sendChunk(&model.LogResponse{Data: append([]byte(nil), buf[:chunkSize]...)}); err != nil {
+				return err
+			}
+			buf = buf[chunkSize:]
+		}
+		if !ok {
+			var messages []string
+			for err := range errs {
+				messages = append(messages, err.Error())
+			}
+			return sendChunk(&model.LogResponse{Data: buf, Done: true, Error: strings.Join(messages, "; ")})
+		}
+		if len(buf) > 0 {
+			if err = sendChunk(&model.LogResponse{Data: append([]byte(nil), buf...)}); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+}
+
+// drainLines appends the lines which are ready without waiting, it returns false when lines is closed
+func drainLines(lines <-chan []byte, buf []byte) ([]byte, bool) {
+	for len(buf) < chunkSize {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return buf, false
+			}
+			buf = append(buf, line...)
+		default:
+			return buf, true
+		}
+	}
+	return buf, true
+}
+
+// logSources finds the containers to read, a container must be specified when the pod has more than one
+// and only one pod is requested
+func logSources(ctx context.Context, kubeClient kubernetes.Interface, request *model.LogRequest) ([]logSource, error) {
+	// the pod without selector is the workload itself, the logs of all pods are never read
+	if len(request.Pod) == 0 && len(request.LabelSelector) == 0 {
+		return nil, errors.New("neither pod nor selector of workload is requested")
+	}
+	var selector labels.Selector
+	if len(request.LabelSelector) > 0 {
+		var err error
+		if selector, err = labels.Parse(request.LabelSelector); err != nil {
+			return nil, err
+		}
+		if selector.Empty() {
+			return nil, errors.New("selector of workload is empty")
+		}
+	}
+	var pods []corev1.Pod
+	if len(request.Pod) > 0 {
+		pod, err := kubeClient.CoreV1().Pods(request.Namespace).Get(ctx, request.Pod, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		// the pod must belong to the workload
+		if selector != nil && !selector.Matches(labels.Set(pod.Labels)) {
+			return nil, fmt.Errorf("pod %s/%s does not match %s", request.Namespace, request.Pod, request.LabelSelector)
+		}
+		pods = append(pods, *pod)
+	} else {
+		podList, err := kubeClient.CoreV1().Pods(request.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		pods = podList.Items
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pods found in %s with %s", request.Namespace, request.LabelSelector)
+	}
+
+	var sources []logSource
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if len(request.Container) > 0 && container.Name != request.Container {
+				continue
+			}
+			sources = append(sources, logSource{pod: pod.Name, container: container.Name})
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("container %s is not found in pods", request.Container)
+	}
+	if len(pods) == 1 && len(sources) > 1 && len(request.Container) == 0 {
+		return nil, fmt.Errorf("a container name must be specified for pod %s, choose one of %v", pods[0].Name, containerNames(sources))
+	}
+	return sources, nil
+}
+
+func containerNames(sources []logSource) []string {
+	var names []string
+	for _, source := range sources {
+		names = append(names, source.container)
+	}
+	return names
+}
+
+// readLogs reads the logs of one container line by line
+func readLogs(ctx context.Context, kubeClient kubernetes.Interface, request *model.LogRequest, source logSource, prefix string, lines chan<- []byte) error {
+	reader, err := kubeClient.CoreV1().Pods(request.Namespace).GetLogs(source.pod, &corev1.PodLogOptions{
+		Container:    source.container,
+		Follow:       request.Follow,
+		SinceSeconds: request.SinceSeconds,
+		TailLines:    request.TailLines,
+		Timestamps:   request.Timestamps,
+		Previous:     request.Previous,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	r := bufio.NewReader(reader)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if len(prefix) > 0 {
+				line = append([]byte(prefix), line...)
+			}
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+func TestLogSources(t *testing.T) {
+	newPod := func(name string, labels map[string]string, containers ...string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: name, Labels: labels}}
+		for _, container := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		}
+		return pod
+	}
+	kubeClient := fake.NewSimpleClientset(
+		newPod("nginx-1", map[string]string{"app": "nginx"}, "nginx", "sidecar"),
+		newPod("nginx-2", map[string]string{"app": "nginx"}, "nginx", "sidecar"),
+		newPod("redis", map[string]string{"app": "redis"}, "redis"),
+	)
+	ctx := context.Background()
+
+	sources, err := logSources(ctx, kubeClient, &model.LogRequest{Namespace: "web", LabelSelector: "app=nginx", Container: "nginx"})
+	if err != nil || len(sources) != 2 {
+		t.Fatalf("expected nginx container of 2 pods, got %v %v", sources, err)
+	}
+	sources, err = logSources(ctx, kubeClient, &model.LogRequest{Namespace: "web", LabelSelector: "app=nginx"})
+	if err != nil || len(sources) != 4 {
+		t.Fatalf("expected all containers of 2 pods, got %v %v", sources, err)
+	}
+	if _, err = logSources(ctx, kubeClient, &model.LogRequest{Namespace: "web", LabelSelector: "app=nginx", Pod: "nginx-1"}); err == nil {
+		t.Fatal("container should be required for one pod with more than one container")
+	}
+	if _, err = logSources(ctx, kubeClient, &model.LogRequest{Namespace: "web", LabelSelector: "app=nginx", Pod: "redis"}); err == nil {
+		t.Fatal("pod which does not belong to the workload should be rejected")
+	}
+	sources, err = logSources(ctx, kubeClient, &model.LogRequest{Namespace: "web", Pod: "redis"})
+	if err != nil || len(sources) != 1 || sources[0].container != "redis" {
+		t.Fatalf("expected redis container, got %v %v", sources, err)
+	}
+	if _, err = logSources(ctx, kubeClient, &model.LogRequest{Namespace: "web", Container: "redis"}); err == nil {
+		t.Fatal("request without pod and selector should not read the pods of namespace")
+	}
+}
+
+func TestLogStreamGrant(t *testing.T) {
+	stream := &logStream{credits: make(chan struct{}, maxLogCredit)}
+	stream.grant(maxLogCredit + 10)
+	if len(stream.credits) != maxLogCredit {
+		t.Fatalf("credit should be limited to %d, got %d", maxLogCredit, len(stream.credits))
+	}
+}
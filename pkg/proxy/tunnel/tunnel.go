@@ -0,0 +1,181 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+var tunnelLog = logf.Log.WithName("proxy_tunnel")
+
+// chunkSize is the max body size in one chunk
+const chunkSize = 32 << 10
+
+// droppedHeaders are set by proxy or only valid between the caller and core
+var droppedHeaders = []string{"Authorization", "Connection", "Upgrade", "Proxy-Connection", "Keep-Alive", "Te", "Trailer", "Transfer-Encoding"}
+
+// Executor runs the Kubernetes API requests tunnelled by core in member cluster
+type Executor struct {
+	restConfig  *rest.Config
+	impersonate bool
+	send        func(context.Context, *model.TunnelResponse) error
+	sendLog     func(context.Context, *model.LogResponse) error
+
+	lock    sync.Mutex
+	cancels map[string]context.CancelFunc
+	logs    map[string]*logStream
+}
+
+var defaultExecutor *Executor
+
+// Setup creates the executor, requests run as the caller when impersonate is true, otherwise as proxy itself,
+// logs are always read by proxy itself, core authorizes the caller on the MultiClusterResource
+func Setup(restConfig *rest.Config, impersonate bool, send func(context.Context, *model.TunnelResponse) error,
+	sendLog func(context.Context, *model.LogResponse) error) *Executor {
+	defaultExecutor = &Executor{
+		restConfig:  restConfig,
+		impersonate: impersonate,
+		send:        send,
+		sendLog:     sendLog,
+		cancels:     make(map[string]context.CancelFunc),
+		logs:        make(map[string]*logStream),
+	}
+	return defaultExecutor
+}
+
+// Handle starts or cancels the request with the default executor
+func Handle(request *model.TunnelRequest) {
+	if defaultExecutor == nil {
+		tunnelLog.Info(fmt.Sprintf("tunnel is not enabled, drop request %s", request.StreamID))
+		return
+	}
+	defaultExecutor.Handle(request)
+}
+
+func (e *Executor) Handle(request *model.TunnelRequest) {
+	if request.Cancel {
+		e.lock.Lock()
+		cancel, ok := e.cancels[request.StreamID]
+		e.lock.Unlock()
+		if ok {
+			tunnelLog.Info(fmt.Sprintf("cancel tunnel %s", request.StreamID))
+			cancel()
+		}
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.lock.Lock()
+	e.cancels[request.StreamID] = cancel
+	e.lock.Unlock()
+	go func() {
+		defer func() {
+			e.lock.Lock()
+			delete(e.cancels, request.StreamID)
+			e.lock.Unlock()
+			cancel()
+		}()
+		if err := e.execute(ctx, request); err != nil && ctx.Err() == nil {
+			tunnelLog.Error(err, fmt.Sprintf("tunnel %s %s failed", request.Method, request.Path))
+		}
+	}()
+}
+
+func (e *Executor) transport(request *model.TunnelRequest) (http.RoundTripper, error) {
+	cfg := rest.CopyConfig(e.restConfig)
+	if e.impersonate {
+		cfg.Impersonate = rest.ImpersonationConfig{UserName: request.User, Groups: request.Groups}
+	}
+	return rest.TransportFor(cfg)
+}
+
+// execute streams the response back in chunks, an error before the response is sent as the only chunk
+func (e *Executor) execute(ctx context.Context, request *model.TunnelRequest) error {
+	var seq int64
+	sendChunk := func(chunk *model.TunnelResponse) error {
+		chunk.StreamID = request.StreamID
+		chunk.Seq = seq
+		seq++
+		return e.send(ctx, chunk)
+	}
+	resp, err := e.do(ctx, request)
+	if err != nil {
+		if sendErr := sendChunk(&model.TunnelResponse{Done: true, Error: err.Error()}); sendErr != nil {
+			return sendErr
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	// status and header are sent at once, a watch may not have any event for a long time
+	if err = sendChunk(&model.TunnelResponse{StatusCode: resp.StatusCode, Header: resp.Header}); err != nil {
+		return err
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		chunk := &model.TunnelResponse{}
+		if n > 0 {
+			chunk.Body = append([]byte(nil), buf[:n]...)
+		}
+		if readErr == io.EOF {
+			chunk.Done = true
+		} else if readErr != nil {
+			chunk.Done = true
+			chunk.Error = readErr.Error()
+		}
+		if n == 0 && !chunk.Done {
+			continue
+		}
+		if err = sendChunk(chunk); err != nil {
+			return err
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+}
+
+func (e *Executor) do(ctx context.Context, request *model.TunnelRequest) (*http.Response, error) {
+	transport, err := e.transport(request)
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(e.restConfig.Host, "/") + request.Path
+	if len(request.RawQuery) > 0 {
+		url += "?" + request.RawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range request.Header {
+		if dropHeader(name) {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	return transport.RoundTrip(req)
+}
+
+func dropHeader(name string) bool {
+	if strings.HasPrefix(http.CanonicalHeaderKey(name), "Impersonate-") {
+		return true
+	}
+	for _, item := range droppedHeaders {
+		if http.CanonicalHeaderKey(name) == item {
+			return true
+		}
+	}
+	return false
+}
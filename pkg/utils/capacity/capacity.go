@@ -0,0 +1,113 @@
+package capacity
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+// summaryResources are the resources in ClusterResourceSummary
+var summaryResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourcePods}
+
+// Summarize counts the allocatable resources of schedulable nodes, and the requests of the pods which are
+// not terminated on them
+func Summarize(nodes []corev1.Node, pods []corev1.Pod) *v1alpha1.ClusterResourceSummary {
+	summary := &v1alpha1.ClusterResourceSummary{
+		Nodes:       int32(len(nodes)),
+		Allocatable: newResourceList(),
+		Allocated:   newResourceList(),
+	}
+	schedulable := make(map[string]bool, len(nodes))
+	for i := range nodes {
+		if !Schedulable(&nodes[i]) {
+			continue
+		}
+		schedulable[nodes[i].Name] = true
+		summary.SchedulableNodes++
+		add(summary.Allocatable, nodes[i].Status.Allocatable)
+	}
+	for i := range pods {
+		pod := &pods[i]
+		if !schedulable[pod.Spec.NodeName] || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		add(summary.Allocated, PodRequests(pod))
+		count := summary.Allocated[corev1.ResourcePods]
+		count.Add(*resource.NewQuantity(1, resource.DecimalSI))
+		summary.Allocated[corev1.ResourcePods] = count
+	}
+	return summary
+}
+
+// Schedulable is true when the node is ready, not cordoned and has no taint which repels all pods
+func Schedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// PodRequests is the larger one of the sum of containers and any init container, plus the pod overhead
+func PodRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		add(requests, container.Resources.Requests)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	add(requests, pod.Spec.Overhead)
+	return requests
+}
+
+// Available is allocatable minus allocated of one resource, it is false when the cluster does not report it
+func Available(summary *v1alpha1.ClusterResourceSummary, name corev1.ResourceName) (resource.Quantity, bool) {
+	if summary == nil {
+		return resource.Quantity{}, false
+	}
+	allocatable, ok := summary.Allocatable[name]
+	if !ok {
+		return resource.Quantity{}, false
+	}
+	available := allocatable.DeepCopy()
+	if allocated, ok := summary.Allocated[name]; ok {
+		available.Sub(allocated)
+	}
+	return available, true
+}
+
+func newResourceList() corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(0, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(0, resource.BinarySI),
+		corev1.ResourcePods:   *resource.NewQuantity(0, resource.DecimalSI),
+	}
+}
+
+// add only sums the resources in summary
+func add(total, list corev1.ResourceList) {
+	for _, name := range summaryResources {
+		quantity, ok := list[name]
+		if !ok {
+			continue
+		}
+		current := total[name]
+		current.Add(quantity)
+		total[name] = current
+	}
+}
@@ -0,0 +1,81 @@
+package capacity
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newNode(name string, ready bool, taints ...corev1.Taint) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+		},
+	}
+}
+
+func newPod(nodeName string, phase corev1.PodPhase, cpu string) corev1.Pod {
+	return corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}}}},
+			InitContainers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("1"),
+			}}}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	nodes := []corev1.Node{
+		newNode("a", true),
+		newNode("b", true),
+		newNode("not-ready", false),
+		newNode("tainted", true, corev1.Taint{Key: "maintenance", Effect: corev1.TaintEffectNoSchedule}),
+	}
+	pods := []corev1.Pod{
+		newPod("a", corev1.PodRunning, "500m"),
+		newPod("b", corev1.PodRunning, "2"),
+		newPod("b", corev1.PodSucceeded, "2"),
+		newPod("tainted", corev1.PodRunning, "2"),
+		newPod("", corev1.PodPending, "2"),
+	}
+	summary := Summarize(nodes, pods)
+	if summary.Nodes != 4 || summary.SchedulableNodes != 2 {
+		t.Fatalf("unexpected nodes %d, schedulable %d", summary.Nodes, summary.SchedulableNodes)
+	}
+	expected := map[corev1.ResourceName]string{corev1.ResourceCPU: "8", corev1.ResourceMemory: "16Gi", corev1.ResourcePods: "220"}
+	for name, value := range expected {
+		if quantity := summary.Allocatable[name]; quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Fatalf("allocatable %s should be %s, got %s", name, value, quantity.String())
+		}
+	}
+	// the init container requests more cpu than the containers of the first pod
+	expected = map[corev1.ResourceName]string{corev1.ResourceCPU: "3", corev1.ResourceMemory: "2Gi", corev1.ResourcePods: "2"}
+	for name, value := range expected {
+		if quantity := summary.Allocated[name]; quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Fatalf("allocated %s should be %s, got %s", name, value, quantity.String())
+		}
+	}
+	available, ok := Available(summary, corev1.ResourcePods)
+	if !ok || available.Value() != 218 {
+		t.Fatalf("available pods should be 218, got %s", available.String())
+	}
+}
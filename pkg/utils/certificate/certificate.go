@@ -0,0 +1,293 @@
+package certificate
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// keys of a kubernetes.io/tls secret, ca.crt is added by cert-manager and kube-webhook-certgen
+	SecretCertKey = "tls.crt"
+	SecretKeyKey  = "tls.key"
+	SecretCAKey   = "ca.crt"
+)
+
+var certificateLog = logf.Log.WithName("certificate")
+
+// Source describes where the certificate, private key and CA bundle are loaded from,
+// either files on disk or a secret, files take precedence when both are set
+type Source struct {
+	CertFile        string
+	KeyFile         string
+	CAFile          string
+	SecretNamespace string
+	SecretName      string
+	KubeClient      kubernetes.Interface
+}
+
+func (s *Source) IsEmpty() bool {
+	return len(s.CertFile) == 0 && len(s.KeyFile) == 0 && len(s.CAFile) == 0 && len(s.SecretName) == 0
+}
+
+func (s *Source) fromFile() bool {
+	return len(s.CertFile) > 0 || len(s.KeyFile) > 0 || len(s.CAFile) > 0
+}
+
+// version returns a token which changes when the underlying certificate material changes
+func (s *Source) version(ctx context.Context) (string, error) {
+	if s.fromFile() {
+		v := ""
+		for _, path := range []string{s.CertFile, s.KeyFile, s.CAFile} {
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", err
+			}
+			v += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+		}
+		return v, nil
+	}
+	secret, err := s.KubeClient.CoreV1().Secrets(s.SecretNamespace).Get(ctx, s.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return secret.ResourceVersion, nil
+}
+
+func (s *Source) load(ctx context.Context) (certPEM, keyPEM, caPEM []byte, err error) {
+	if s.fromFile() {
+		if certPEM, err = ioutil.ReadFile(s.CertFile); err != nil {
+			return
+		}
+		if keyPEM, err = ioutil.ReadFile(s.KeyFile); err != nil {
+			return
+		}
+		caPEM, err = ioutil.ReadFile(s.CAFile)
+		return
+	}
+	if s.KubeClient == nil {
+		return nil, nil, nil, errors.New("kube client is required when load certificate from secret")
+	}
+	secret, err := s.KubeClient.CoreV1().Secrets(s.SecretNamespace).Get(ctx, s.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, key := range []string{SecretCertKey, SecretKeyKey, SecretCAKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, nil, nil, fmt.Errorf("secret %s/%s does not have data with field: %s", s.SecretNamespace, s.SecretName, key)
+		}
+	}
+	return secret.Data[SecretCertKey], secret.Data[SecretKeyKey], secret.Data[SecretCAKey], nil
+}
+
+// Store keeps the latest certificate and CA pool of a Source and reloads them when they rotate
+type Store struct {
+	source  *Source
+	lock    sync.RWMutex
+	version string
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+}
+
+func NewStore(source *Source) (*Store, error) {
+	s := &Store{source: source}
+	if err := s.reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start checks the source every period and reloads the certificate when it changed, it blocks until ctx is done
+func (s *Store) Start(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reload(ctx); err != nil {
+				certificateLog.Error(err, "reload certificate failed, keep using the previous one")
+			}
+		}
+	}
+}
+
+func (s *Store) reload(ctx context.Context) error {
+	version, err := s.source.version(ctx)
+	if err != nil {
+		return err
+	}
+	s.lock.RLock()
+	unchanged := s.cert != nil && version == s.version
+	s.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	certPEM, keyPEM, caPEM, err := s.source.load(ctx)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return errors.New("no valid certificate found in CA bundle")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.version = version
+	s.cert = &cert
+	s.caPool = caPool
+	certificateLog.Info("certificate loaded")
+	return nil
+}
+
+func (s *Store) Certificate() *tls.Certificate {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.cert
+}
+
+func (s *Store) CAPool() *x509.CertPool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.caPool
+}
+
+// CommonName returns the common name of the current certificate
+func (s *Store) CommonName() (string, error) {
+	leaf, err := s.leaf()
+	if err != nil {
+		return "", err
+	}
+	return leaf.Subject.CommonName, nil
+}
+
+// NotAfter returns the expiry time of the current certificate
+func (s *Store) NotAfter() (time.Time, error) {
+	leaf, err := s.leaf()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return leaf.NotAfter, nil
+}
+
+func (s *Store) leaf() (*x509.Certificate, error) {
+	cert := s.Certificate()
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("certificate is empty")
+	}
+	return x509.ParseCertificate(cert.Certificate[0])
+}
+
+// ServerTLSConfig requires and verifies client certificates, every handshake uses the latest certificate and CA
+func ServerTLSConfig(store *Store) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*store.Certificate()},
+				ClientCAs:    store.CAPool(),
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+			}, nil
+		},
+	}
+}
+
+// HTTPServerTLSConfig serves the certificate without requiring client certificates, callers authenticate with tokens
+func HTTPServerTLSConfig(store *Store) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return store.Certificate(), nil
+		},
+	}
+}
+
+// ClientTLSConfig should be called for every new connection, so that a rotated CA is picked up on reconnect
+func ClientTLSConfig(store *Store, serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		RootCAs:    store.CAPool(),
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return store.Certificate(), nil
+		},
+	}
+}
+
+// clientCredentials builds the tls config on every handshake, the grpc connections which live long and reconnect
+// by themselves pick up the rotated certificate and CA
+type clientCredentials struct {
+	store      *Store
+	serverName string
+}
+
+// ClientCredentials is used by the grpc clients which dial once, such as the forwarding between core replicas
+func ClientCredentials(store *Store, serverName string) credentials.TransportCredentials {
+	return &clientCredentials{store: store, serverName: serverName}
+}
+
+func (c *clientCredentials) current() credentials.TransportCredentials {
+	return credentials.NewTLS(ClientTLSConfig(c.store, c.serverName))
+}
+
+func (c *clientCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.current().ClientHandshake(ctx, authority, conn)
+}
+
+func (c *clientCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("client credentials can not be used by server")
+}
+
+func (c *clientCredentials) Info() credentials.ProtocolInfo {
+	return c.current().Info()
+}
+
+func (c *clientCredentials) Clone() credentials.TransportCredentials {
+	return &clientCredentials{store: c.store, serverName: c.serverName}
+}
+
+func (c *clientCredentials) OverrideServerName(serverName string) error {
+	c.serverName = serverName
+	return nil
+}
+
+// ClusterNameFromContext returns the cluster identity, which is the common name of the verified client certificate
+func ClusterNameFromContext(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", errors.New("cannot find peer in context")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", errors.New("connection is not secured by tls")
+	}
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", errors.New("client certificate is not verified")
+	}
+	name := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+	if len(name) == 0 {
+		return "", errors.New("client certificate subject common name is empty")
+	}
+	return name, nil
+}
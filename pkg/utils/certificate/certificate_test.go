@@ -0,0 +1,127 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSelfSignedCert(t *testing.T, dir, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	for name, data := range map[string][]byte{SecretCertKey: certPEM, SecretKeyKey: keyPEM, SecretCAKey: certPEM} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStoreReloadRotatedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeSelfSignedCert(t, dir, "cluster-a")
+	store, err := NewStore(&Source{
+		CertFile: filepath.Join(dir, SecretCertKey),
+		KeyFile:  filepath.Join(dir, SecretKeyKey),
+		CAFile:   filepath.Join(dir, SecretCAKey),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := store.Certificate()
+
+	// make sure the modify time changes on file systems with coarse timestamps
+	time.Sleep(10 * time.Millisecond)
+	writeSelfSignedCert(t, dir, "cluster-b")
+	if err := store.reload(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	second := store.Certificate()
+	if first == second {
+		t.Fatal("certificate is not reloaded after rotation")
+	}
+	leaf, err := x509.ParseCertificate(second.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.Subject.CommonName != "cluster-b" {
+		t.Fatalf("expect common name cluster-b, but got %s", leaf.Subject.CommonName)
+	}
+}
+
+func TestClientCredentialsUseRotatedCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeSelfSignedCert(t, dir, "core-a")
+	store, err := NewStore(&Source{
+		CertFile: filepath.Join(dir, SecretCertKey),
+		KeyFile:  filepath.Join(dir, SecretKeyKey),
+		CAFile:   filepath.Join(dir, SecretCAKey),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := ClientCredentials(store, "")
+	handshake := func(serverName string) error {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		go tls.Server(server, ServerTLSConfig(store)).Handshake()
+		_, _, err := creds.ClientHandshake(context.TODO(), serverName, client)
+		return err
+	}
+	if err := handshake("core-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	writeSelfSignedCert(t, dir, "core-b")
+	if err := store.reload(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake("core-b"); err != nil {
+		t.Fatalf("handshake should verify with the rotated CA, but got %s", err)
+	}
+}
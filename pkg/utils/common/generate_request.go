@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/google/uuid"
 	"harmonycloud.cn/stellaris/config"
 )
 
@@ -16,6 +17,7 @@ func GenerateRequest(sendType string, v interface{}, clusterName string) (*confi
 		Type:        sendType,
 		ClusterName: clusterName,
 		Body:        string(requestBody),
+		RequestId:   uuid.NewString(),
 	}
 	return request, nil
 }
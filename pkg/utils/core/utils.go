@@ -3,8 +3,7 @@ package core
 import (
 	"encoding/json"
 
-	"harmonycloud.cn/stellaris/pkg/apis/multicluster/common"
-
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"harmonycloud.cn/stellaris/config"
 	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
@@ -14,6 +13,9 @@ import (
 )
 
 func SendResponse(res *config.Response, stream config.Channel_EstablishServer) {
+	if len(res.RequestId) == 0 {
+		res.RequestId = uuid.NewString()
+	}
 	if err := stream.Send(res); err != nil {
 		logrus.Errorf("failed to send message to cluster %s", err)
 	}
@@ -62,20 +64,6 @@ func ConvertRegisterAddons2KubeAddons(addons []model.Addon) ([]v1alpha1.ClusterA
 	return result, nil
 }
 
-func ConvertCondition2KubeCondition(conditions []model.Condition) []common.Condition {
-	result := make([]common.Condition, len(conditions))
-	for _, condition := range conditions {
-		clusterCondition := common.Condition{
-			Timestamp: condition.Timestamp,
-			Message:   condition.Message,
-			Reason:    condition.Reason,
-			Type:      condition.Type,
-		}
-		result = append(result, clusterCondition)
-	}
-	return result
-}
-
 func Object2RawExtension(obj interface{}) (*runtime.RawExtension, error) {
 	b, err := json.Marshal(obj)
 	if err != nil {
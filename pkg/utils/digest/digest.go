@@ -0,0 +1,97 @@
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+	"harmonycloud.cn/stellaris/pkg/model"
+)
+
+// SpecHash hashes the spec of ClusterResource, the resource is decoded and encoded again so that
+// the same object has the same hash in control plane and member cluster
+func SpecHash(spec v1alpha1.ClusterResourceSpec) (string, error) {
+	var resource interface{}
+	if spec.Resource != nil && len(spec.Resource.Raw) > 0 {
+		if err := json.Unmarshal(spec.Resource.Raw, &resource); err != nil {
+			return "", err
+		}
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// New builds the digest of ClusterResources, the items are sorted by namespace and name
+func New(clusterResources []v1alpha1.ClusterResource) (*model.ResourceDigest, error) {
+	result := &model.ResourceDigest{}
+	for _, clusterResource := range clusterResources {
+		hash, err := SpecHash(clusterResource.Spec)
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, model.DigestItem{
+			Name:      clusterResource.Name,
+			Namespace: clusterResource.Namespace,
+			SpecHash:  hash,
+		})
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		if result.Items[i].Namespace != result.Items[j].Namespace {
+			return result.Items[i].Namespace < result.Items[j].Namespace
+		}
+		return result.Items[i].Name < result.Items[j].Name
+	})
+	result.Checksum = Checksum(result.Items)
+	return result, nil
+}
+
+// Checksum hashes the namespaces, names and spec hashes of sorted items
+func Checksum(items []model.DigestItem) string {
+	h := sha256.New()
+	for _, item := range items {
+		h.Write([]byte(item.Namespace))
+		h.Write([]byte{0})
+		h.Write([]byte(item.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(item.SpecHash))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Diff returns the items which only exist in local or remote, and the local items whose spec hashes differ
+func Diff(local, remote *model.ResourceDigest) (localOnly, remoteOnly, changed []model.DigestItem) {
+	remoteItems := make(map[types.NamespacedName]model.DigestItem, len(remote.Items))
+	for _, item := range remote.Items {
+		remoteItems[keyOf(item)] = item
+	}
+	for _, item := range local.Items {
+		remoteItem, ok := remoteItems[keyOf(item)]
+		if !ok {
+			localOnly = append(localOnly, item)
+			continue
+		}
+		delete(remoteItems, keyOf(item))
+		if remoteItem.SpecHash != item.SpecHash {
+			changed = append(changed, item)
+		}
+	}
+	for _, item := range remote.Items {
+		if _, ok := remoteItems[keyOf(item)]; ok {
+			remoteOnly = append(remoteOnly, item)
+		}
+	}
+	return localOnly, remoteOnly, changed
+}
+
+func keyOf(item model.DigestItem) types.NamespacedName {
+	return types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+}
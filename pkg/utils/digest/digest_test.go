@@ -0,0 +1,66 @@
+package digest
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+func newClusterResource(namespace, name, raw string) v1alpha1.ClusterResource {
+	return v1alpha1.ClusterResource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v1alpha1.ClusterResourceSpec{Resource: &runtime.RawExtension{Raw: []byte(raw)}},
+	}
+}
+
+func TestDigest(t *testing.T) {
+	core, err := New([]v1alpha1.ClusterResource{
+		newClusterResource("stellaris-harmonycloud-cn-a", "b", `{"kind":"Deployment","spec":{"replicas":1}}`),
+		newClusterResource("stellaris-harmonycloud-cn-a", "a", `{"kind":"Service"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key order in member cluster does not change the checksum
+	proxy, err := New([]v1alpha1.ClusterResource{
+		newClusterResource("stellaris-harmonycloud-cn-a", "a", `{"kind":"Service"}`),
+		newClusterResource("stellaris-harmonycloud-cn-a", "b", `{"spec":{"replicas":1},"kind":"Deployment"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if core.Checksum != proxy.Checksum {
+		t.Fatal("same ClusterResources should have the same checksum")
+	}
+
+	// the ClusterResource with the same name in another cluster namespace is a different one
+	other, _ := New([]v1alpha1.ClusterResource{
+		newClusterResource("stellaris-harmonycloud-cn-b", "a", `{"kind":"Service"}`),
+		newClusterResource("stellaris-harmonycloud-cn-b", "b", `{"kind":"Deployment","spec":{"replicas":1}}`),
+	})
+	if core.Checksum == other.Checksum {
+		t.Fatal("ClusterResources in different namespaces should have different checksums")
+	}
+
+	proxy, _ = New([]v1alpha1.ClusterResource{
+		newClusterResource("stellaris-harmonycloud-cn-a", "b", `{"kind":"Deployment","spec":{"replicas":2}}`),
+		newClusterResource("stellaris-harmonycloud-cn-a", "c", `{"kind":"ConfigMap"}`),
+		newClusterResource("stellaris-harmonycloud-cn-b", "a", `{"kind":"Service"}`),
+	})
+	if core.Checksum == proxy.Checksum {
+		t.Fatal("different ClusterResources should have different checksums")
+	}
+	coreOnly, proxyOnly, changed := Diff(core, proxy)
+	if len(coreOnly) != 1 || coreOnly[0].Name != "a" {
+		t.Fatalf("unexpected core only items %v", coreOnly)
+	}
+	if len(proxyOnly) != 2 || proxyOnly[0].Name != "c" || proxyOnly[1].Namespace != "stellaris-harmonycloud-cn-b" {
+		t.Fatalf("unexpected proxy only items %v", proxyOnly)
+	}
+	if len(changed) != 1 || changed[0].Name != "b" {
+		t.Fatalf("unexpected changed items %v", changed)
+	}
+}
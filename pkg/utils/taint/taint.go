@@ -0,0 +1,88 @@
+package taint
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+const (
+	// UnschedulableTaintKey taints the cordoned cluster with NoSchedule
+	UnschedulableTaintKey = "cluster.stellaris.harmonycloud.cn/unschedulable"
+	// MaintenanceTaintKey taints the cluster in its maintenance window with the effect of window
+	MaintenanceTaintKey = "cluster.stellaris.harmonycloud.cn/maintenance"
+)
+
+// ClusterTaints are the taints in spec of cluster, and the taints of cordon and maintenance window
+func ClusterTaints(cluster *v1alpha1.Cluster, now time.Time) []corev1.Taint {
+	taints := append([]corev1.Taint(nil), cluster.Spec.Taints...)
+	if cluster.Spec.Unschedulable {
+		taints = append(taints, corev1.Taint{Key: UnschedulableTaintKey, Effect: corev1.TaintEffectNoSchedule})
+	}
+	if window := cluster.Spec.Maintenance; InMaintenance(window, now) {
+		effect := window.Effect
+		if len(effect) == 0 {
+			effect = corev1.TaintEffectNoSchedule
+		}
+		start := window.Start
+		taints = append(taints, corev1.Taint{Key: MaintenanceTaintKey, Value: window.Reason, Effect: effect, TimeAdded: &start})
+	}
+	return taints
+}
+
+// InMaintenance tells whether now is in the window, the window without end never ends
+func InMaintenance(window *v1alpha1.MaintenanceWindow, now time.Time) bool {
+	if window == nil || now.Before(window.Start.Time) {
+		return false
+	}
+	return window.End == nil || now.Before(window.End.Time)
+}
+
+// NextMaintenanceChange is how long until the maintenance window of cluster starts or ends, false is returned when
+// the window does not change any more
+func NextMaintenanceChange(cluster *v1alpha1.Cluster, now time.Time) (time.Duration, bool) {
+	window := cluster.Spec.Maintenance
+	switch {
+	case window == nil:
+		return 0, false
+	case now.Before(window.Start.Time):
+		return window.Start.Sub(now), true
+	case window.End != nil && now.Before(window.End.Time):
+		return window.End.Sub(now), true
+	default:
+		return 0, false
+	}
+}
+
+// Untolerated are the taints which are not tolerated by any of tolerations
+func Untolerated(taints []corev1.Taint, tolerations []corev1.Toleration) []corev1.Taint {
+	var untolerated []corev1.Taint
+	for i := range taints {
+		if !tolerated(&taints[i], tolerations) {
+			untolerated = append(untolerated, taints[i])
+		}
+	}
+	return untolerated
+}
+
+func tolerated(taint *corev1.Taint, tolerations []corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithEffect are the taints of effect
+func WithEffect(taints []corev1.Taint, effect corev1.TaintEffect) []corev1.Taint {
+	var result []corev1.Taint
+	for _, taint := range taints {
+		if taint.Effect == effect {
+			result = append(result, taint)
+		}
+	}
+	return result
+}
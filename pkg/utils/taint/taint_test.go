@@ -0,0 +1,61 @@
+package taint
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"harmonycloud.cn/stellaris/pkg/apis/multicluster/v1alpha1"
+)
+
+func TestClusterTaints(t *testing.T) {
+	now := time.Now()
+	end := metav1.NewTime(now.Add(time.Hour))
+	cluster := &v1alpha1.Cluster{Spec: v1alpha1.ClusterSpec{
+		Unschedulable: true,
+		Taints:        []corev1.Taint{{Key: "gpu", Value: "true", Effect: corev1.TaintEffectPreferNoSchedule}},
+		Maintenance:   &v1alpha1.MaintenanceWindow{Start: metav1.NewTime(now.Add(-time.Hour)), End: &end, Reason: "upgrade"},
+	}}
+
+	taints := ClusterTaints(cluster, now)
+	if len(taints) != 3 {
+		t.Fatalf("taints = %+v, want gpu, unschedulable and maintenance", taints)
+	}
+	if taints[1].Key != UnschedulableTaintKey || taints[1].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("cordon taint = %+v", taints[1])
+	}
+	if taints[2].Key != MaintenanceTaintKey || taints[2].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("maintenance taint = %+v, effect should default to NoSchedule", taints[2])
+	}
+
+	if taints := ClusterTaints(cluster, now.Add(2*time.Hour)); len(taints) != 2 {
+		t.Errorf("taints after window = %+v, want gpu and unschedulable", taints)
+	}
+	if d, ok := NextMaintenanceChange(cluster, now); !ok || d != time.Hour {
+		t.Errorf("next change = %s %v, want end of window in 1h", d, ok)
+	}
+	if _, ok := NextMaintenanceChange(cluster, now.Add(2*time.Hour)); ok {
+		t.Errorf("window should not change after it ends")
+	}
+}
+
+func TestUntolerated(t *testing.T) {
+	taints := []corev1.Taint{
+		{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+		{Key: MaintenanceTaintKey, Effect: corev1.TaintEffectNoExecute},
+	}
+	tolerations := []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "true"}}
+
+	untolerated := Untolerated(taints, tolerations)
+	if len(untolerated) != 1 || untolerated[0].Key != MaintenanceTaintKey {
+		t.Errorf("untolerated = %+v, want maintenance", untolerated)
+	}
+	if noExecute := WithEffect(untolerated, corev1.TaintEffectNoExecute); len(noExecute) != 1 {
+		t.Errorf("NoExecute taints = %+v", noExecute)
+	}
+	if untolerated := Untolerated(taints, []corev1.Toleration{{Operator: corev1.TolerationOpExists}}); len(untolerated) != 0 {
+		t.Errorf("untolerated = %+v, empty key with Exists should tolerate everything", untolerated)
+	}
+}